@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// inActions reports whether the process runs as a GitHub Actions step.
+func inActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// actionsGroup starts a collapsible log group and returns the function that ends it. Outside
+// Actions both are no-ops.
+func actionsGroup(title string) func() {
+	if !inActions() {
+		return func() {}
+	}
+	fmt.Printf("::group::%s\n", actionsEscape(title))
+	return func() { fmt.Println("::endgroup::") }
+}
+
+// actionsAnnotate emits a workflow annotation of the given level (error, warning or notice).
+func actionsAnnotate(level, msg string) {
+	if inActions() {
+		fmt.Printf("::%s::%s\n", level, actionsEscape(msg))
+	}
+}
+
+// actionsEscape encodes the characters workflow commands reserve in their message.
+func actionsEscape(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// actionsOutput appends step outputs to the file named by GITHUB_OUTPUT, in key order. Values
+// spanning several lines are written with a random heredoc delimiter.
+func actionsOutput(keys []string, values map[string]string) error {
+	file := os.Getenv("GITHUB_OUTPUT")
+	if !inActions() || file == "" {
+		return nil
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open GITHUB_OUTPUT: %w", err)
+	}
+	var b strings.Builder
+	for _, k := range keys {
+		v := values[k]
+		if !strings.ContainsAny(v, "\r\n") {
+			fmt.Fprintf(&b, "%s=%s\n", k, v)
+			continue
+		}
+		var raw [8]byte
+		rand.Read(raw[:])
+		delim := "ghadelim_" + hex.EncodeToString(raw[:])
+		fmt.Fprintf(&b, "%s<<%s\n%s\n%s\n", k, delim, v, delim)
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
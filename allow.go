@@ -0,0 +1,208 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// A project manifest is picked up from the working directory or one of its parents, so any
+// repository that is cloned or unpacked can bring one. Its install locations could point at
+// any file the user can write and its hooks are shell commands. The dest, name and hooks
+// settings of a project manifest are therefore ignored until the file, as it is now, has been
+// allowed with the allow subcommand. Allowed files are kept in allowed.json next to the state
+// file, by absolute path with the SHA-256 of their contents, so that editing a file revokes it.
+
+// allowList maps the absolute path of each allowed project manifest to the SHA-256 of its
+// contents.
+type allowList map[string]string
+
+// allowListPath returns the location of the allow list, next to the state file.
+func allowListPath() (string, error) {
+	path, err := statePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "allowed.json"), nil
+}
+
+// loadAllowList reads the allow list. A missing file allows nothing.
+func loadAllowList() (allowList, error) {
+	path, err := allowListPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return allowList{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read allow list: %w", err)
+	}
+	a := allowList{}
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("could not parse allow list %s: %w", path, err)
+	}
+	return a, nil
+}
+
+// updateAllowList applies f to the allow list under its lock and saves the result.
+func updateAllowList(f func(allowList)) error {
+	path, err := allowListPath()
+	if err != nil {
+		return err
+	}
+	release, err := lockFile(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer release()
+	a, err := loadAllowList()
+	if err != nil {
+		return err
+	}
+	f(a)
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("could not write allow list: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// isProjectManifest reports whether file has one of the names of a project manifest.
+func isProjectManifest(file string) bool {
+	return slices.Contains(projectManifestNames, filepath.Base(file))
+}
+
+// contentDigest returns the hex SHA-256 of a manifest's contents.
+func contentDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// allowedAsIs reports whether file, whose contents are data, has been allowed as it is now.
+func allowedAsIs(file string, data []byte) (bool, error) {
+	a, err := loadAllowList()
+	if err != nil {
+		return false, err
+	}
+	return a[file] == contentDigest(data), nil
+}
+
+// needsAllow reports whether m says where any tool is installed or has a hook.
+func (m *manifest) needsAllow() bool {
+	if m.Defaults.Dest != "" {
+		return true
+	}
+	for _, t := range m.Tools {
+		if t.Dest != "" || t.Name != "" || t.Hooks != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// distrusted holds the manifests whose settings were dropped, so that each is warned about
+// once although a run may load it more than once.
+var distrusted = map[string]bool{}
+
+// distrust drops the install locations and hooks of m, read from data, with a warning if m
+// is a project manifest that has not been allowed as it is now. Its tools are then installed
+// to the default locations. The hooks of an allowed project manifest remember where they
+// came from, so that updates stop running them once it is no longer allowed.
+func (m *manifest) distrust(data []byte) error {
+	if m.file == "" || !isProjectManifest(m.file) || !m.needsAllow() {
+		return nil
+	}
+	ok, err := allowedAsIs(m.file, data)
+	if err != nil {
+		return err
+	}
+	if ok {
+		for _, t := range m.Tools {
+			if t.Hooks != nil {
+				t.Hooks.From = m.file
+			}
+		}
+		return nil
+	}
+	if !distrusted[m.file] {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring the install locations and hooks in %s, which has not been allowed; review them and run get_gh_release allow %s to use them\n", m.file, m.file)
+		distrusted[m.file] = true
+	}
+	m.Defaults.Dest = ""
+	for i := range m.Tools {
+		m.Tools[i].Dest, m.Tools[i].Name, m.Tools[i].Hooks = "", "", nil
+	}
+	return nil
+}
+
+// runAllow allows the settings of a project manifest as it is now, or with -revoke stops
+// using them, printing the settings allowed.
+func runAllow(args []string) error {
+	fs := flag.NewFlagSet("allow", flag.ExitOnError)
+	revoke := fs.Bool("revoke", false, "Ignore the install locations and hooks of the manifest again.")
+	fs.Parse(args)
+	file, err := manifestArg(fs)
+	if err != nil {
+		return fmt.Errorf("usage: get_gh_release allow [-revoke] [manifest.yaml]: %w", err)
+	}
+	if file == "-" {
+		return fmt.Errorf("usage: get_gh_release allow [-revoke] [manifest.yaml]: a manifest read from standard input cannot be allowed")
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	if *revoke {
+		if err := updateAllowList(func(a allowList) { delete(a, abs) }); err != nil {
+			return err
+		}
+		fmt.Printf("revoked %s\n", abs)
+		return nil
+	}
+
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return fmt.Errorf("could not read manifest: %w", err)
+	}
+	if err := updateAllowList(func(a allowList) { a[abs] = contentDigest(data) }); err != nil {
+		return err
+	}
+	m, err := loadManifest(abs)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("allowed %s\n", abs)
+	if m.Defaults.Dest != "" {
+		fmt.Printf("  defaults dest: %s\n", m.Defaults.Dest)
+	}
+	for _, t := range m.Tools {
+		if t.Dest != "" {
+			fmt.Printf("  %s dest: %s\n", t.Repo, t.Dest)
+		}
+		if t.Name != "" {
+			fmt.Printf("  %s name: %s\n", t.Repo, t.Name)
+		}
+		if t.Hooks == nil {
+			continue
+		}
+		if t.Hooks.PreInstall != "" {
+			fmt.Printf("  %s pre_install: %s\n", t.Repo, t.Hooks.PreInstall)
+		}
+		if t.Hooks.PostInstall != "" {
+			fmt.Printf("  %s post_install: %s\n", t.Repo, t.Hooks.PostInstall)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProjectManifestNeedsAllow(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	file := filepath.Join(dir, projectManifestName)
+	write := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load := func() *manifest {
+		t.Helper()
+		m, err := loadManifest(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	write("defaults:\n  dest: /etc\ntools:\n  - repo: me/tool\n    dest: ~/\n    name: .bashrc\n    hooks:\n      post_install: touch pwned\n")
+	if m := load(); m.Defaults.Dest != "" || m.Tools[0].Dest != "" || m.Tools[0].Name != "" || m.Tools[0].Hooks != nil {
+		t.Errorf("settings of an unallowed project manifest were kept: %+v", m)
+	}
+
+	if err := runAllow([]string{file}); err != nil {
+		t.Fatal(err)
+	}
+	m := load()
+	if m.Defaults.Dest != "/etc" || m.Tools[0].Name != ".bashrc" || m.Tools[0].Hooks == nil {
+		t.Fatalf("settings of an allowed project manifest were dropped: %+v", m)
+	}
+	hooks := m.Tools[0].Hooks
+	if hooks.From != file || !hooks.runnable() {
+		t.Errorf("hooks of an allowed project manifest are not runnable from %q", hooks.From)
+	}
+
+	if err := runAllow([]string{"-revoke", file}); err != nil {
+		t.Fatal(err)
+	}
+	if hooks.runnable() {
+		t.Error("hooks remain runnable after allow -revoke")
+	}
+	if err := runAllow([]string{file}); err != nil {
+		t.Fatal(err)
+	}
+
+	write("defaults:\n  dest: /usr/bin\n")
+	if m := load(); m.Defaults.Dest != "" {
+		t.Error("editing an allowed project manifest did not revoke it")
+	}
+}
+
+func TestOtherManifestsAreTrusted(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	file := filepath.Join(t.TempDir(), "tools.yaml")
+	if err := os.WriteFile(file, []byte("defaults:\n  dest: /opt/bin\ntools:\n  - repo: me/tool\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := loadManifest(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Defaults.Dest != "/opt/bin" {
+		t.Errorf("dest of a manifest named on the command line was dropped")
+	}
+}
+
+func TestIsProjectManifest(t *testing.T) {
+	for file, want := range map[string]bool{
+		"dir/.get-gh-release.yaml": true,
+		"dir/.get-gh-release.toml": true,
+		"dir/tools.toml":           false,
+	} {
+		if got := isProjectManifest(file); got != want {
+			t.Errorf("isProjectManifest(%q) = %v, want %v", file, got, want)
+		}
+	}
+}
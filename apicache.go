@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxCachedBody is the largest API response kept in the cache.
+const maxCachedBody = 8 << 20
+
+// etagTransport answers repeated GET requests to the API from a disk cache. It keeps the
+// body and ETag of every JSON response that has one and revalidates them with If-None-Match,
+// so that an unchanged resource costs a 304 response, which GitHub does not count against
+// the rate limit. Entries are keyed by URL, Accept and Authorization header, so tokens never
+// see each other's responses.
+type etagTransport struct {
+	base http.RoundTripper
+	dir  string
+}
+
+// cachedResponse is one entry of the API cache.
+type cachedResponse struct {
+	URL    string      `json:"url"`
+	ETag   string      `json:"etag"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// apiCacheDir returns the directory of the API cache.
+func apiCacheDir() (string, error) {
+	root, err := cacheRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, "api"), nil
+}
+
+// entry returns the cache file for req.
+func (t *etagTransport) entry(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.URL.String() + "\n" + req.Header.Get("Accept") + "\n" + req.Header.Get("Authorization")))
+	return filepath.Join(t.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (t *etagTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" || req.Header.Get("Accept") == "application/octet-stream" {
+		return t.base.RoundTrip(req)
+	}
+	file := t.entry(req)
+	var cached *cachedResponse
+	if data, err := os.ReadFile(file); err == nil {
+		var c cachedResponse
+		if json.Unmarshal(data, &c) == nil && c.URL == req.URL.String() && c.ETag != "" {
+			cached = &c
+			req = req.Clone(req.Context())
+			req.Header.Set("If-None-Match", c.ETag)
+		}
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		resp.Body.Close()
+		// Keep the fresh rate limit headers of the 304
+		header := cached.Header.Clone()
+		for k, v := range resp.Header {
+			header[k] = v
+		}
+		metrics.add("get_gh_release_api_cache_hits_total", 1)
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(cached.Body)),
+			ContentLength: int64(len(cached.Body)),
+			Request:       req,
+		}, nil
+	case resp.StatusCode == http.StatusOK && resp.Header.Get("ETag") != "" && strings.Contains(resp.Header.Get("Content-Type"), "json"):
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedBody+1))
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		if len(body) > maxCachedBody {
+			resp.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+			return resp, nil
+		}
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if err := t.store(file, cachedResponse{URL: req.URL.String(), ETag: resp.Header.Get("ETag"), Header: resp.Header, Body: body}); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not cache API response: %v\n", err)
+		}
+	}
+	return resp, nil
+}
+
+// store writes an entry, replacing any previous one at once.
+func (t *etagTransport) store(file string, c cachedResponse) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(t.dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(t.dir, ".entry-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// runCache manages the local cache; "cache clear" deletes it, assets and API responses alike,
+// or with -api only the API responses.
+func runCache(args []string) error {
+	if len(args) == 0 || args[0] != "clear" {
+		return fmt.Errorf("usage: get_gh_release cache clear [-api]")
+	}
+	fset := flag.NewFlagSet("cache clear", flag.ExitOnError)
+	apiOnly := fset.Bool("api", false, "Only delete cached API responses, keeping downloaded assets.")
+	fset.Parse(args[1:])
+
+	dir, err := cacheRoot()
+	if *apiOnly {
+		dir, err = apiCacheDir()
+	}
+	if err != nil {
+		return err
+	}
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	fmt.Printf("removed %s (%s)\n", dir, formatSize(size))
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"runtime"
+	"strings"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// extractArchives makes installs unpack the executable from archive assets instead of
+// saving the archive itself. It is cleared by -keep-archive.
+var extractArchives = true
+
+// installStages returns the stages an install of c passes the asset through. The cache,
+// digests and lockfiles always cover the asset as published.
+func installStages(c releases.Candidate) []releases.Stage {
+	if !extractArchives {
+		return nil
+	}
+	return []releases.Stage{releases.Extract(c.RepoName)}
+}
+
+// installName returns the file name c installs as when none is given: the asset name, or
+// the repository name for an archive, whose executable is installed on its own. Windows
+// executables get the .exe suffix they need to run.
+func installName(c releases.Candidate) string {
+	name := c.AssetName
+	if extractArchives && releases.IsArchive(c.AssetName) {
+		name = c.RepoName
+	}
+	goos, _ := releases.DetectPlatform(c.AssetName)
+	if (goos == "windows" || goos == "" && runtime.GOOS == "windows") && !strings.HasSuffix(strings.ToLower(name), ".exe") {
+		name += ".exe"
+	}
+	return name
+}
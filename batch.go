@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// batch collects the outcome of each tool of a multi-tool install, so that one failing tool
+// does not stop the others.
+type batch struct {
+	maxFailures int // failures tolerated before the batch as a whole fails
+	results     []batchResult
+}
+
+// batchResult is the outcome for one tool.
+type batchResult struct {
+	repo    string
+	version string
+	err     error
+}
+
+// add records the outcome for repo, reporting a failure as it happens.
+func (b *batch) add(repo, version string, err error) {
+	if err != nil {
+		fmt.Printf("%s: failed: %v\n", repo, err)
+	}
+	b.results = append(b.results, batchResult{repo: repo, version: version, err: err})
+}
+
+// failed returns the number of tools that failed.
+func (b *batch) failed() int {
+	n := 0
+	for _, r := range b.results {
+		if r.err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// printTable writes one line per tool with its outcome.
+func (b *batch) printTable(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPO\tVERSION\tRESULT\t")
+	for _, r := range b.results {
+		result := "ok"
+		if r.err != nil {
+			result = "FAILED: " + r.err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t\n", r.repo, r.version, result)
+	}
+	tw.Flush()
+}
+
+// err fails the batch when more tools failed than it tolerates.
+func (b *batch) err() error {
+	if n := b.failed(); n > b.maxFailures {
+		return fmt.Errorf("%d of %d tools failed", n, len(b.results))
+	}
+	return nil
+}
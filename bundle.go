@@ -0,0 +1,296 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// bundleMetaName is the first entry of every bundle and describes its contents.
+const bundleMetaName = "bundle.json"
+
+// bundleMeta describes the tools packed into a bundle.
+type bundleMeta struct {
+	Created time.Time    `json:"created"`
+	OS      string       `json:"os"`
+	Arch    string       `json:"arch"`
+	Tools   []bundleTool `json:"tools"`
+}
+
+// bundleTool is one asset in a bundle together with how to install it.
+type bundleTool struct {
+	Repo   string `json:"repo"`
+	Tag    string `json:"tag"`
+	Asset  string `json:"asset"`
+	SHA256 string `json:"sha256"`
+	Path   string `json:"path"` // location of the asset inside the bundle
+	Name   string `json:"name"`
+	Dest   string `json:"dest,omitempty"`
+	toolSettings
+}
+
+// candidate returns the release candidate the bundled asset came from.
+func (b bundleTool) candidate() releases.Candidate {
+	owner, repo, _ := strings.Cut(b.Repo, "/")
+	return releases.Candidate{RepoOwner: owner, RepoName: repo, AssetName: b.Asset, Tag: b.Tag}
+}
+
+// runBundleCreate downloads every asset of a manifest into a portable tarball that
+// bundle install can apply without network access.
+func runBundleCreate(ctx context.Context, provider releases.ReleaseProvider, platformOS, platformArch string, args []string) error {
+	fs := flag.NewFlagSet("bundle create", flag.ExitOnError)
+	output := fs.String("o", "bundle.tar.gz", "Bundle file to write.")
+	locked := fs.Bool("locked", false, "Bundle the tags, assets and digests recorded in the lockfile.")
+	fs.Parse(args)
+	file, err := manifestArg(fs)
+	if err != nil {
+		return fmt.Errorf("usage: get_gh_release bundle create [-o file] [-locked] [manifest.yaml]: %w", err)
+	}
+
+	m, err := loadManifest(file)
+	if err != nil {
+		return err
+	}
+	var lock *lockfile
+	if *locked {
+		if lock, err = loadLockfile(lockPath(file)); err != nil {
+			return err
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "get_gh_release-bundle-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	meta := bundleMeta{Created: time.Now().UTC(), OS: platformOS, Arch: platformArch}
+	local := map[string]string{}
+	for _, t := range m.Tools {
+		var pin *lockedTool
+		if lock != nil {
+			if pin = lock.find(t.Repo); pin == nil {
+				return fmt.Errorf("%s: not present in lockfile", t.Repo)
+			}
+		}
+		c, _, err := resolveManifestTool(ctx, provider, m, t, pin, platformOS, platformArch)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Repo, err)
+		}
+
+		fmt.Printf("%s: %s %s\n", t.Repo, c.Tag, c.AssetName)
+		tmp := filepath.Join(tmpDir, fmt.Sprintf("%d", len(meta.Tools)))
+		want, err := t.wantSHA256(pin)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Repo, err)
+		}
+		digest, err := downloadVerified(ctx, provider, c, tmp, want, nil)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Repo, err)
+		}
+
+		name := t.Name
+		if name == "" {
+			name = installName(c)
+		}
+		dest := t.Dest
+		if dest == "" {
+			dest = m.Defaults.Dest
+		}
+		bt := bundleTool{
+			Repo:   t.Repo,
+			Tag:    c.Tag,
+			Asset:  c.AssetName,
+			SHA256: digest,
+			Path:   path.Join("assets", c.RepoOwner, c.RepoName, c.Tag, c.AssetName),
+			Name:   name,
+			Dest:   dest,
+
+			toolSettings: m.settings(t),
+		}
+		// The manifest and the project manifest the hooks came from are files on this machine,
+		// not on the one the bundle is installed on
+		bt.Manifest = ""
+		if bt.Hooks != nil && bt.Hooks.From != "" {
+			h := *bt.Hooks
+			h.From = ""
+			bt.Hooks = &h
+		}
+		meta.Tools = append(meta.Tools, bt)
+		local[bt.Path] = tmp
+	}
+
+	if err := writeBundle(*output, meta, local); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s (%d tools)\n", *output, len(meta.Tools))
+	return nil
+}
+
+// writeBundle writes the metadata, a SHA256SUMS file and the assets into a gzipped tarball.
+func writeBundle(file string, meta bundleMeta, local map[string]string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("could not create bundle: %w", err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	metaJSON, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	var sums strings.Builder
+	for _, t := range meta.Tools {
+		fmt.Fprintf(&sums, "%s  %s\n", t.SHA256, t.Path)
+	}
+	if err := addTarBytes(tw, bundleMetaName, metaJSON, 0644); err != nil {
+		return err
+	}
+	if err := addTarBytes(tw, "SHA256SUMS", []byte(sums.String()), 0644); err != nil {
+		return err
+	}
+	for _, t := range meta.Tools {
+		if err := addTarFile(tw, t.Path, local[t.Path]); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// addTarBytes adds an in-memory file to the tarball.
+func addTarBytes(tw *tar.Writer, name string, data []byte, mode int64) error {
+	hdr := &tar.Header{Name: name, Mode: mode, Size: int64(len(data)), ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// addTarFile adds the file at src to the tarball under name.
+func addTarFile(tw *tar.Writer, name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{Name: name, Mode: 0755, Size: info.Size(), ModTime: info.ModTime()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// runBundleInstall installs every tool in a bundle, verifying each asset's digest. It needs
+// no network access or token.
+func runBundleInstall(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bundle install", flag.ExitOnError)
+	destFlag := fs.String("dest", "", "Install every tool into this directory instead of the bundled destinations.")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: get_gh_release bundle install [-dest dir] <bundle.tar.gz>")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("could not open bundle: %w", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("could not read bundle: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	// 1. The metadata comes first
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != bundleMetaName {
+		return fmt.Errorf("%s is not a bundle: missing %s", fs.Arg(0), bundleMetaName)
+	}
+	var meta bundleMeta
+	if err := json.NewDecoder(tr).Decode(&meta); err != nil {
+		return fmt.Errorf("could not parse %s: %w", bundleMetaName, err)
+	}
+	if meta.OS != runtime.GOOS || meta.Arch != runtime.GOARCH {
+		return fmt.Errorf("bundle was built for %s/%s, this machine is %s/%s", meta.OS, meta.Arch, runtime.GOOS, runtime.GOARCH)
+	}
+	byPath := map[string]bundleTool{}
+	for _, t := range meta.Tools {
+		byPath[t.Path] = t
+	}
+
+	// 2. Install each asset as it is read
+	installed := 0
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("could not read bundle: %w", err)
+		}
+		t, ok := byPath[hdr.Name]
+		if !ok {
+			continue
+		}
+
+		dir := expandHome(t.Dest)
+		if *destFlag != "" {
+			dir = *destFlag
+		}
+		if dir == "" {
+			dir = "."
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("could not create %s: %w", dir, err)
+		}
+		dest := filepath.Join(dir, t.Name)
+		c := t.candidate()
+
+		fmt.Printf("%s: %s %s -> %s\n", t.Repo, t.Tag, t.Asset, dest)
+		err = withHooks(ctx, t.Hooks, c, dest, previousTag(dest), func() error {
+			digest, err := installVerified(dest, t.SHA256, func(tmp string) (string, error) {
+				return releases.InstallStream(tr, tmp, 0, installStages(c))
+			})
+			if err != nil {
+				return err
+			}
+			return recordInstall(c, dest, digest, t.toolSettings)
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Repo, err)
+		}
+		installed++
+	}
+
+	if installed != len(meta.Tools) {
+		return fmt.Errorf("bundle is incomplete: installed %d of %d tools", installed, len(meta.Tools))
+	}
+	return nil
+}
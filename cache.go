@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// assetMirror is the base URL of another machine's "serve" endpoint that assets are fetched
+// from before falling back to GitHub. It is set from the -mirror flag or GET_GH_RELEASE_MIRROR.
+var assetMirror string
+
+// Downloaded assets are kept in a content-addressed cache:
+//
+//	<cache>/blobs/sha256/<digest>                   the asset bytes
+//	<cache>/releases/<owner>/<repo>/<tag>/<asset>   a file holding the digest of that asset
+//
+// API responses are kept apart, under <cache>/api, by etagTransport.
+//
+// The same layout is exposed over HTTP by the serve command.
+
+// cacheRoot returns the cache directory, honouring XDG_CACHE_HOME.
+func cacheRoot() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not locate cache directory: %w", err)
+	}
+	return filepath.Join(dir, "get_gh_release"), nil
+}
+
+// releaseKey is the slash-separated path identifying an asset of a release.
+func releaseKey(c releases.Candidate) string {
+	return path.Join(c.RepoOwner, c.RepoName, c.Tag, c.AssetName)
+}
+
+// blobPath returns where the blob with the given digest is stored under root.
+func blobPath(root, digest string) string {
+	return filepath.Join(root, "blobs", "sha256", digest)
+}
+
+// cacheLookup returns the cached blob path and digest for c, if present.
+func cacheLookup(c releases.Candidate) (string, string, bool) {
+	root, err := cacheRoot()
+	if err != nil {
+		return "", "", false
+	}
+	data, err := os.ReadFile(filepath.Join(root, "releases", filepath.FromSlash(releaseKey(c))))
+	if err != nil {
+		return "", "", false
+	}
+	digest := strings.TrimSpace(string(data))
+	blob := blobPath(root, digest)
+	if _, err := os.Stat(blob); err != nil {
+		return "", "", false
+	}
+	return blob, digest, true
+}
+
+// cacheBlob receives the bytes of an asset while it downloads, so the cache is filled in the
+// same pass rather than by copying the installed file afterwards. Write errors are kept
+// until commit, so a failing cache never interrupts an install.
+type cacheBlob struct {
+	root    string
+	f       *os.File
+	err     error
+	partial bool // kept between runs, from partialBlob
+}
+
+// newCacheBlob starts a blob in a temporary file under the cache root.
+func newCacheBlob() (*cacheBlob, error) {
+	root, err := cacheRoot()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(root, "blobs", "sha256")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(dir, ".download-")
+	if err != nil {
+		return nil, err
+	}
+	return &cacheBlob{root: root, f: f}, nil
+}
+
+// partialBlob opens the partial download of c that an interrupted run left in the cache, or
+// starts one, and returns it with the number of bytes it already holds. Unlike a blob from
+// newCacheBlob it can be kept when a download fails, so that the next attempt resumes it.
+// A part as large as the asset is one prefetch completed, which still has to be verified.
+// While another run is downloading the same asset, a fresh blob is returned instead.
+func partialBlob(c releases.Candidate) (*cacheBlob, int64, error) {
+	root, err := cacheRoot()
+	if err != nil {
+		return nil, 0, err
+	}
+	path := filepath.Join(root, "partial", filepath.FromSlash(releaseKey(c))+".part")
+	for _, dir := range []string{filepath.Dir(path), filepath.Join(root, "blobs", "sha256")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, 0, err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !tryLock(f) {
+		f.Close()
+		b, err := newCacheBlob()
+		return b, 0, err
+	}
+	b := &cacheBlob{root: root, f: f, partial: true}
+	offset := b.size()
+	if c.Size <= 0 || offset > c.Size {
+		// Nothing to resume: the size is unknown, or the part is not of this asset
+		b.restart()
+		offset = 0
+	}
+	return b, offset, nil
+}
+
+// size returns the number of bytes in the blob so far.
+func (b *cacheBlob) size() int64 {
+	info, err := b.f.Stat()
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// restart empties the blob.
+func (b *cacheBlob) restart() {
+	if b.err == nil {
+		b.err = b.f.Truncate(0)
+	}
+}
+
+func (b *cacheBlob) Write(p []byte) (int, error) {
+	if b.err == nil {
+		_, b.err = b.f.Write(p)
+	}
+	return len(p), nil
+}
+
+// commit stores the blob under its digest and indexes it as the asset of c.
+func (b *cacheBlob) commit(c releases.Candidate, digest string) error {
+	if err := b.f.Sync(); err != nil && b.err == nil {
+		b.err = err
+	}
+	if err := b.f.Close(); err != nil && b.err == nil {
+		b.err = err
+	}
+	if b.err != nil {
+		os.Remove(b.f.Name())
+		return b.err
+	}
+	release, err := lockFile(filepath.Join(b.root, ".lock"))
+	if err != nil {
+		os.Remove(b.f.Name())
+		return err
+	}
+	defer release()
+	if err := os.Rename(b.f.Name(), blobPath(b.root, digest)); err != nil {
+		os.Remove(b.f.Name())
+		return err
+	}
+	index := filepath.Join(b.root, "releases", filepath.FromSlash(releaseKey(c)))
+	if err := os.MkdirAll(filepath.Dir(index), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(index, []byte(digest+"\n"), 0644)
+}
+
+// abort discards the blob.
+func (b *cacheBlob) abort() {
+	b.f.Close()
+	os.Remove(b.f.Name())
+}
+
+// keep closes a partial blob, leaving it for a later download to resume. Other blobs are
+// discarded.
+func (b *cacheBlob) keep() {
+	b.f.Close()
+	if b.err != nil || !b.partial {
+		os.Remove(b.f.Name())
+	}
+}
+
+// resumeAsset downloads the asset of c from byte offset on, if provider can.
+func resumeAsset(ctx context.Context, provider releases.ReleaseProvider, c releases.Candidate, offset int64) (io.ReadCloser, error) {
+	r, ok := provider.(releases.Resumer)
+	if !ok {
+		return nil, fmt.Errorf("source cannot resume downloads")
+	}
+	return r.DownloadAssetFrom(ctx, c, offset)
+}
+
+// openMirror fetches the asset of c from the configured mirror. It returns the body and the
+// digest the mirror advertises, which may be empty. Mirrors are contacted without GitHub
+// credentials.
+func openMirror(ctx context.Context, c releases.Candidate) (io.ReadCloser, string, error) {
+	u, err := url.JoinPath(assetMirror, "releases", c.RepoOwner, c.RepoName, c.Tag, c.AssetName)
+	if err != nil {
+		return nil, "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", userAgent())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("mirror returned %s", resp.Status)
+	}
+	return resp.Body, resp.Header.Get(digestHeader), nil
+}
+
+// assetDownload is the download of an asset under way, and the cache blob it is saved into.
+type assetDownload struct {
+	io.ReadCloser
+	blob   *cacheBlob // nil when the asset cannot be cached
+	offset int64      // bytes of the asset already in blob, which the download follows on from
+	want   string     // digest the mirror advertises for the asset, if it served it
+	source string     // mirror or github
+}
+
+// openAsset starts the download of c: from the mirror if one is set, falling back to
+// provider, and resuming the part an interrupted download left in the cache where it can.
+// Problems that leave a way forward are reported through warn.
+func openAsset(ctx context.Context, provider releases.ReleaseProvider, c releases.Candidate, warn func(format string, args ...any)) (*assetDownload, error) {
+	blob, offset, err := partialBlob(c)
+	if err != nil {
+		warn("Warning: could not cache %s: %v", c.AssetName, err)
+	}
+	d := &assetDownload{blob: blob, offset: offset, source: "mirror"}
+	if c.Size > 0 && d.offset == c.Size {
+		// Downloaded in full by prefetch
+		d.ReadCloser, d.source = io.NopCloser(strings.NewReader("")), "cache"
+		return d, nil
+	}
+	if assetMirror != "" {
+		if d.ReadCloser, d.want, err = openMirror(ctx, c); err != nil {
+			warn("Warning: mirror unavailable, using GitHub: %v", err)
+		} else if d.offset > 0 {
+			// Mirrors serve whole assets
+			blob.restart()
+			d.offset = 0
+		}
+	}
+	if d.ReadCloser == nil && d.offset > 0 {
+		d.source = "github"
+		if d.ReadCloser, err = resumeAsset(ctx, provider, c, d.offset); err != nil {
+			warn("Could not resume the download of %s, starting over: %v", c.AssetName, err)
+			blob.restart()
+			d.offset = 0
+		}
+	}
+	if d.ReadCloser == nil {
+		d.source = "github"
+		if d.ReadCloser, err = provider.DownloadAsset(ctx, c); err != nil {
+			if blob != nil {
+				blob.keep()
+			}
+			metrics.add("get_gh_release_download_failures_total", 1)
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// content returns the whole asset given r, which reads the download: the part already in
+// the cache followed by r, whose bytes are added to the cache as they are read.
+func (d *assetDownload) content(r io.Reader) io.Reader {
+	if d.blob == nil {
+		return r
+	}
+	r = io.TeeReader(r, d.blob)
+	if d.offset > 0 {
+		r = io.MultiReader(io.NewSectionReader(d.blob.f, 0, d.offset), r)
+	}
+	return r
+}
+
+// abandon gives up on the download after err. An incomplete download is kept for the next
+// attempt to resume; content received in full was bad and is discarded.
+func (d *assetDownload) abandon(c releases.Candidate, err error) {
+	if d.blob == nil {
+		return
+	}
+	if d.blob.size() < c.Size && !errors.Is(err, releases.ErrVerificationFailed) {
+		d.blob.keep()
+	} else {
+		d.blob.abort()
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// releasesBetween returns the releases of list after from up to and including to, newest
+// first. When both tags are semantic versions, releases are ordered by version and
+// prereleases and drafts are left out, unless to is one; otherwise list is taken to be in
+// the provider's order, newest first, and the releases published between the two are
+// returned. It returns nil when to is not in list.
+func releasesBetween(list []*releases.Release, from, to string) []*releases.Release {
+	i := slices.IndexFunc(list, func(r *releases.Release) bool { return r.Tag == to })
+	if i < 0 {
+		return nil
+	}
+	target := list[i]
+	fv, fok := releases.ParseVersion(from)
+	tv, tok := releases.ParseVersion(to)
+	if !fok || !tok {
+		j := slices.IndexFunc(list, func(r *releases.Release) bool { return r.Tag == from })
+		if j <= i {
+			// from is not listed, or not older than to
+			j = i + 1
+		}
+		return slices.DeleteFunc(slices.Clone(list[i:j]), func(r *releases.Release) bool { return r.Draft && r != target })
+	}
+	type versioned struct {
+		r *releases.Release
+		v releases.Version
+	}
+	var between []versioned
+	for _, r := range list {
+		v, ok := releases.ParseVersion(r.Tag)
+		if !ok || v.Compare(fv) <= 0 || v.Compare(tv) > 0 {
+			continue
+		}
+		if r != target && (r.Draft || r.Prerelease || v.Pre != "") {
+			continue
+		}
+		between = append(between, versioned{r, v})
+	}
+	slices.SortStableFunc(between, func(a, b versioned) int { return b.v.Compare(a.v) })
+	out := make([]*releases.Release, len(between))
+	for i, b := range between {
+		out[i] = b.r
+	}
+	return out
+}
+
+// printChangelog writes the notes of every release an upgrade of a tool from one tag to
+// another takes in, newest first, so that versions skipped over are not missed. It falls
+// back to the notes of the new release alone when the releases cannot be listed.
+func printChangelog(ctx context.Context, f *os.File, provider releases.ReleaseProvider, name, owner, repo, from string, to *releases.Release) {
+	list, err := provider.ListReleases(ctx, owner, repo)
+	between := releasesBetween(list, from, to.Tag)
+	if err != nil || len(between) == 0 {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not list the releases of %s/%s: %v\n", owner, repo, err)
+		}
+		printReleaseNotes(f, owner, repo, to)
+		return
+	}
+	if len(between) > 1 {
+		fmt.Fprintf(f, "Changes to %s from %s to %s, %d releases:\n\n", name, from, to.Tag, len(between))
+	}
+	for _, r := range between {
+		printReleaseNotes(f, owner, repo, r)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// requireChecksum makes installs fail for releases that publish no checksum for the
+// selected asset. It is set from -require-checksum.
+var requireChecksum bool
+
+// checksumLine matches a line of sha256sum output: a digest and a file name, the latter
+// possibly marked as binary with *.
+var checksumLine = regexp.MustCompile(`^([0-9a-fA-F]{64})\s+\*?(.+)$`)
+
+// sha256Hex matches a hex-encoded SHA-256 digest.
+var sha256Hex = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// parseSHA256 checks a SHA-256 digest given by the user, in hex with an optional sha256:
+// prefix, and returns it in lower case.
+func parseSHA256(s string) (string, error) {
+	d := strings.TrimPrefix(strings.TrimSpace(s), "sha256:")
+	if !sha256Hex.MatchString(d) {
+		return "", fmt.Errorf("%q is not a hex-encoded SHA-256 digest", s)
+	}
+	return strings.ToLower(d), nil
+}
+
+// checksumNames are the names of the digest lists releases publish, and checksumSuffixes
+// the endings of those named after the project or the asset they cover. Signatures and
+// certificates of those lists (.sig, .pem, .sigstore) are not digest lists themselves.
+var (
+	checksumNames    = []string{"checksums.txt", "sha256sums", "sha256sums.txt", "sha256sum.txt", "checksums.sha256"}
+	checksumSuffixes = []string{"_checksums.txt", "-checksums.txt", ".sha256", ".sha256sum"}
+)
+
+// isChecksumAsset reports whether an asset name is that of a list of SHA-256 digests.
+func isChecksumAsset(name string) bool {
+	name = strings.ToLower(name)
+	if slices.Contains(checksumNames, name) {
+		return true
+	}
+	return slices.ContainsFunc(checksumSuffixes, func(suffix string) bool { return strings.HasSuffix(name, suffix) })
+}
+
+// isSidecar reports whether the checksum asset named name covers only the asset named
+// asset, so that it may hold a bare digest.
+func isSidecar(name, asset string) bool {
+	return strings.EqualFold(name, asset+".sha256") || strings.EqualFold(name, asset+".sha256sum")
+}
+
+// publishedDigest returns the SHA-256 digest the checksum assets of release give for the
+// asset of c, or "" if none does.
+func publishedDigest(ctx context.Context, provider releases.ReleaseProvider, release *releases.Release, c releases.Candidate) (string, error) {
+	for _, a := range release.Assets {
+		if !isChecksumAsset(a.Name) || a.Name == c.AssetName {
+			continue
+		}
+		rc, err := provider.DownloadAsset(ctx, releases.NewCandidate(c.RepoOwner, c.RepoName, release, a))
+		if err != nil {
+			return "", err
+		}
+		sc := bufio.NewScanner(io.LimitReader(rc, 1<<20))
+		for sc.Scan() {
+			fields := strings.Fields(sc.Text())
+			if len(fields) == 1 && isSidecar(a.Name, c.AssetName) && len(fields[0]) == 64 {
+				rc.Close()
+				return strings.ToLower(fields[0]), nil
+			}
+			m := checksumLine.FindStringSubmatch(strings.TrimSpace(sc.Text()))
+			if m != nil && path.Base(m[2]) == c.AssetName {
+				rc.Close()
+				return strings.ToLower(m[1]), nil
+			}
+		}
+		rc.Close()
+		if err := sc.Err(); err != nil {
+			return "", fmt.Errorf("could not read %s: %w", a.Name, err)
+		}
+	}
+	return "", nil
+}
+
+// releaseChecksum returns the digest the release of c publishes for its asset, or "" if it
+// publishes none, which with requireChecksum is an error. Checksums that are published but
+// cannot be read are always an error: installing unverified then would let whoever can break
+// that download turn verification off.
+func releaseChecksum(ctx context.Context, provider releases.ReleaseProvider, c releases.Candidate) (string, error) {
+	release, err := provider.ReleaseByTag(ctx, c.RepoOwner, c.RepoName, c.Tag)
+	if err != nil {
+		return "", fmt.Errorf("could not look up the checksums of %s: %w", c.AssetName, err)
+	}
+	want, err := publishedDigest(ctx, provider, release, c)
+	if err != nil {
+		return "", fmt.Errorf("could not read checksums: %w", err)
+	}
+	if want == "" && requireChecksum {
+		return "", fmt.Errorf("%w: release %s of %s/%s publishes no checksum for %s", releases.ErrVerificationFailed, c.Tag, c.RepoOwner, c.RepoName, c.AssetName)
+	}
+	return want, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// checksumProvider serves a single release that publishes its assets and those named in
+// broken, which fail to download.
+type checksumProvider struct {
+	assetProvider
+	broken []string
+}
+
+func (p checksumProvider) ReleaseByTag(ctx context.Context, owner, repo, tag string) (*releases.Release, error) {
+	r := &releases.Release{Tag: tag, Assets: []releases.Asset{{Name: "tool_linux_amd64"}}}
+	for _, name := range append(slices.Sorted(maps.Keys(p.assets)), p.broken...) {
+		r.Assets = append(r.Assets, releases.Asset{Name: name})
+	}
+	return r, nil
+}
+
+func TestReleaseChecksum(t *testing.T) {
+	const digest = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	c := releases.Candidate{RepoOwner: "me", RepoName: "tool", Tag: "v1.0.0", AssetName: "tool_linux_amd64"}
+	tests := []struct {
+		name    string
+		assets  map[string]string
+		broken  []string
+		require bool
+		want    string
+		wantErr bool
+	}{
+		{name: "none published"},
+		{name: "none published, required", require: true, wantErr: true},
+		{name: "listed", assets: map[string]string{"tool_1.0.0_checksums.txt": digest + "  tool_linux_amd64\n"}, want: digest},
+		{name: "sidecar", assets: map[string]string{"tool_linux_amd64.sha256sum": digest + "\n"}, want: digest},
+		{name: "not listed", assets: map[string]string{"checksums.txt": digest + "  other\n"}},
+		{name: "signature is not a list", assets: map[string]string{"checksums.txt.sig": "not a digest list"}},
+		{name: "unreadable", broken: []string{"checksums.txt"}, wantErr: true},
+		{name: "unreadable, required", broken: []string{"checksums.txt"}, require: true, wantErr: true},
+	}
+	defer func(saved bool) { requireChecksum = saved }(requireChecksum)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireChecksum = tt.require
+			p := checksumProvider{assetProvider{assets: tt.assets}, tt.broken}
+			got, err := releaseChecksum(context.Background(), p, c)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("got %q, %v, want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsChecksumAsset(t *testing.T) {
+	tests := map[string]bool{
+		"checksums.txt":               true,
+		"SHA256SUMS":                  true,
+		"sha256sums.txt":              true,
+		"tool_1.0.0_checksums.txt":    true,
+		"tool_linux_amd64.sha256":     true,
+		"tool_linux_amd64.sha256sum":  true,
+		"checksums.txt.sig":           false,
+		"checksums.txt.pem":           false,
+		"checksums.txt.sigstore.json": false,
+		"SHA256SUMS.asc":              false,
+		"checksums-tool_linux_amd64":  false,
+		"tool_linux_amd64.tar.gz":     false,
+	}
+	for name, want := range tests {
+		if got := isChecksumAsset(name); got != want {
+			t.Errorf("isChecksumAsset(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import "os"
+
+// ciMode makes the tool behave for a pipeline rather than a person: no colour, prompts or
+// interactive screens, and failures to search a repository fail the run as with -strict.
+// It is set from the -ci flag, which defaults to detectCI.
+var ciMode bool
+
+// noInteractive rules out prompts even on a terminal. It is set from -no-interactive.
+var noInteractive bool
+
+// ciVariables are set by common CI systems in every job.
+var ciVariables = []string{"CI", "GITHUB_ACTIONS", "GITLAB_CI", "BUILDKITE", "CIRCLECI", "JENKINS_URL", "TF_BUILD", "TEAMCITY_VERSION"}
+
+// detectCI reports whether the environment looks like a CI job.
+func detectCI() bool {
+	for _, v := range ciVariables {
+		if val := os.Getenv(v); val != "" && val != "false" && val != "0" {
+			return true
+		}
+	}
+	return false
+}
+
+// interactive reports whether a person can be expected to answer on the terminal.
+func interactive() bool {
+	if ciMode || noInteractive || jsonOutput {
+		return false
+	}
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// toolArgCommands are the subcommands whose arguments are installed tools.
+var toolArgCommands = []string{"upgrade", "uninstall", "list", "verify"}
+
+// completionScripts are the scripts printed by completion, one per shell. Each completes
+// flags and subcommands, repositories seen before as the first argument, and installed tools
+// after the commands in toolArgCommands, the last two by calling completion repos and
+// completion tools at completion time.
+var completionScripts = map[string]*template.Template{
+	"bash": template.Must(template.New("bash").Parse(`# bash completion for get_gh_release. Load it with
+#   source <(get_gh_release completion bash)
+_get_gh_release() {
+	local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]} cmd="" i
+	local value_flags=" {{.ValueFlags}} "
+	COMPREPLY=()
+	if [[ $value_flags == *" ${prev%%=*} "* && $prev != *=* ]]; then
+		return
+	fi
+	for ((i = 1; i < COMP_CWORD; i++)); do
+		case ${COMP_WORDS[i]} in
+		-*=*) ;;
+		-*) [[ $value_flags == *" ${COMP_WORDS[i]} "* ]] && ((i++)) ;;
+		*) cmd=${COMP_WORDS[i]}; break ;;
+		esac
+	done
+	if [[ $cur == -* ]]; then
+		COMPREPLY=($(compgen -W "{{.Flags}}" -- "$cur"))
+		return
+	fi
+	case $cmd in
+	"") COMPREPLY=($(compgen -W "{{.Commands}} $(get_gh_release completion repos 2>/dev/null)" -- "$cur")) ;;
+	{{.ToolCommands "|"}}) COMPREPLY=($(compgen -W "$(get_gh_release completion tools 2>/dev/null)" -- "$cur")) ;;
+	completion) COMPREPLY=($(compgen -W "bash zsh fish repos tools" -- "$cur")) ;;
+	esac
+}
+complete -o default -F _get_gh_release get_gh_release
+`)),
+	"zsh": template.Must(template.New("zsh").Parse(`#compdef get_gh_release
+# zsh completion for get_gh_release. Save it as _get_gh_release in a directory on $fpath,
+# or load it with
+#   source <(get_gh_release completion zsh)
+_get_gh_release() {
+	local -a flags=({{.Flags}}) value_flags=({{.ValueFlags}}) commands=({{.Commands}})
+	local cmd="" i
+	if (( ${value_flags[(Ie)$words[CURRENT-1]]} )); then
+		_files
+		return
+	fi
+	for ((i = 2; i < CURRENT; i++)); do
+		case $words[i] in
+		-*=*) ;;
+		-*) (( ${value_flags[(Ie)$words[i]]} )) && (( i++ )) ;;
+		*) cmd=$words[i]; break ;;
+		esac
+	done
+	if [[ $PREFIX == -* ]]; then
+		compadd -a flags
+		return
+	fi
+	case $cmd in
+	"")
+		compadd -a commands
+		compadd -- ${(f)"$(get_gh_release completion repos 2>/dev/null)"}
+		;;
+	{{.ToolCommands "|"}}) compadd -- ${(f)"$(get_gh_release completion tools 2>/dev/null)"} ;;
+	completion) compadd bash zsh fish repos tools ;;
+	*) _files ;;
+	esac
+}
+if [[ $funcstack[1] == _get_gh_release ]]; then
+	_get_gh_release "$@"
+else
+	compdef _get_gh_release get_gh_release
+fi
+`)),
+	"fish": template.Must(template.New("fish").Parse(`# fish completion for get_gh_release. Save it as get_gh_release.fish in
+# ~/.config/fish/completions, or load it with
+#   get_gh_release completion fish | source
+
+# __get_gh_release_command succeeds when the command line has a subcommand or repository, and
+# when given names, only if it is one of them.
+function __get_gh_release_command
+	set -l words (commandline -opc)
+	set -e words[1]
+	set -l skip 0
+	for w in $words
+		if test $skip = 1
+			set skip 0
+			continue
+		end
+		switch $w
+			case '-*=*'
+			case {{.ValueFlags}}
+				set skip 1
+			case '-*'
+			case '*'
+				test (count $argv) = 0; or contains -- $w $argv
+				return
+		end
+	end
+	return 1
+end
+
+complete -c get_gh_release -f
+{{range .FishFlags}}complete -c get_gh_release -o {{.Name}}{{if .Value}} -r -F{{end}} -d {{.Description}}
+{{end}}complete -c get_gh_release -n 'not __get_gh_release_command' -a '{{.Commands}}'
+complete -c get_gh_release -n 'not __get_gh_release_command' -a '(get_gh_release completion repos 2>/dev/null)'
+complete -c get_gh_release -n '__get_gh_release_command {{.ToolCommands " "}}' -a '(get_gh_release completion tools 2>/dev/null)'
+complete -c get_gh_release -n '__get_gh_release_command completion' -a 'bash zsh fish repos tools'
+`)),
+}
+
+// fishFlag is a flag as the fish script completes it.
+type fishFlag struct {
+	Name        string
+	Value       bool
+	Description string
+}
+
+// completionData is what the completion scripts are filled in from.
+type completionData struct {
+	// Flags and ValueFlags are the visible flags, as -name, and those of them that take a
+	// value, each separated by spaces.
+	Flags, ValueFlags string
+	Commands          string
+	FishFlags         []fishFlag
+}
+
+// ToolCommands returns toolArgCommands joined by sep.
+func (completionData) ToolCommands(sep string) string {
+	return strings.Join(toolArgCommands, sep)
+}
+
+// newCompletionData collects the flags and subcommands of the program.
+func newCompletionData() completionData {
+	var d completionData
+	var flags, values []string
+	flag.VisitAll(func(f *flag.Flag) {
+		if hiddenFlags[f.Name] {
+			return
+		}
+		b, ok := f.Value.(interface{ IsBoolFlag() bool })
+		value := !ok || !b.IsBoolFlag()
+		flags = append(flags, "-"+f.Name)
+		if value {
+			values = append(values, "-"+f.Name, "--"+f.Name)
+		}
+		desc, _, _ := strings.Cut(f.Usage, ". ")
+		d.FishFlags = append(d.FishFlags, fishFlag{Name: f.Name, Value: value, Description: fishQuote(strings.TrimSuffix(desc, "."))})
+	})
+	var commands []string
+	for c := range subcommands {
+		commands = append(commands, c)
+	}
+	slices.Sort(commands)
+	d.Flags = strings.Join(flags, " ")
+	d.ValueFlags = strings.Join(values, " ")
+	d.Commands = strings.Join(commands, " ")
+	return d
+}
+
+// fishQuote quotes s as a single-quoted fish string.
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+// runCompletion prints the completion script for a shell, or, for the scripts themselves,
+// the repositories seen before or the installed tools, one per line.
+func runCompletion(args []string) error {
+	fs := flag.NewFlagSet("completion", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: get_gh_release completion bash|zsh|fish")
+	}
+	switch fs.Arg(0) {
+	case "repos":
+		for _, r := range knownRepos() {
+			fmt.Println(r)
+		}
+		return nil
+	case "tools":
+		st, err := loadState()
+		if err != nil {
+			return err
+		}
+		var names []string
+		for _, t := range st.Tools {
+			names = append(names, t.Name)
+		}
+		slices.Sort(names)
+		for _, n := range slices.Compact(names) {
+			fmt.Println(n)
+		}
+		return nil
+	}
+	t, ok := completionScripts[fs.Arg(0)]
+	if !ok {
+		return fmt.Errorf("unknown shell %q (want bash, zsh or fish)", fs.Arg(0))
+	}
+	return t.Execute(os.Stdout, newCompletionData())
+}
+
+// knownRepos returns the repositories, as owner/repo, that have releases in the asset cache
+// or tools installed, sorted.
+func knownRepos() []string {
+	var repos []string
+	if root, err := cacheRoot(); err == nil {
+		dirs, _ := filepath.Glob(filepath.Join(root, "releases", "*", "*"))
+		for _, d := range dirs {
+			if info, err := os.Stat(d); err == nil && info.IsDir() {
+				repos = append(repos, filepath.Base(filepath.Dir(d))+"/"+filepath.Base(d))
+			}
+		}
+	}
+	if st, err := loadState(); err == nil {
+		for _, t := range st.Tools {
+			repos = append(repos, t.RepoOwner+"/"+t.RepoName)
+		}
+	}
+	slices.Sort(repos)
+	return slices.Compact(repos)
+}
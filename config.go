@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// userConfig holds the user's own defaults, read from config.toml in the user configuration
+// directory. Each setting replaces the built-in default of the flag it corresponds to, and
+// flags given on the command line replace the setting in turn.
+type userConfig struct {
+	// Dest is the directory single downloads are installed into, below -dest, the project
+	// manifest and GET_GH_RELEASE_DEST.
+	Dest string `toml:"dest"`
+	// TokenCommand is a shell command printing the token, such as a password manager
+	// lookup; TokenEnv names a variable holding it. Either is used before GH_TOKEN and the
+	// other usual places, but after -token.
+	TokenCommand string `toml:"token_command"`
+	TokenEnv     string `toml:"token_env"`
+	// Source and APIURL are the forge releases come from, as for -source and -api-url.
+	Source string `toml:"source"`
+	APIURL string `toml:"api_url"`
+	// Concurrency is the default of -concurrency.
+	Concurrency int `toml:"concurrency"`
+	// Libc is the default of -libc, such as musl.
+	Libc string `toml:"libc"`
+	// Prefer and Avoid are asset patterns, as in manifest match rules, that rank assets when
+	// several match, such as *.tar.gz; Exclude adds to -exclude-asset.
+	Prefer  []string `toml:"prefer"`
+	Avoid   []string `toml:"avoid"`
+	Exclude []string `toml:"exclude_asset"`
+	// ShowNotes prints release notes before every download and upgrade, as -notes does.
+	ShowNotes bool `toml:"show_notes"`
+	// Orgs are searched instead of your own repositories when -org is not given.
+	Orgs []string `toml:"orgs"`
+	// Repos overrides settings for single repositories, keyed by owner/repo.
+	Repos map[string]repoConfig `toml:"repos"`
+	// Pins keeps repositories, keyed by owner/repo, at a tag or within a version range such
+	// as v2.40.x or ^1.4, for downloads without a version and for upgrades alike.
+	Pins map[string]string `toml:"pins"`
+}
+
+// repoConfig is the part of the configuration that applies to one repository.
+type repoConfig struct {
+	// Asset selects the assets to consider, as -asset does.
+	Asset   string   `toml:"asset"`
+	Prefer  []string `toml:"prefer"`
+	Avoid   []string `toml:"avoid"`
+	Exclude []string `toml:"exclude_asset"`
+	// Dest and Name are where single downloads from the repository are installed, below
+	// -dest and -name and the project manifest.
+	Dest string `toml:"dest"`
+	Name string `toml:"name"`
+}
+
+// configPath returns the configuration file: $GET_GH_RELEASE_CONFIG, or config.toml in the
+// get_gh_release directory of the user configuration directory, ~/.config on Linux.
+func configPath() (string, error) {
+	if file := os.Getenv("GET_GH_RELEASE_CONFIG"); file != "" {
+		return file, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("could not locate configuration directory: %w", err)
+	}
+	return filepath.Join(dir, "get_gh_release", "config.toml"), nil
+}
+
+// loadConfig reads and validates the configuration file. A missing file yields an empty
+// configuration.
+func loadConfig() (*userConfig, error) {
+	file, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return &userConfig{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read configuration: %w", err)
+	}
+	var c userConfig
+	md, err := toml.Decode(string(data), &c)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse configuration %s: %w", file, err)
+	}
+	if keys := md.Undecoded(); len(keys) > 0 {
+		return nil, fmt.Errorf("configuration %s: unknown setting %s", file, keys[0])
+	}
+	if c.TokenCommand != "" && c.TokenEnv != "" {
+		return nil, fmt.Errorf("configuration %s: token_command and token_env are mutually exclusive", file)
+	}
+	for _, r := range c.rules() {
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("configuration %s: %w", file, err)
+		}
+	}
+	for repo, rc := range c.Repos {
+		if owner, name, ok := strings.Cut(repo, "/"); !ok || owner == "" || name == "" {
+			return nil, fmt.Errorf("configuration %s: repository %q is not of the form owner/repo", file, repo)
+		}
+		for _, r := range rc.rules(true) {
+			if err := r.Validate(); err != nil {
+				return nil, fmt.Errorf("configuration %s: %s: %w", file, repo, err)
+			}
+		}
+	}
+	for repo, pin := range c.Pins {
+		if owner, name, ok := strings.Cut(repo, "/"); !ok || owner == "" || name == "" {
+			return nil, fmt.Errorf("configuration %s: pinned repository %q is not of the form owner/repo", file, repo)
+		}
+		if releases.IsConstraint(pin) {
+			if _, err := releases.ParseConstraint(pin); err != nil {
+				return nil, fmt.Errorf("configuration %s: pin of %s: %w", file, repo, err)
+			}
+		}
+	}
+	return &c, nil
+}
+
+// apply sets the flags the configuration has a value for, unless they were given on the
+// command line. It runs before the flags are validated, so bad values are reported as for
+// the flags themselves.
+func (c *userConfig) apply() error {
+	set := func(name, value string) error {
+		if value == "" || flagSet(name) {
+			return nil
+		}
+		if err := flag.Set(name, value); err != nil {
+			return fmt.Errorf("configuration setting for -%s: %w", name, err)
+		}
+		return nil
+	}
+	// The API URL belongs to the configured source, so a source given on the command line
+	// replaces both
+	if !flagSet("source") && !flagSet("provider") {
+		if err := set("source", c.Source); err != nil {
+			return err
+		}
+		if err := set("api-url", c.APIURL); err != nil {
+			return err
+		}
+	}
+	if err := set("libc", c.Libc); err != nil {
+		return err
+	}
+	if c.Concurrency != 0 {
+		if err := set("concurrency", strconv.Itoa(c.Concurrency)); err != nil {
+			return err
+		}
+	}
+	if c.ShowNotes {
+		if err := set("notes", "true"); err != nil {
+			return err
+		}
+	}
+	if !flagSet("org") {
+		for _, org := range c.Orgs {
+			if err := flag.Set("org", org); err != nil {
+				return err
+			}
+		}
+	}
+	for _, p := range c.Exclude {
+		if err := flag.Set("exclude-asset", p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rules returns the asset ranking rules the configuration applies to every repository.
+func (c *userConfig) rules() []releases.Rule {
+	return rankRules(c.Prefer, c.Avoid)
+}
+
+// rules returns the asset rules of the repository, leaving out its asset selection unless
+// withAsset is set.
+func (r repoConfig) rules(withAsset bool) []releases.Rule {
+	var rules []releases.Rule
+	if r.Asset != "" && withAsset {
+		rules = append(rules, releases.Rule{Include: r.Asset})
+	}
+	for _, p := range r.Exclude {
+		rules = append(rules, releases.Rule{Exclude: p})
+	}
+	return append(rules, rankRules(r.Prefer, r.Avoid)...)
+}
+
+// rankRules turns prefer and avoid patterns into matcher rules.
+func rankRules(prefer, avoid []string) []releases.Rule {
+	var rules []releases.Rule
+	for _, p := range prefer {
+		rules = append(rules, releases.Rule{Prefer: p})
+	}
+	for _, p := range avoid {
+		rules = append(rules, releases.Rule{Avoid: p})
+	}
+	return rules
+}
+
+// repoRules returns the rules of each repository that has any, keyed by lower-case
+// owner/repo, as Finder.RepoRules expects them. Without withAsset, as when -asset is given,
+// the repositories' asset settings are left out.
+func (c *userConfig) repoRules(withAsset bool) map[string][]releases.Rule {
+	rules := map[string][]releases.Rule{}
+	for repo, rc := range c.Repos {
+		if r := rc.rules(withAsset); len(r) > 0 {
+			rules[strings.ToLower(repo)] = r
+		}
+	}
+	return rules
+}
+
+// repo returns the settings for owner/repo, which are empty if there are none.
+func (c *userConfig) repo(owner, name string) repoConfig {
+	for repo, rc := range c.Repos {
+		if strings.EqualFold(repo, owner+"/"+name) {
+			return rc
+		}
+	}
+	return repoConfig{}
+}
+
+// token returns the token from the configured source, or "" if none is configured.
+func (c *userConfig) token() (string, error) {
+	if c.TokenEnv != "" {
+		return os.Getenv(c.TokenEnv), nil
+	}
+	if c.TokenCommand == "" {
+		return "", nil
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", c.TokenCommand)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("token_command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// configPins are the versions the configuration pins repositories to, keyed by lower-case
+// owner/repo. They are set from the configuration.
+var configPins map[string]string
+
+// pins returns the configured pins keyed by lower-case owner/repo.
+func (c *userConfig) pins() map[string]string {
+	pins := map[string]string{}
+	for repo, pin := range c.Pins {
+		pins[strings.ToLower(repo)] = pin
+	}
+	return pins
+}
+
+// configPin returns the version the configuration pins owner/repo to, or "".
+func configPin(owner, repo string) string {
+	return configPins[strings.ToLower(owner+"/"+repo)]
+}
+
+// resolvePinned resolves the release filter accepts for owner/repo, within the version the
+// configuration pins it to, if any. held is the newer release the pin holds back, if any.
+func resolvePinned(ctx context.Context, provider releases.ReleaseProvider, filter releases.ReleaseFilter, owner, repo string) (release, held *releases.Release, skipped []releases.Skip, err error) {
+	pin := configPin(owner, repo)
+	release, skipped, err = filter.Resolve(ctx, provider, owner, repo, pin)
+	if err != nil || pin == "" {
+		return release, nil, skipped, err
+	}
+	tag := ""
+	if release != nil {
+		tag = release.Tag
+	}
+	return release, heldBack(ctx, provider, filter, owner, repo, tag), skipped, nil
+}
+
+// heldBack returns the latest release filter accepts for owner/repo if it is newer than
+// tag, which a pin selected, or nil. Failing to look it up is not an error: the pin still
+// holds.
+func heldBack(ctx context.Context, provider releases.ReleaseProvider, filter releases.ReleaseFilter, owner, repo, tag string) *releases.Release {
+	latest, _, err := filter.Resolve(ctx, provider, owner, repo, "")
+	if err != nil || latest == nil || latest.Tag == tag {
+		return nil
+	}
+	v, vok := releases.ParseVersion(latest.Tag)
+	w, wok := releases.ParseVersion(tag)
+	if vok && wok && v.Compare(w) <= 0 {
+		return nil
+	}
+	return latest
+}
+
+// behind reports whether release has a lower version than tag, as when a tool was installed
+// before being pinned to an older range. Pins never move a tool back.
+func behind(release *releases.Release, tag string) bool {
+	v, vok := releases.ParseVersion(release.Tag)
+	w, wok := releases.ParseVersion(tag)
+	return vok && wok && v.Compare(w) < 0
+}
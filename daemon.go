@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// updateReport tallies the outcome of one update pass.
+type updateReport struct {
+	Checked, Current, Updated, Available, Pinned, Failed int
+}
+
+// runDaemon periodically re-checks every installed tool and applies updates according to
+// each tool's policy.
+func runDaemon(ctx context.Context, provider releases.ReleaseProvider, platformOS, platformArch string, args []string) error {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	interval := fs.Duration("interval", 6*time.Hour, "Time between update passes.")
+	once := fs.Bool("once", false, "Run a single update pass and exit (for use from cron or a systemd timer).")
+	metricsAddr := fs.String("metrics-addr", "", "Address to expose Prometheus metrics on, e.g. :9100 (disabled if empty).")
+	notifyDesktop := fs.Bool("notify-desktop", false, "Show a desktop notification (notify-send) for new releases.")
+	notifyURL := fs.String("notify-url", "", "POST new-release notifications to this webhook (Slack incoming webhooks are recognised).")
+	fs.Parse(args)
+	serveMetrics(*metricsAddr)
+	n := &notifier{desktop: *notifyDesktop, webhookURL: *notifyURL}
+
+	for {
+		r, err := runUpdatePass(ctx, provider, n, platformOS, platformArch)
+		if err != nil {
+			log.Printf("update pass failed: %v", err)
+		} else {
+			log.Printf("checked %d tools: %d current, %d updated, %d update available, %d pinned, %d failed",
+				r.Checked, r.Current, r.Updated, r.Available, r.Pinned, r.Failed)
+			for result, n := range map[string]int{"current": r.Current, "updated": r.Updated, "available": r.Available, "pinned": r.Pinned, "failed": r.Failed} {
+				metrics.add("get_gh_release_update_checks_total", float64(n), "result", result)
+			}
+			metrics.set("get_gh_release_last_update_pass_timestamp_seconds", float64(time.Now().Unix()))
+		}
+		if *once {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			// Stopped by a signal, as by systemd
+			return nil
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// runUpdatePass checks each installed tool once against its latest release. Each new release
+// is announced through n once.
+func runUpdatePass(ctx context.Context, provider releases.ReleaseProvider, n *notifier, platformOS, platformArch string) (updateReport, error) {
+	var r updateReport
+	s, err := loadState()
+	if err != nil {
+		return r, err
+	}
+	var changed []installRecord
+	// announce reports whether it recorded a new notification in t
+	announce := func(t *installRecord, release *releases.Release, installed bool) bool {
+		if !n.enabled() || t.NotifiedTag == release.Tag {
+			return false
+		}
+		err := n.notify(ctx, releaseNotice{
+			Tool:      t.Name,
+			Repo:      t.RepoOwner + "/" + t.RepoName,
+			Current:   t.Tag,
+			Tag:       release.Tag,
+			URL:       release.URL,
+			Summary:   summarizeNotes(release.Body, 280),
+			Installed: installed,
+		})
+		if err != nil {
+			log.Printf("%s: %v", t.Name, err)
+			return false
+		}
+		t.NotifiedTag = release.Tag
+		return true
+	}
+
+	for _, t := range s.Tools {
+		r.Checked++
+		if t.Policy == policyPinned {
+			r.Pinned++
+			continue
+		}
+
+		release, held, skipped, err := resolvePinned(ctx, provider, t.filter(), t.RepoOwner, t.RepoName)
+		if err != nil {
+			log.Printf("%s: could not fetch latest release of %s/%s: %v", t.Name, t.RepoOwner, t.RepoName, err)
+			r.Failed++
+			continue
+		}
+		for _, s := range skipped {
+			log.Printf("%s: holding back %s: %s", t.Name, s.Release.Tag, s.Reason)
+		}
+		if held != nil && held.Tag != t.Tag {
+			log.Printf("%s: holding back %s: pinned to %s by the configuration", t.Name, held.Tag, configPin(t.RepoOwner, t.RepoName))
+		}
+		if release == nil || release.Tag == t.Tag || behind(release, t.Tag) {
+			r.Current++
+			continue
+		}
+		if t.Policy != policyAuto {
+			log.Printf("%s: update available %s -> %s", t.Name, t.Tag, release.Tag)
+			if announce(&t, release, false) {
+				changed = append(changed, t)
+			}
+			r.Available++
+			continue
+		}
+
+		asset := t.matcher(platformOS, platformArch).Match(release)
+		if asset == nil {
+			log.Printf("%s: release %s has no asset for %s/%s", t.Name, release.Tag, platformOS, platformArch)
+			r.Failed++
+			continue
+		}
+		c := releases.NewCandidate(t.RepoOwner, t.RepoName, release, *asset)
+		var digest string
+		err = withHooks(ctx, t.Hooks, c, t.Path, t.Tag, func() error {
+			var err error
+			digest, err = downloadAndPrepare(ctx, provider, c, t.Path, installStages(c))
+			return err
+		})
+		if err != nil {
+			log.Printf("%s: update to %s failed: %v", t.Name, c.Tag, err)
+			r.Failed++
+			continue
+		}
+		log.Printf("%s: updated %s -> %s", t.Name, t.Tag, c.Tag)
+		announce(&t, release, true)
+		t.PreviousTag, t.PreviousAsset = t.Tag, t.AssetName
+		t.Tag, t.AssetName, t.SHA256, t.InstalledAt = c.Tag, c.AssetName, digest, time.Now().UTC()
+		t.FileSHA256 = unpackedDigest(t.Path, digest)
+		changed = append(changed, t)
+		r.Updated++
+	}
+
+	// Merge into the current state, which other runs may have changed during the pass
+	if len(changed) > 0 {
+		err := updateState(func(s *installState) error {
+			for _, t := range changed {
+				s.put(t)
+			}
+			return nil
+		})
+		if err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
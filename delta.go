@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// deltaUpdates makes updates fetch a bsdiff patch against the cached previous release
+// instead of the whole asset when the release publishes one. It is set from -delta.
+//
+// A patch is a release asset named <asset>.<previous tag>.bsdiff, in the BSDIFF40 format.
+// It is only used when a checksums asset of the release gives the digest of the new asset,
+// so the patched result can be verified.
+var deltaUpdates bool
+
+// installDelta tries to build the asset of c, passed through stages, at dest by patching the cached asset of the
+// release installed there, and returns its digest. It returns "" and no error when nothing
+// is installed there to patch, and an error when no usable patch exists or the result does
+// not verify; either way the caller then downloads the asset in full.
+func installDelta(ctx context.Context, provider releases.ReleaseProvider, c releases.Candidate, dest string, stages []releases.Stage) (string, error) {
+	rec := installedAt(dest)
+	if rec == nil || rec.Tag == c.Tag || !strings.EqualFold(rec.RepoOwner+"/"+rec.RepoName, c.RepoOwner+"/"+c.RepoName) {
+		return "", nil
+	}
+	root, err := cacheRoot()
+	if err != nil {
+		return "", err
+	}
+	old, err := os.Open(blobPath(root, rec.SHA256))
+	if err != nil {
+		return "", errors.New("previous release is not cached")
+	}
+	defer old.Close()
+	oldInfo, err := old.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	release, err := provider.ReleaseByTag(ctx, c.RepoOwner, c.RepoName, c.Tag)
+	if err != nil {
+		return "", err
+	}
+	var patchAsset *releases.Asset
+	for i, a := range release.Assets {
+		if strings.EqualFold(a.Name, c.AssetName+"."+rec.Tag+".bsdiff") {
+			patchAsset = &release.Assets[i]
+		}
+	}
+	if patchAsset == nil {
+		return "", fmt.Errorf("release has no patch from %s", rec.Tag)
+	}
+	want, err := publishedDigest(ctx, provider, release, c)
+	if err != nil {
+		return "", err
+	}
+	if want == "" {
+		return "", errors.New("release publishes no checksum to verify the patched asset")
+	}
+
+	// Patches are small; keep one on disk so its blocks can be read side by side
+	patch, err := os.CreateTemp("", "get_gh_release-*.bsdiff")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(patch.Name())
+	defer patch.Close()
+	rc, err := provider.DownloadAsset(ctx, releases.NewCandidate(c.RepoOwner, c.RepoName, release, *patchAsset))
+	if err != nil {
+		return "", err
+	}
+	patchSize, err := io.Copy(patch, &countingReader{r: rc, source: "delta"})
+	rc.Close()
+	if err != nil {
+		return "", err
+	}
+
+	var taps []io.Writer
+	blob, err := newCacheBlob()
+	if err == nil {
+		taps = append(taps, blob)
+	}
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pw.CloseWithError(releases.Bspatch(old, oldInfo.Size(), patch, patchSize, pw))
+	}()
+	digest, err := releases.InstallStream(pr, dest, c.Size, stages, taps...)
+	pr.Close()
+	<-done
+	if err == nil && digest != want {
+		err = fmt.Errorf("patched asset has digest %s, expected %s", digest, want)
+	}
+	if err != nil {
+		if blob != nil {
+			blob.abort()
+		}
+		return "", err
+	}
+	if blob != nil {
+		if err := blob.commit(c, digest); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not cache %s: %v\n", c.AssetName, err)
+		}
+	}
+	fmt.Printf("patched %s -> %s (%s instead of %s)\n", rec.Tag, c.Tag, formatSize(patchSize), formatSize(c.Size))
+	return digest, nil
+}
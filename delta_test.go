@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// assetProvider serves assets with the given contents; any other asset fails to download.
+type assetProvider struct {
+	releases.ReleaseProvider
+	assets map[string]string
+}
+
+func (p assetProvider) DownloadAsset(ctx context.Context, c releases.Candidate) (io.ReadCloser, error) {
+	body, ok := p.assets[c.AssetName]
+	if !ok {
+		return nil, errors.New("connection reset")
+	}
+	return io.NopCloser(strings.NewReader(body)), nil
+}
+
+func TestPublishedDigest(t *testing.T) {
+	const digest = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	c := releases.Candidate{RepoOwner: "me", RepoName: "tool", Tag: "v1.0.0", AssetName: "tool_linux_amd64"}
+	tests := []struct {
+		name   string
+		assets map[string]string
+		want   string
+	}{
+		{name: "none published"},
+		{name: "listed", assets: map[string]string{"checksums.txt": strings.ToUpper(digest) + "  tool_linux_amd64\n"}, want: digest},
+		{name: "listed as binary with a path", assets: map[string]string{"SHA256SUMS": digest + " *dist/tool_linux_amd64\n"}, want: digest},
+		{name: "sidecar", assets: map[string]string{"tool_linux_amd64.sha256": digest + "\n"}, want: digest},
+		{name: "not listed", assets: map[string]string{"checksums.txt": digest + "  tool_darwin_arm64\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := &releases.Release{Tag: c.Tag, Assets: []releases.Asset{{Name: c.AssetName}}}
+			for name := range tt.assets {
+				release.Assets = append(release.Assets, releases.Asset{Name: name})
+			}
+			got, err := publishedDigest(context.Background(), assetProvider{assets: tt.assets}, release, c)
+			if err != nil || got != tt.want {
+				t.Errorf("got %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
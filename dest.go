@@ -0,0 +1,59 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// defaultInstallDir is where a single download goes when neither -dest, the
+// GET_GH_RELEASE_DEST variable, a project manifest nor the configuration says otherwise.
+const defaultInstallDir = "~/.local/bin"
+
+// installTarget returns the directory and file name a single download of c goes to, in
+// order of precedence: -dest and -name, the project manifest, the configuration for the
+// repository, GET_GH_RELEASE_DEST, the configured directory, the default. The directory may
+// start with ~/.
+func installTarget(c releases.Candidate, project *manifest, cfg *userConfig, dest, name string) (string, string) {
+	dir, file := defaultInstallDir, installName(c)
+	if cfg.Dest != "" {
+		dir = cfg.Dest
+	}
+	if env := os.Getenv("GET_GH_RELEASE_DEST"); env != "" {
+		dir = env
+	}
+	if rc := cfg.repo(c.RepoOwner, c.RepoName); rc.Dest != "" || rc.Name != "" {
+		dir, file = cmp.Or(rc.Dest, dir), cmp.Or(rc.Name, file)
+	}
+	if project != nil {
+		if d := project.projectDest(c); d != "" {
+			dir, file = filepath.Dir(d), filepath.Base(d)
+		}
+	}
+	if dest != "" {
+		dir = dest
+	}
+	if name != "" {
+		file = name
+	}
+	return dir, file
+}
+
+// warnNotOnPath warns when binaries installed in dir cannot be run by name.
+func warnNotOnPath(dir string) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return
+	}
+	for _, p := range filepath.SplitList(os.Getenv("PATH")) {
+		if p, err := filepath.Abs(expandHome(p)); err == nil && (p == abs || runtime.GOOS == "windows" && strings.EqualFold(p, abs)) {
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s is not on your PATH\n", abs)
+}
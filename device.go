@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+
+	"golang.org/x/oauth2"
+)
+
+// oauthClientID is the client ID of the OAuth app used for the device flow. Source builds
+// leave it empty; release builds set it like version, with -ldflags "-X main.oauthClientID=...",
+// from the OAUTH_CLIENT_ID environment variable of the GoReleaser run. GET_GH_RELEASE_CLIENT_ID
+// and login -client-id override it.
+var oauthClientID = ""
+
+// webRoot returns the root of the web interface of the server whose API URL is apiURL, or of
+// github.com when apiURL is empty.
+func webRoot(apiURL string) string {
+	if u, err := url.Parse(apiURL); apiURL != "" && err == nil && u.Host != "" {
+		return u.Scheme + "://" + u.Host + "/"
+	}
+	return "https://github.com/"
+}
+
+// deviceFlowToken obtains a token through OAuth's device flow: it prints a code and the page
+// to enter it on, then waits until the user has authorised the app there.
+func deviceFlowToken(ctx context.Context, web, clientID string, scopes []string) (string, error) {
+	if clientID == "" {
+		return "", errors.New("this build has no OAuth client ID for -device: set GET_GH_RELEASE_CLIENT_ID or pass -client-id, or run login without -device and paste a personal access token")
+	}
+	conf := &oauth2.Config{
+		ClientID: clientID,
+		Scopes:   scopes,
+		Endpoint: oauth2.Endpoint{
+			DeviceAuthURL: web + "login/device/code",
+			TokenURL:      web + "login/oauth/access_token",
+		},
+	}
+	da, err := conf.DeviceAuth(ctx)
+	if err != nil {
+		return "", fmt.Errorf("could not start device authorisation: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Open %s and enter the code %s\n", da.VerificationURI, da.UserCode)
+	fmt.Fprintln(os.Stderr, "Waiting for authorisation...")
+	tok, err := conf.DeviceAccessToken(ctx, da)
+	if err != nil {
+		return "", fmt.Errorf("device authorisation failed: %w", err)
+	}
+	return tok.AccessToken, nil
+}
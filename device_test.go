@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeviceFlowToken(t *testing.T) {
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("client_id") != "client" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/login/device/code":
+			if r.Form.Get("scope") != "repo" {
+				t.Errorf("got scope %q, want repo", r.Form.Get("scope"))
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"device_code":      "device",
+				"user_code":        "ABCD-1234",
+				"verification_uri": "https://github.com/login/device",
+				"interval":         1,
+			})
+		case "/login/oauth/access_token":
+			polls++
+			if r.Form.Get("device_code") != "device" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "gho_token", "token_type": "bearer"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	tok, err := deviceFlowToken(context.Background(), srv.URL+"/", "client", []string{"repo"})
+	if err != nil || tok != "gho_token" {
+		t.Errorf("got %q, %v, want gho_token", tok, err)
+	}
+	if polls != 1 {
+		t.Errorf("got %d token requests, want 1", polls)
+	}
+	if _, err := deviceFlowToken(context.Background(), srv.URL+"/", "", nil); err == nil {
+		t.Error("got no error without a client ID")
+	}
+}
+
+func TestWebRoot(t *testing.T) {
+	tests := map[string]string{
+		"":                                "https://github.com/",
+		"https://ghe.example.com/api/v3/": "https://ghe.example.com/",
+	}
+	for apiURL, want := range tests {
+		if got := webRoot(apiURL); got != want {
+			t.Errorf("webRoot(%q) = %q, want %q", apiURL, got, want)
+		}
+	}
+}
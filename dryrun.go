@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// dryRun makes a download stop once the asset is chosen and print what the install would do
+// instead, without downloading or writing anything. It is set from -dry-run.
+var dryRun bool
+
+// installPlan describes what installing c at dest would do.
+type installPlan struct {
+	c    releases.Candidate
+	dest string
+	// upToDate is set when dest already holds c unmodified and -if-needed would skip it
+	upToDate bool
+	// existing describes the file at dest, if any
+	existing string
+	// overwrite is set when the install would replace a file
+	overwrite bool
+}
+
+// planInstall works out what installing c at dest would do, reading only the state file and
+// dest itself.
+func planInstall(c releases.Candidate, dest string, ifNeeded bool) installPlan {
+	if abs, err := filepath.Abs(dest); err == nil {
+		dest = abs
+	}
+	p := installPlan{c: c, dest: dest}
+	rec := installedAt(dest)
+	if ifNeeded && rec.current(c) {
+		p.upToDate = true
+		p.existing = fmt.Sprintf("%s installed by get_gh_release, unmodified; nothing would be done", rec.Tag)
+		return p
+	}
+	info, err := os.Lstat(dest)
+	switch {
+	case err != nil:
+		p.existing = "none"
+	case info.IsDir():
+		p.existing = "a directory; the install would fail"
+	case rec != nil:
+		p.overwrite = true
+		p.existing = fmt.Sprintf("%s installed by get_gh_release %s, would be replaced", rec.Tag, rec.InstalledAt.Local().Format("2006-01-02"))
+	default:
+		p.overwrite = true
+		p.existing = "a file not installed by get_gh_release, would be overwritten"
+	}
+	return p
+}
+
+// print writes the plan as a list of fields, with the hooks the install would run.
+func (p installPlan) print(w io.Writer, hooks *toolHooks) error {
+	size := "unknown"
+	if p.c.Size > 0 {
+		size = formatSize(p.c.Size)
+	}
+	install := p.dest
+	if extractArchives && releases.IsArchive(p.c.AssetName) {
+		install += " (the executable unpacked from the archive)"
+	}
+	fmt.Fprintln(w, "Dry run: nothing was downloaded or written.")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "  repository\t%s/%s\n", p.c.RepoOwner, p.c.RepoName)
+	if p.c.Published.IsZero() {
+		fmt.Fprintf(tw, "  release\t%s\n", p.c.Tag)
+	} else {
+		fmt.Fprintf(tw, "  release\t%s, published %s\n", p.c.Tag, p.c.Published.Local().Format("2006-01-02"))
+	}
+	fmt.Fprintf(tw, "  asset\t%s (%s)\n", p.c.AssetName, size)
+	fmt.Fprintf(tw, "  url\t%s\n", p.c.DownloadURL)
+	fmt.Fprintf(tw, "  install to\t%s\n", install)
+	fmt.Fprintf(tw, "  existing\t%s\n", p.existing)
+	if hooks != nil && !p.upToDate {
+		if hooks.PreInstall != "" {
+			fmt.Fprintf(tw, "  pre-install\t%s\n", hooks.PreInstall)
+		}
+		if hooks.PostInstall != "" {
+			fmt.Fprintf(tw, "  post-install\t%s\n", hooks.PostInstall)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"strings"
+)
+
+// githubAPI is the root of the REST API in use, ending in a slash, and githubWeb the root of
+// the matching web interface. Both change when a GitHub Enterprise Server or GitLab is used.
+var (
+	githubAPI = "https://api.github.com/"
+	githubWeb = "https://github.com/"
+)
+
+// enterpriseAPIURL returns the API URL of the GitHub Enterprise Server to use: apiURL, or
+// failing that the host named by GH_HOST, as the gh CLI understands it. It returns "" for
+// github.com.
+func enterpriseAPIURL(apiURL string) string {
+	if apiURL != "" {
+		return apiURL
+	}
+	if host := os.Getenv("GH_HOST"); host != "" && !strings.EqualFold(host, "github.com") {
+		return "https://" + host + "/api/v3/"
+	}
+	return ""
+}
+
+// useEnterprise points the URLs written into scripts and reports at the server whose API
+// root is api.
+func useEnterprise(api *url.URL) {
+	githubAPI = api.String()
+	githubWeb = api.Scheme + "://" + api.Host + "/"
+}
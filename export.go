@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+	"gopkg.in/yaml.v3"
+)
+
+// runExport writes the installed tool set as a manifest that import, apply or sync accept.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	output := fs.String("o", "-", "File to write the manifest to (- for standard output).")
+	latest := fs.Bool("latest", false, "Omit version pins so importing installs the latest releases.")
+	fs.Parse(args)
+
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(exportManifest(st, *latest)); err != nil {
+		return err
+	}
+	if *output == "-" {
+		_, err = os.Stdout.Write(b.Bytes())
+		return err
+	}
+	return os.WriteFile(*output, b.Bytes(), 0644)
+}
+
+// exportManifest converts recorded installs into a manifest. Asset names are left out so the
+// manifest resolves the right build on machines of a different platform, but the asset glob
+// and rules are kept; a directory shared by every tool becomes the manifest default.
+func exportManifest(st *installState, latest bool) *manifest {
+	m := &manifest{}
+	dirs := map[string]bool{}
+	for _, r := range st.Tools {
+		dirs[filepath.Dir(r.Path)] = true
+	}
+	shared := len(dirs) == 1
+	// The importing machine detects its own C library, which a manifest cannot override
+	local := resolveLibc("auto", "linux")
+	for _, r := range st.Tools {
+		dir := collapseHome(filepath.Dir(r.Path))
+		if shared {
+			m.Defaults.Dest = dir
+			dir = ""
+		}
+		t := manifestTool{
+			Repo:   r.RepoOwner + "/" + r.RepoName,
+			Dest:   dir,
+			Name:   r.Name,
+			Policy: r.Policy,
+			MinAge: r.MinAge,
+			Block:  r.Block,
+			Pre:    r.Pre,
+			Asset:  r.Glob,
+			Match:  r.Rules,
+		}
+		if r.MinSize != 0 {
+			fmt.Fprintf(os.Stderr, "Warning: %s was installed with -min-size %d, which a manifest cannot express\n", r.Path, r.MinSize)
+		}
+		if r.Libc != "" && r.Libc != local {
+			fmt.Fprintf(os.Stderr, "Warning: %s was installed with -libc %s, which a manifest cannot express\n", r.Path, r.Libc)
+		}
+		if r.Hooks != nil && r.Hooks.runnable() {
+			t.Hooks = r.Hooks
+		}
+		if !latest {
+			t.Version = r.Tag
+		}
+		m.Tools = append(m.Tools, t)
+	}
+	return m
+}
+
+// collapseHome replaces the user's home directory prefix with ~, the inverse of expandHome.
+func collapseHome(p string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return p
+	}
+	if p == home {
+		return "~"
+	}
+	if rest, ok := strings.CutPrefix(p, home+string(filepath.Separator)); ok {
+		return "~/" + filepath.ToSlash(rest)
+	}
+	return p
+}
+
+// runImport installs a manifest produced by export on this machine, skipping tools that are
+// already present at the requested version. Versions are looked up as exact tags, the way
+// export writes them, rather than matched as substrings.
+func runImport(ctx context.Context, provider releases.ReleaseProvider, platformOS, platformArch string, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	maxFailures := fs.Int("max-failures", 0, "Number of tools that may fail to install before the command fails.")
+	jobs := fs.Int("jobs", 4, "Number of assets downloaded at once before installing them in order; 1 downloads each as it is installed.")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: get_gh_release import [-max-failures n] [-jobs n] <manifest.yaml|->")
+	}
+	m, err := loadManifest(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	m.exact = true
+	b := batch{maxFailures: *maxFailures}
+	_, err = reconcile(ctx, provider, m, nil, "", false, *jobs, platformOS, platformArch, &b)
+	b.printTable(os.Stdout)
+	if err != nil {
+		return err
+	}
+	return b.err()
+}
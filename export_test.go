@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExportManifest(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	st := &installState{Tools: []installRecord{
+		{
+			Name: "rg", Path: "/opt/bin/rg", RepoOwner: "BurntSushi", RepoName: "ripgrep", Tag: "14.1.0",
+			toolSettings: toolSettings{Policy: policyAuto, MinAge: "72h0m0s", Block: []string{"14.0.0"}, Pre: true, Glob: "*musl*", Rules: []releases.Rule{{Exclude: "debug"}}},
+		},
+		{
+			Name: "fzf", Path: "/opt/bin/fzf", RepoOwner: "junegunn", RepoName: "fzf", Tag: "v0.54.0",
+			toolSettings: toolSettings{Policy: policyNotify},
+		},
+	}}
+
+	m := exportManifest(st, false)
+	if m.Defaults.Dest != "/opt/bin" {
+		t.Errorf("got default dest %q, want the shared directory", m.Defaults.Dest)
+	}
+	if got := m.Tools[1].Version; got != "v0.54.0" {
+		t.Errorf("got version %q, want the installed tag", got)
+	}
+	if got := exportManifest(st, true).Tools[1].Version; got != "" {
+		t.Errorf("got version %q with -latest, want none", got)
+	}
+
+	// Importing the manifest installs with the settings it was exported from
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "tools.yaml")
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := loadManifest(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range st.Tools {
+		got := imported.settings(imported.Tools[i])
+		got.Manifest = ""
+		if !reflect.DeepEqual(got, r.toolSettings) {
+			t.Errorf("%s: imported settings %+v, want %+v", r.Name, got, r.toolSettings)
+		}
+	}
+}
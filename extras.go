@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// saveExtras writes the context files of the release of c next to the binary installed at
+// dest: with notes, the release notes as <tool>-<tag>.md; with sbom, the release's SBOM
+// assets under their own names. SBOMs named after the installed asset are preferred over
+// the others.
+func saveExtras(ctx context.Context, provider releases.ReleaseProvider, c releases.Candidate, dest string, notes, sbom bool) error {
+	release, err := provider.ReleaseByTag(ctx, c.RepoOwner, c.RepoName, c.Tag)
+	if err != nil {
+		return fmt.Errorf("could not fetch release %s: %w", c.Tag, err)
+	}
+	dir := filepath.Dir(dest)
+	if notes {
+		file := filepath.Join(dir, fmt.Sprintf("%s-%s.md", filepath.Base(dest), c.Tag))
+		if err := os.WriteFile(file, []byte(strings.TrimRight(release.Body, "\n")+"\n"), 0644); err != nil {
+			return fmt.Errorf("could not write release notes: %w", err)
+		}
+		fmt.Printf("saved release notes to %s\n", file)
+	}
+	if !sbom {
+		return nil
+	}
+	var all, own []releases.Asset
+	for _, a := range release.Assets {
+		if releases.IsSBOM(a) {
+			all = append(all, a)
+			if strings.HasPrefix(strings.ToLower(a.Name), strings.ToLower(c.AssetName)) {
+				own = append(own, a)
+			}
+		}
+	}
+	if len(own) > 0 {
+		all = own
+	}
+	if len(all) == 0 {
+		fmt.Printf("release %s has no SBOM\n", c.Tag)
+	}
+	for _, a := range all {
+		file := filepath.Join(dir, filepath.Base(a.Name))
+		if err := saveAsset(ctx, provider, releases.NewCandidate(c.RepoOwner, c.RepoName, release, a), file); err != nil {
+			return fmt.Errorf("could not save SBOM %s: %w", a.Name, err)
+		}
+		fmt.Printf("saved SBOM to %s\n", file)
+	}
+	return nil
+}
+
+// saveAsset downloads the asset of c to a regular file.
+func saveAsset(ctx context.Context, provider releases.ReleaseProvider, c releases.Candidate, file string) error {
+	rc, err := provider.DownloadAsset(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, rc); err != nil {
+		f.Close()
+		os.Remove(file)
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// webRepo splits a repository given by its web address, such as gitlab.com/owner/repo or
+// https://gitlab.com/group/subgroup/repo, into the host and the owner/repo path. It reports
+// false for patterns that are not addresses: GitHub owners cannot contain dots, so a first
+// path element with one is taken to be a host.
+func webRepo(pattern string) (host, repo string, ok bool) {
+	rest := pattern
+	if u, err := url.Parse(pattern); err == nil && (u.Scheme == "https" || u.Scheme == "http") {
+		rest = u.Host + u.Path
+	}
+	host, repo, ok = strings.Cut(rest, "/")
+	if !ok || !strings.Contains(host, ".") {
+		return "", "", false
+	}
+	// Drop the pages below a project, as in group/repo/-/releases, and a clone URL's suffix
+	repo, _, _ = strings.Cut(repo, "/-/")
+	repo = strings.TrimSuffix(strings.TrimSuffix(repo, "/"), ".git")
+	if !strings.Contains(repo, "/") {
+		return "", "", false
+	}
+	return host, repo, true
+}
+
+// sourceForHost returns the source and API URL to use for a repository on host, given the
+// -source and -api-url flags: GitLab for gitlab.com and hosts named gitlab.*, Gitea for
+// codeberg.org, and GitHub for github.com and the Enterprise Server in use. Gitea and
+// GitLab instances elsewhere need -source.
+func sourceForHost(host, source, apiURL string) (string, string, error) {
+	switch {
+	case source == "gitlab" || source == "gitea":
+		if apiURL == "" {
+			apiURL = "https://" + host + "/"
+		}
+		return source, apiURL, nil
+	case source != "github":
+		return source, apiURL, nil
+	case strings.EqualFold(host, "github.com") || strings.EqualFold(host, apiHost(enterpriseAPIURL(apiURL))):
+		return source, apiURL, nil
+	case strings.EqualFold(host, "gitlab.com") || strings.HasPrefix(strings.ToLower(host), "gitlab."):
+		return "gitlab", "https://" + host + "/", nil
+	case strings.EqualFold(host, "codeberg.org"):
+		return "gitea", "https://" + host + "/", nil
+	}
+	return "", "", fmt.Errorf("cannot tell which forge %s is: pass -source gitlab or -source gitea, or -api-url for a GitHub Enterprise Server", host)
+}
+
+// forgeAPIURL returns the API URL of the forge to use with source: apiURL, or by default
+// gitlab.com for GitLab and the Enterprise Server or github.com for GitHub. Gitea has no
+// default and gives "".
+func forgeAPIURL(source, apiURL string) string {
+	switch source {
+	case "gitlab":
+		if apiURL == "" {
+			return releases.DefaultGitLabURL
+		}
+		return apiURL
+	case "gitea":
+		return apiURL
+	}
+	return enterpriseAPIURL(apiURL)
+}
+
+// forgeToken resolves the token for a GitLab or Gitea host from the -token flag, the
+// environment variable env, a token stored by login, or ~/.netrc. GitHub tokens are never
+// sent to other forges.
+func forgeToken(tokenFlag, env, host string) string {
+	if tokenFlag != "" {
+		return tokenFlag
+	}
+	if token := os.Getenv(env); token != "" {
+		return token
+	}
+	if token := storedToken(host); token != "" {
+		return token
+	}
+	return netrcToken(host)
+}
+
+// useForge points the web links written into reports, and the identity expected of keyless
+// signatures, at the GitLab or Gitea instance whose API root is api. Signatures made in
+// its CI name the instance as their issuer.
+func useForge(api *url.URL) {
+	githubAPI = api.String()
+	githubWeb = api.Scheme + "://" + api.Host + "/"
+	sigstoreIssuer = api.Scheme + "://" + api.Host
+}
@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestWebRepo(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		host, repo string
+		ok         bool
+	}{
+		{"gitlab.com/owner/repo", "gitlab.com", "owner/repo", true},
+		{"https://gitlab.com/group/subgroup/repo", "gitlab.com", "group/subgroup/repo", true},
+		{"https://gitlab.com/group/repo/-/releases", "gitlab.com", "group/repo", true},
+		{"https://codeberg.org/owner/repo.git", "codeberg.org", "owner/repo", true},
+		{"github.com/owner/repo/", "github.com", "owner/repo", true},
+		{"owner/repo", "", "", false},
+		{"fzf", "", "", false},
+		{"gitlab.com/owner", "", "", false},
+	}
+	for _, tt := range tests {
+		host, repo, ok := webRepo(tt.pattern)
+		if host != tt.host || repo != tt.repo || ok != tt.ok {
+			t.Errorf("webRepo(%q) = %q, %q, %v, want %q, %q, %v", tt.pattern, host, repo, ok, tt.host, tt.repo, tt.ok)
+		}
+	}
+}
+
+func TestSourceForHost(t *testing.T) {
+	t.Setenv("GH_HOST", "")
+	tests := []struct {
+		host, source, apiURL string
+		wantSource, wantURL  string
+		wantErr              bool
+	}{
+		{host: "github.com", source: "github", wantSource: "github"},
+		{host: "GitHub.com", source: "github", wantSource: "github"},
+		{host: "gitlab.com", source: "github", wantSource: "gitlab", wantURL: "https://gitlab.com/"},
+		{host: "gitlab.example.org", source: "github", wantSource: "gitlab", wantURL: "https://gitlab.example.org/"},
+		{host: "codeberg.org", source: "github", wantSource: "gitea", wantURL: "https://codeberg.org/"},
+		{host: "ghe.example.com", source: "github", apiURL: "https://ghe.example.com/api/v3/", wantSource: "github", wantURL: "https://ghe.example.com/api/v3/"},
+		{host: "git.example.com", source: "gitea", wantSource: "gitea", wantURL: "https://git.example.com/"},
+		{host: "git.example.com", source: "gitlab", apiURL: "https://api.example.com/", wantSource: "gitlab", wantURL: "https://api.example.com/"},
+		{host: "git.example.com", source: "github", wantErr: true},
+	}
+	for _, tt := range tests {
+		source, apiURL, err := sourceForHost(tt.host, tt.source, tt.apiURL)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("sourceForHost(%q, %q, %q): error %v, want error %v", tt.host, tt.source, tt.apiURL, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (source != tt.wantSource || apiURL != tt.wantURL) {
+			t.Errorf("sourceForHost(%q, %q, %q) = %q, %q, want %q, %q", tt.host, tt.source, tt.apiURL, source, apiURL, tt.wantSource, tt.wantURL)
+		}
+	}
+}
+
+func TestSourceForHostEnterpriseFromGHHost(t *testing.T) {
+	t.Setenv("GH_HOST", "ghe.example.com")
+	source, apiURL, err := sourceForHost("ghe.example.com", "github", "")
+	if err != nil || source != "github" || apiURL != "" {
+		t.Errorf("got %q, %q, %v, want the Enterprise Server of GH_HOST", source, apiURL, err)
+	}
+}
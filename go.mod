@@ -3,8 +3,10 @@ module github.com/abgoyal/get_gh_release
 go 1.25.0
 
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/google/go-github/v62 v62.0.0
 	golang.org/x/oauth2 v0.31.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require github.com/google/go-querystring v1.1.0 // indirect
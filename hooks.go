@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// toolHooks are shell commands run around the installation of a tool.
+type toolHooks struct {
+	PreInstall  string `yaml:"pre_install,omitempty" json:"pre_install,omitempty"`
+	PostInstall string `yaml:"post_install,omitempty" json:"post_install,omitempty"`
+
+	// From is the project manifest the hooks came from, if any. They run only while it is
+	// allowed.
+	From string `yaml:"-" json:"from,omitempty"`
+}
+
+// runnable reports whether the hooks may run: those from a project manifest only while it
+// is allowed as it is now.
+func (h *toolHooks) runnable() bool {
+	if h.From == "" {
+		return true
+	}
+	data, err := os.ReadFile(h.From)
+	if err != nil {
+		return false
+	}
+	ok, err := allowedAsIs(h.From, data)
+	return err == nil && ok
+}
+
+// runHook runs a hook command through sh with the install details exposed as
+// GET_GH_RELEASE_* environment variables, printing the command first so that what runs on
+// the user's behalf is never hidden. An empty command is a no-op.
+func runHook(ctx context.Context, stage, command string, c releases.Candidate, dest, previous string) error {
+	if command == "" {
+		return nil
+	}
+	fmt.Printf("running %s hook: %s\n", stage, command)
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"GET_GH_RELEASE_HOOK="+stage,
+		"GET_GH_RELEASE_PATH="+dest,
+		"GET_GH_RELEASE_VERSION="+c.Tag,
+		"GET_GH_RELEASE_PREVIOUS_VERSION="+previous,
+		"GET_GH_RELEASE_REPO="+c.RepoOwner+"/"+c.RepoName,
+		"GET_GH_RELEASE_ASSET="+c.AssetName,
+	)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s hook failed: %w", stage, err)
+	}
+	return nil
+}
+
+// withHooks runs install between the pre- and post-install hooks, then hands the result to
+// the post-install plugins. A failing pre-install hook prevents the install; previous is the
+// version being replaced, if any. Hooks from a project manifest that is no longer allowed are
+// skipped with a warning. The directory of dest is locked throughout.
+func withHooks(ctx context.Context, hooks *toolHooks, c releases.Candidate, dest, previous string, install func() error) error {
+	if hooks == nil {
+		hooks = &toolHooks{}
+	}
+	if !hooks.runnable() {
+		fmt.Fprintf(os.Stderr, "Warning: not running the hooks from %s, which is no longer allowed\n", hooks.From)
+		hooks = &toolHooks{}
+	}
+	release, err := lockDir(filepath.Dir(dest))
+	if err != nil {
+		return err
+	}
+	defer release()
+	if err := runHook(ctx, "pre_install", hooks.PreInstall, c, dest, previous); err != nil {
+		return err
+	}
+	if err := install(); err != nil {
+		return err
+	}
+	if err := runHook(ctx, "post_install", hooks.PostInstall, c, dest, previous); err != nil {
+		return err
+	}
+	return runPostInstallPlugins(ctx, c, dest, previous)
+}
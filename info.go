@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// runInfo shows a release of one repository, its notes and its assets. Digests are shown for assets
+// that are in the local cache, since computing them would mean downloading every asset. filter
+// says whether prereleases and drafts may be shown.
+func runInfo(ctx context.Context, provider releases.ReleaseProvider, filter releases.ReleaseFilter, args []string) error {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	fs.Parse(args)
+	owner, repo, ok := strings.Cut(fs.Arg(0), "/")
+	if !ok || owner == "" || repo == "" || fs.NArg() > 2 {
+		return fmt.Errorf("usage: get_gh_release info <owner/repo> [version]")
+	}
+	version := fs.Arg(1)
+
+	release, _, err := filter.Resolve(ctx, provider, owner, repo, version)
+	if err != nil {
+		return err
+	}
+	if release == nil {
+		return fmt.Errorf("%w: no release of %s/%s matching %q", releases.ErrNoCandidates, owner, repo, version)
+	}
+
+	fmt.Printf("%s/%s %s\n", owner, repo, release.Tag)
+	if release.Name != "" && release.Name != release.Tag {
+		fmt.Printf("Name:       %s\n", release.Name)
+	}
+	if !release.PublishedAt.IsZero() {
+		fmt.Printf("Published:  %s\n", release.PublishedAt.UTC().Format("2006-01-02 15:04 MST"))
+	}
+	fmt.Printf("Prerelease: %t\n", release.Prerelease)
+	if release.URL != "" {
+		fmt.Printf("URL:        %s\n", release.URL)
+	}
+	fmt.Println()
+	if strings.TrimSpace(release.Body) != "" {
+		printNotes(os.Stdout, release.Body)
+		fmt.Println()
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ASSET\tSIZE\tDOWNLOADS\tSHA256\t")
+	for _, a := range release.Assets {
+		digest := "-"
+		if _, d, ok := cacheLookup(releases.NewCandidate(owner, repo, release, a)); ok {
+			digest = d
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t\n", a.Name, formatSize(a.Size), a.Downloads, digest)
+	}
+	return tw.Flush()
+}
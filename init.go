@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// prompter asks questions on the terminal. With defaults set it answers every question with
+// its default without asking.
+type prompter struct {
+	r        *bufio.Reader
+	w        io.Writer
+	defaults bool
+}
+
+// ask prints question with its default and returns the answer once valid accepts it; an
+// empty answer takes the default. valid may be nil.
+func (p *prompter) ask(question, def string, valid func(string) error) (string, error) {
+	if p.defaults {
+		return def, nil
+	}
+	for {
+		if def != "" {
+			fmt.Fprintf(p.w, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(p.w, "%s: ", question)
+		}
+		line, err := p.r.ReadString('\n')
+		if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+			return "", err
+		}
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			answer = def
+		}
+		if valid == nil {
+			return answer, nil
+		}
+		if err := valid(answer); err != nil {
+			fmt.Fprintf(p.w, "  %v\n", err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// confirm asks a yes/no question.
+func (p *prompter) confirm(question string, def bool) (bool, error) {
+	d := "y/N"
+	if def {
+		d = "Y/n"
+	}
+	answer, err := p.ask(question, d, func(s string) error {
+		switch strings.ToLower(s) {
+		case "y", "yes", "n", "no", "y/n":
+			return nil
+		}
+		return fmt.Errorf("answer y or n")
+	})
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, nil
+	case "n", "no":
+		return false, nil
+	}
+	return def, nil
+}
+
+// oneOf returns a validator accepting only the given answers.
+func oneOf(choices ...string) func(string) error {
+	return func(s string) error {
+		for _, c := range choices {
+			if s == c {
+				return nil
+			}
+		}
+		return fmt.Errorf("answer one of %s", strings.Join(choices, ", "))
+	}
+}
+
+// initAnswers are the choices made in the init wizard.
+type initAnswers struct {
+	token   string // env, flag or none
+	dest    string
+	musl    bool
+	exclude []string
+	policy  string
+	minAge  string
+	locked  bool
+}
+
+// runInit walks through the main settings and writes a commented manifest, by default the
+// project manifest in the working directory.
+func runInit(args []string) error {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	yes := fs.Bool("y", false, "Accept the default answers without asking.")
+	force := fs.Bool("force", false, "Overwrite an existing file.")
+	fs.Parse(args)
+	file := projectManifestName
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		file = fs.Arg(0)
+	default:
+		return fmt.Errorf("usage: get_gh_release init [-y] [-force] [manifest.yaml]")
+	}
+	if _, err := os.Stat(file); err == nil && !*force {
+		return fmt.Errorf("%s already exists (use -force to overwrite)", file)
+	}
+	if !*yes && !interactive() {
+		return fmt.Errorf("init asks questions on the terminal; use -y to accept the defaults")
+	}
+
+	p := &prompter{r: bufio.NewReader(os.Stdin), w: os.Stdout, defaults: *yes}
+	a, err := askInit(p)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, []byte(renderInit(a)), 0644); err != nil {
+		return fmt.Errorf("could not write %s: %w", file, err)
+	}
+	if _, err := loadManifest(file); err != nil {
+		return fmt.Errorf("the written file does not load: %w", err)
+	}
+	fmt.Printf("wrote %s\n", file)
+	return nil
+}
+
+// askInit asks the wizard's questions.
+func askInit(p *prompter) (initAnswers, error) {
+	var a initAnswers
+	var err error
+
+	switch {
+	case os.Getenv("GH_TOKEN") != "":
+		fmt.Fprintln(p.w, "A GitHub token is set in GH_TOKEN.")
+	case hasCommand("gh"):
+		fmt.Fprintln(p.w, "No GH_TOKEN is set; the GitHub CLI can provide one with: export GH_TOKEN=$(gh auth token)")
+	}
+	if a.token, err = p.ask("Where does the GitHub token come from (env: GH_TOKEN, flag: -token, none: public repositories only)", "env", oneOf("env", "flag", "none")); err != nil {
+		return a, err
+	}
+	if a.dest, err = p.ask("Install directory", "~/.local/bin", nil); err != nil {
+		return a, err
+	}
+	fmt.Fprintf(p.w, "Assets are matched to this machine, %s/%s.\n", runtime.GOOS, runtime.GOARCH)
+	if a.musl, err = p.confirm("Prefer statically linked musl builds when a release has both", false); err != nil {
+		return a, err
+	}
+	exclude, err := p.ask("Asset patterns never to install, comma-separated (e.g. *-debug*)", "", nil)
+	if err != nil {
+		return a, err
+	}
+	a.exclude = splitList(exclude)
+	if a.policy, err = p.ask("Update policy for installed tools (auto, notify, pinned)", policyNotify, oneOf(policyAuto, policyNotify, policyPinned)); err != nil {
+		return a, err
+	}
+	if a.minAge, err = p.ask("Minimum age of releases to adopt, e.g. 72h (empty for none)", "", validMinAge); err != nil {
+		return a, err
+	}
+	if a.locked, err = p.confirm("Verify installs against the digests in a lockfile", false); err != nil {
+		return a, err
+	}
+	return a, nil
+}
+
+// hasCommand reports whether name is on PATH.
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// renderInit writes the manifest for the answers, with comments on each setting.
+func renderInit(a initAnswers) string {
+	var b strings.Builder
+	b.WriteString("# get_gh_release manifest, written by get_gh_release init.\n#\n")
+	switch a.token {
+	case "env":
+		b.WriteString("# The GitHub token is read from GH_TOKEN, e.g. export GH_TOKEN=$(gh auth token).\n")
+	case "flag":
+		b.WriteString("# The GitHub token is passed with -token on each run.\n")
+	case "none":
+		b.WriteString("# No GitHub token: run with -public to use public repositories only.\n")
+	}
+	if a.locked {
+		b.WriteString("# Installs are verified: `apply` writes a lockfile of tags and SHA-256 digests next to\n")
+		b.WriteString("# this file; commit it and install with `apply -locked` or `sync -locked`.\n")
+	} else {
+		b.WriteString("# To pin exact digests later, commit the lockfile `apply` writes and use -locked.\n")
+	}
+	b.WriteString("\ndefaults:\n")
+	fmt.Fprintf(&b, "  dest: %s  # install directory; relative paths are relative to this file\n", yamlScalar(a.dest))
+	fmt.Fprintf(&b, "  policy: %s  # what the daemon does with new releases: auto, notify or pinned\n", a.policy)
+	if a.minAge != "" {
+		fmt.Fprintf(&b, "  min_age: %s  # only adopt releases published at least this long ago\n", a.minAge)
+	} else {
+		b.WriteString("  # min_age: 72h  # only adopt releases published at least this long ago\n")
+	}
+	if a.musl || len(a.exclude) > 0 {
+		b.WriteString("  match:  # asset rules applied after the OS/architecture check\n")
+		for _, e := range a.exclude {
+			fmt.Fprintf(&b, "    - exclude: %s\n", yamlScalar(e))
+		}
+		if a.musl {
+			b.WriteString("    - prefer: musl\n")
+		}
+	} else {
+		b.WriteString("  # match:  # asset rules applied after the OS/architecture check\n")
+		b.WriteString("  #   - exclude: \"*-debug*\"\n")
+		b.WriteString("  #   - prefer: musl\n")
+	}
+	b.WriteString("\n# Tools to install with `get_gh_release apply`, for example:\n")
+	b.WriteString("#   - repo: junegunn/fzf\n")
+	b.WriteString("#     version: v0.44  # substring of the release tag; omit for the latest release\n")
+	b.WriteString("tools: []\n")
+	return b.String()
+}
+
+// yamlScalar quotes s if YAML would not read it back as the same plain string.
+func yamlScalar(s string) string {
+	if s == "" || strings.ContainsAny(s, ":#*&!|>'\"%@`{}[],?") || strings.TrimSpace(s) != s {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// selectInstalled returns the records of the tools named by args: by name, install path,
+// repository name or owner/repo. Naming a tool that was never installed is an error.
+func selectInstalled(st *installState, args []string) ([]installRecord, error) {
+	var out []installRecord
+	for _, a := range args {
+		abs, _ := filepath.Abs(a)
+		found := false
+		for _, t := range st.Tools {
+			if t.Name == a || t.Path == abs || strings.EqualFold(t.RepoName, a) || strings.EqualFold(t.RepoOwner+"/"+t.RepoName, a) {
+				out = append(out, t)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s is not installed by get_gh_release", a)
+		}
+	}
+	return out, nil
+}
+
+// runList prints the installed tools with their versions.
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	fs.Parse(args)
+
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+	tools := st.Tools
+	if fs.NArg() > 0 {
+		if tools, err = selectInstalled(st, fs.Args()); err != nil {
+			return err
+		}
+	}
+	sort.SliceStable(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTAG\tREPO\tPOLICY\tINSTALLED\tPATH\t")
+	for _, t := range tools {
+		fmt.Fprintf(tw, "%s\t%s\t%s/%s\t%s\t%s\t%s\t\n", t.Name, t.Tag, t.RepoOwner, t.RepoName, t.Policy, t.InstalledAt.Local().Format("2006-01-02"), t.Path)
+	}
+	return tw.Flush()
+}
+
+// runUpgrade installs the latest acceptable release of the named tools, or with -all of
+// every installed tool that is not pinned. Assets are picked for the platform with the glob
+// and rules the tool was installed with, as the daemon does.
+func runUpgrade(ctx context.Context, provider releases.ReleaseProvider, platformOS, platformArch string, args []string) error {
+	fs := flag.NewFlagSet("upgrade", flag.ExitOnError)
+	all := fs.Bool("all", false, "Upgrade every installed tool whose policy is not pinned.")
+	fs.Parse(args)
+	if *all == (fs.NArg() > 0) {
+		return fmt.Errorf("usage: get_gh_release upgrade name... | -all")
+	}
+
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+	tools := st.Tools
+	if !*all {
+		if tools, err = selectInstalled(st, fs.Args()); err != nil {
+			return err
+		}
+	}
+	failed := 0
+	for _, t := range tools {
+		if *all && t.Policy == policyPinned {
+			fmt.Printf("%s: pinned at %s\n", t.Name, t.Tag)
+			continue
+		}
+		if err := upgradeTool(ctx, provider, platformOS, platformArch, t); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", t.Name, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tools could not be upgraded", failed, len(tools))
+	}
+	return nil
+}
+
+// upgradeTool replaces the install t records with the latest release its settings accept.
+func upgradeTool(ctx context.Context, provider releases.ReleaseProvider, platformOS, platformArch string, t installRecord) error {
+	release, held, skipped, err := resolvePinned(ctx, provider, t.filter(), t.RepoOwner, t.RepoName)
+	if err != nil {
+		return fmt.Errorf("could not fetch latest release of %s/%s: %w", t.RepoOwner, t.RepoName, err)
+	}
+	for _, s := range skipped {
+		fmt.Printf("%s: holding back %s: %s\n", t.Name, s.Release.Tag, s.Reason)
+	}
+	if held != nil && held.Tag != t.Tag {
+		fmt.Printf("%s: holding back %s: pinned to %s by the configuration\n", t.Name, held.Tag, configPin(t.RepoOwner, t.RepoName))
+	}
+	if release == nil || release.Tag == t.Tag || behind(release, t.Tag) {
+		fmt.Printf("%s: %s is up to date\n", t.Name, t.Tag)
+		return nil
+	}
+	asset := t.matcher(platformOS, platformArch).Match(release)
+	if asset == nil {
+		return fmt.Errorf("%w: release %s has no asset for %s/%s", releases.ErrNoCandidates, release.Tag, platformOS, platformArch)
+	}
+	c := releases.NewCandidate(t.RepoOwner, t.RepoName, release, *asset)
+	fmt.Printf("%s: %s -> %s\n", t.Name, t.Tag, c.Tag)
+	if showNotes {
+		printChangelog(ctx, os.Stdout, provider, t.Name, t.RepoOwner, t.RepoName, t.Tag, release)
+	}
+	return withHooks(ctx, t.Hooks, c, t.Path, t.Tag, func() error {
+		digest, err := downloadAndPrepare(ctx, provider, c, t.Path, installStages(c))
+		if err != nil {
+			return err
+		}
+		return recordInstall(c, t.Path, digest, t.toolSettings)
+	})
+}
+
+// runUninstall removes the named tools, with the release notes saved next to them, and
+// forgets them.
+func runUninstall(args []string) error {
+	fs := flag.NewFlagSet("uninstall", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: get_gh_release uninstall name...")
+	}
+
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+	tools, err := selectInstalled(st, fs.Args())
+	if err != nil {
+		return err
+	}
+	for _, t := range tools {
+		if err := os.Remove(t.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not remove %s: %w", t.Path, err)
+		}
+		os.Remove(filepath.Join(filepath.Dir(t.Path), fmt.Sprintf("%s-%s.md", filepath.Base(t.Path), t.Tag)))
+		err := updateState(func(s *installState) error {
+			s.remove(t.Path)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("removed %s (%s %s)\n", t.Path, t.Name, t.Tag)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// inventoryItem is the resolved release of one manifest entry.
+type inventoryItem struct {
+	Repo    string `json:"repo"`
+	Version string `json:"version"`
+	Asset   string `json:"asset"`
+	URL     string `json:"url"`
+	SHA256  string `json:"sha256"`
+	Dest    string `json:"dest"`
+}
+
+// runInventory resolves every tool of a manifest and prints its download URL, version and
+// checksum as JSON for infrastructure tooling. The ansible format is a vars file holding a
+// get_gh_release_tools map; the terraform format is the flat string map an external data
+// source expects, with keys of the form <tool>_<field>.
+func runInventory(ctx context.Context, provider releases.ReleaseProvider, platformOS, platformArch string, args []string) error {
+	fs := flag.NewFlagSet("inventory", flag.ExitOnError)
+	format := fs.String("format", "ansible", "Output format: ansible or terraform.")
+	locked := fs.Bool("locked", false, "Report the tags, assets and digests recorded in the lockfile.")
+	output := fs.String("o", "-", "File to write the inventory to (- for standard output).")
+	fs.Parse(args)
+	if *format != "ansible" && *format != "terraform" {
+		return fmt.Errorf("unknown inventory format %q (want ansible or terraform)", *format)
+	}
+	file, err := manifestArg(fs)
+	if err != nil {
+		return fmt.Errorf("usage: get_gh_release inventory [-format ansible|terraform] [-locked] [manifest.yaml]: %w", err)
+	}
+	m, err := loadManifest(file)
+	if err != nil {
+		return err
+	}
+	var lock *lockfile
+	if *locked {
+		if lock, err = loadLockfile(lockPath(file)); err != nil {
+			return err
+		}
+	}
+
+	items := map[string]inventoryItem{}
+	for _, t := range m.Tools {
+		var pin *lockedTool
+		if lock != nil {
+			if pin = lock.find(t.Repo); pin == nil {
+				return fmt.Errorf("%s: not present in lockfile", t.Repo)
+			}
+		}
+		c, dest, err := resolveManifestTool(ctx, provider, m, t, pin, platformOS, platformArch)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Repo, err)
+		}
+		var digest string
+		if pin != nil {
+			digest = pin.SHA256
+		} else if digest, err = assetDigest(ctx, provider, c); err != nil {
+			return fmt.Errorf("%s: %w", t.Repo, err)
+		}
+		name := t.Name
+		if name == "" {
+			name = c.RepoName
+		}
+		items[name] = inventoryItem{
+			Repo:    t.Repo,
+			Version: c.Tag,
+			Asset:   c.AssetName,
+			URL:     c.DownloadURL,
+			SHA256:  digest,
+			Dest:    dest,
+		}
+	}
+
+	var doc any = map[string]any{"get_gh_release_tools": items}
+	if *format == "terraform" {
+		flat := map[string]string{}
+		for name, it := range items {
+			flat[name+"_repo"] = it.Repo
+			flat[name+"_version"] = it.Version
+			flat[name+"_asset"] = it.Asset
+			flat[name+"_url"] = it.URL
+			flat[name+"_sha256"] = it.SHA256
+			flat[name+"_dest"] = it.Dest
+		}
+		doc = flat
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if *output == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*output, data, 0644)
+}
+
+// assetDigest returns the SHA-256 of the asset of c, from the cache when it holds the asset
+// and otherwise by streaming it from GitHub without keeping a copy.
+func assetDigest(ctx context.Context, provider releases.ReleaseProvider, c releases.Candidate) (string, error) {
+	if _, digest, ok := cacheLookup(c); ok {
+		return digest, nil
+	}
+	rc, err := provider.DownloadAsset(ctx, c)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, &countingReader{r: rc, source: "github"}); err != nil {
+		return "", fmt.Errorf("could not read asset content: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
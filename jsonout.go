@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// jsonOutput makes a download print a single JSON document describing what it did, for
+// scripts and configuration management. It is set from -json.
+var jsonOutput bool
+
+// jsonStdout is where the JSON document goes. While -json is in effect everything else
+// written to standard output is sent to standard error instead, so that the document is
+// the only thing a caller has to parse.
+var jsonStdout io.Writer = os.Stdout
+
+// Actions reported in a jsonResult.
+const (
+	actionInstalled = "installed"
+	actionUpToDate  = "up-to-date"
+	actionScript    = "script"
+	actionDryRun    = "dry-run"
+	actionNone      = "none"
+	actionMultiple  = "multiple"
+	actionList      = "list"
+	actionFailed    = "failed"
+)
+
+// jsonResult is the document printed by -json. It is filled in as the run goes on, so that a
+// failure reports as much as was known when it happened.
+type jsonResult struct {
+	Action     string               `json:"action"`
+	Repo       string               `json:"repo,omitempty"`
+	Tag        string               `json:"tag,omitempty"`
+	Asset      string               `json:"asset,omitempty"`
+	Size       int64                `json:"size,omitempty"`
+	URL        string               `json:"url,omitempty"`
+	Path       string               `json:"path,omitempty"`
+	SHA256     string               `json:"sha256,omitempty"`
+	Overwrite  bool                 `json:"overwrite,omitempty"` // with -dry-run, whether the install would replace a file
+	Candidates []releases.Candidate `json:"candidates,omitempty"`
+	Error      string               `json:"error,omitempty"`
+	ExitCode   int                  `json:"exit_code,omitempty"`
+}
+
+// result is the outcome of the current run.
+var result jsonResult
+
+// startJSON turns on -json, moving other output to standard error.
+func startJSON() {
+	jsonOutput = true
+	jsonStdout = os.Stdout
+	os.Stdout = os.Stderr
+}
+
+// selected records the candidate being installed.
+func (r *jsonResult) selected(c releases.Candidate) {
+	r.Repo = c.RepoOwner + "/" + c.RepoName
+	r.Tag, r.Asset, r.Size, r.URL = c.Tag, c.AssetName, c.Size, c.DownloadURL
+}
+
+// finish prints the result with the given action when -json is in effect.
+func (r *jsonResult) finish(action string) {
+	if !jsonOutput {
+		return
+	}
+	r.Action = action
+	enc := json.NewEncoder(jsonStdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(r)
+}
+
+// fail prints the result as a failure with the given message and exit status.
+func (r *jsonResult) fail(msg string, code int) {
+	r.Error, r.ExitCode = msg, code
+	r.finish(actionFailed)
+}
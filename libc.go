@@ -0,0 +1,31 @@
+package main
+
+import (
+	"runtime"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// platformLibc is the C library assets are preferred for on Linux, musl or gnu, or "" when
+// it is not known. It is set from -libc, or detected when installing for this machine.
+var platformLibc string
+
+// validLibc reports whether l is a value -libc accepts.
+func validLibc(l string) bool {
+	return l == "auto" || l == "musl" || l == "gnu" || l == "none"
+}
+
+// resolveLibc turns a -libc value into platformLibc: auto detects the C library of this
+// machine when assets are picked for it, and none turns the preference off.
+func resolveLibc(flagValue, platformOS string) string {
+	switch flagValue {
+	case "auto":
+		if platformOS != runtime.GOOS {
+			return ""
+		}
+		return releases.DetectLibc()
+	case "none":
+		return ""
+	}
+	return flagValue
+}
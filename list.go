@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// printCandidates lists candidates one per line as owner/repo: asset, or with long as a
+// table that adds the tag, size and publication date.
+func printCandidates(w io.Writer, candidates []releases.Candidate, long bool) error {
+	if !long {
+		for _, c := range candidates {
+			fmt.Fprintf(w, "%s/%s: %s\n", c.RepoOwner, c.RepoName, c.AssetName)
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPO\tTAG\tASSET\tSIZE\tPUBLISHED\t")
+	for _, c := range candidates {
+		published := "-"
+		if !c.Published.IsZero() {
+			published = c.Published.UTC().Format("2006-01-02")
+		}
+		fmt.Fprintf(tw, "%s/%s\t%s\t%s\t%s\t%s\t\n", c.RepoOwner, c.RepoName, c.Tag, c.AssetName, formatSize(c.Size), published)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lockFile takes an exclusive advisory lock on path, creating the file if needed, so that
+// concurrent runs do not interleave their writes to the state file, the cache or an install
+// directory. It waits for other holders and returns the function that releases the lock.
+func lockFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open lock %s: %w", path, err)
+	}
+	if !tryLock(f) {
+		fmt.Fprintf(os.Stderr, "Waiting for another get_gh_release to release %s\n", path)
+		if err := waitLock(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("could not lock %s: %w", path, err)
+		}
+	}
+	return func() {
+		unlock(f)
+		f.Close()
+	}, nil
+}
+
+// lockDir locks the directory dir for an install into it.
+func lockDir(dir string) (func(), error) {
+	return lockFile(filepath.Join(dir, ".get_gh_release.lock"))
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// Advisory locks are only available on Unix; elsewhere concurrent runs are not coordinated.
+
+func tryLock(f *os.File) bool { return true }
+
+func waitLock(f *os.File) error { return nil }
+
+func unlock(f *os.File) {}
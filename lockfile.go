@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+	"gopkg.in/yaml.v3"
+)
+
+// lockfile pins every tool of a manifest to the exact release that was installed.
+type lockfile struct {
+	Tools []lockedTool `yaml:"tools"`
+}
+
+// lockedTool is the resolved release of one manifest entry.
+type lockedTool struct {
+	Repo    string `yaml:"repo"`
+	Tag     string `yaml:"tag"`
+	Asset   string `yaml:"asset"`
+	AssetID int64  `yaml:"asset_id,omitempty"` // replaced when an asset is re-uploaded
+	SHA256  string `yaml:"sha256"`
+}
+
+// lockedCandidate returns the lock entry for c installed as entry repo of a manifest.
+func lockedCandidate(repo string, c releases.Candidate, digest string) lockedTool {
+	return lockedTool{Repo: repo, Tag: c.Tag, Asset: c.AssetName, AssetID: c.AssetID, SHA256: digest}
+}
+
+// lockPath returns the lockfile that belongs to a manifest: tools.yaml -> tools.lock. A
+// manifest read from standard input has none, and "" is returned.
+func lockPath(manifestFile string) string {
+	if manifestFile == "-" {
+		return ""
+	}
+	return strings.TrimSuffix(manifestFile, filepath.Ext(manifestFile)) + ".lock"
+}
+
+// loadLockfile reads a lockfile written by a previous apply.
+func loadLockfile(file string) (*lockfile, error) {
+	if file == "" {
+		return nil, errors.New("a manifest read from standard input has no lockfile")
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read lockfile: %w", err)
+	}
+	var l lockfile
+	if err := yaml.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("could not parse lockfile %s: %w", file, err)
+	}
+	return &l, nil
+}
+
+// find returns the entry for repo, or nil. Repositories are compared ignoring case, as on
+// GitHub.
+func (l *lockfile) find(repo string) *lockedTool {
+	for i := range l.Tools {
+		if strings.EqualFold(l.Tools[i].Repo, repo) {
+			return &l.Tools[i]
+		}
+	}
+	return nil
+}
+
+// manifestLock loads the lockfile of m for -locked or -frozen, and returns nil for neither.
+// With frozen, a lockfile that has drifted from the manifest is an error.
+func manifestLock(m *manifest, file string, locked, frozen bool) (*lockfile, error) {
+	if !locked && !frozen {
+		return nil, nil
+	}
+	lock, err := loadLockfile(file)
+	if err != nil {
+		return nil, err
+	}
+	if frozen {
+		if problems := lock.drift(m); len(problems) > 0 {
+			return nil, fmt.Errorf("lockfile %s does not match the manifest:\n  %s", file, strings.Join(problems, "\n  "))
+		}
+	}
+	return lock, nil
+}
+
+// drift lists where the lockfile no longer describes m: tools missing from either side, and
+// locked releases the manifest would not choose.
+func (l *lockfile) drift(m *manifest) []string {
+	var problems []string
+	listed := map[string]bool{}
+	for _, t := range m.Tools {
+		listed[strings.ToLower(t.Repo)] = true
+		pin := l.find(t.Repo)
+		switch {
+		case pin == nil:
+			problems = append(problems, fmt.Sprintf("%s is not locked", t.Repo))
+		case t.Version != "" && !releases.MatchVersion(t.Version, pin.Tag):
+			problems = append(problems, fmt.Sprintf("%s is locked to %s, which does not match version %q", t.Repo, pin.Tag, t.Version))
+		case slices.ContainsFunc(t.Block, func(tag string) bool { return strings.EqualFold(tag, pin.Tag) }):
+			problems = append(problems, fmt.Sprintf("%s is locked to %s, which is blocked", t.Repo, pin.Tag))
+		case t.Asset != "" && !globMatch(t.Asset, pin.Asset):
+			problems = append(problems, fmt.Sprintf("%s is locked to asset %s, which does not match %q", t.Repo, pin.Asset, t.Asset))
+		}
+	}
+	for _, pin := range l.Tools {
+		if !listed[strings.ToLower(pin.Repo)] {
+			problems = append(problems, fmt.Sprintf("%s is locked but not in the manifest", pin.Repo))
+		}
+	}
+	return problems
+}
+
+// globMatch reports whether an asset glob, as in a manifest, matches name.
+func globMatch(pattern, name string) bool {
+	ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(name))
+	return ok
+}
+
+// save writes the lockfile.
+func (l *lockfile) save(file string) error {
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+	header := "# Generated by get_gh_release apply. Do not edit.\n"
+	if err := os.WriteFile(file, append([]byte(header), data...), 0644); err != nil {
+		return fmt.Errorf("could not write lockfile: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLockfileDrift(t *testing.T) {
+	lock := &lockfile{Tools: []lockedTool{
+		{Repo: "Me/Tool", Tag: "v1.2.0", Asset: "tool_linux_amd64.tar.gz"},
+		{Repo: "me/other", Tag: "v2.0.0", Asset: "other"},
+	}}
+	tests := []struct {
+		name  string
+		tools []manifestTool
+		want  []string
+	}{
+		{
+			name:  "in step, ignoring case",
+			tools: []manifestTool{{Repo: "me/tool", Version: "^1.2"}, {Repo: "me/other"}},
+		},
+		{
+			name:  "tool not locked",
+			tools: []manifestTool{{Repo: "me/tool"}, {Repo: "me/other"}, {Repo: "me/new"}},
+			want:  []string{"me/new is not locked"},
+		},
+		{
+			name:  "tool no longer listed",
+			tools: []manifestTool{{Repo: "me/tool"}},
+			want:  []string{"me/other is locked but not in the manifest"},
+		},
+		{
+			name:  "version moved on",
+			tools: []manifestTool{{Repo: "me/tool", Version: "^2"}, {Repo: "me/other", Version: "v2.0"}},
+			want:  []string{`me/tool is locked to v1.2.0, which does not match version "^2"`},
+		},
+		{
+			name:  "locked tag blocked",
+			tools: []manifestTool{{Repo: "me/tool", Block: []string{"V1.2.0"}}, {Repo: "me/other"}},
+			want:  []string{"me/tool is locked to v1.2.0, which is blocked"},
+		},
+		{
+			name:  "asset glob changed",
+			tools: []manifestTool{{Repo: "me/tool", Asset: "*.zip"}, {Repo: "me/other", Asset: "OTHER*"}},
+			want:  []string{`me/tool is locked to asset tool_linux_amd64.tar.gz, which does not match "*.zip"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lock.drift(&manifest{Tools: tt.tools})
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockPath(t *testing.T) {
+	tests := map[string]string{
+		"tools.yaml":           "tools.lock",
+		"dir/tools.yml":        "dir/tools.lock",
+		"dir/tools.toml":       "dir/tools.lock",
+		".get-gh-release.yaml": ".get-gh-release.lock",
+		"-":                    "",
+	}
+	for manifest, want := range tests {
+		if got := lockPath(manifest); got != want {
+			t.Errorf("lockPath(%q) = %q, want %q", manifest, got, want)
+		}
+	}
+}
+
+func TestLockfileFind(t *testing.T) {
+	lock := &lockfile{Tools: []lockedTool{{Repo: "Me/Tool", Tag: "v1.2.0"}}}
+	if pin := lock.find("me/tool"); pin == nil || pin.Tag != "v1.2.0" {
+		t.Errorf("find(me/tool) = %v, want the entry locked as Me/Tool", pin)
+	}
+	if pin := lock.find("me/other"); pin != nil {
+		t.Errorf("find(me/other) = %v, want nil", pin)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// setupLogging sets the level of log messages, from slog and the log package alike, and
+// their format: text keeps the log package's lines on standard error, json writes one JSON
+// object per message there instead.
+func setupLogging(level, format string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("unknown log level %q (want error, warn, info or debug)", level)
+	}
+	switch format {
+	case "text":
+		slog.SetLogLoggerLevel(l)
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: l})))
+	default:
+		return fmt.Errorf("unknown log format %q (want text or json)", format)
+	}
+	return nil
+}
+
+// debugEnabled reports whether debug messages are logged.
+func debugEnabled() bool {
+	return slog.Default().Enabled(context.Background(), slog.LevelDebug)
+}
+
+// logExplanation logs one search decision at debug level, as -explain prints it.
+func logExplanation(e releases.Explanation) {
+	slog.Debug("match", "repo", e.Repo.Owner+"/"+e.Repo.Name, "tag", e.Tag, "asset", e.Asset,
+		"verdict", explanationVerdict(e), "reason", e.Reason)
+}
+
+// logTransport logs every request at debug level with its outcome and duration.
+type logTransport struct {
+	base http.RoundTripper
+}
+
+func (t logTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	took := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		slog.Debug("request failed", "method", req.Method, "url", req.URL.Redacted(), "duration", took, "err", err)
+		return resp, err
+	}
+	slog.Debug("request", "method", req.Method, "url", req.URL.Redacted(), "status", resp.StatusCode, "duration", took)
+	return resp, err
+}
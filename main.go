@@ -1,17 +1,24 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"syscall"
+	"time"
 
-	"github.com/google/go-github/v62/github"
-	"golang.org/x/oauth2"
+	"github.com/abgoyal/get_gh_release/pkg/releases"
 )
 
 // staticToken is a fallback token.
@@ -19,25 +26,288 @@ import (
 // If you must embed a token, be aware of the security risks.
 const staticToken = ""
 
-// releaseCandidate holds information about a downloadable release asset.
-type releaseCandidate struct {
-	RepoOwner   string
-	RepoName    string
-	AssetName   string
-	DownloadURL string
-	AssetID     int64
-}
-
 func main() {
 
 	// 1. Argument and Flag Parsing
-	tokenFlag := flag.String("token", "", "GitHub personal access token.")
+	tokenFlag := flag.String("token", "", "GitHub personal access token, or GitLab or Gitea access token with -source gitlab or gitea.")
+	noAuthFlag := flag.Bool("no-auth", false, "Use no token, for public repositories named as owner/repo; GitHub allows 60 such requests an hour.")
+	apiURLFlag := flag.String("api-url", "", "API URL of a GitHub Enterprise Server, e.g. https://github.example.com/api/v3/ (default from GH_HOST, otherwise github.com), or of a GitLab instance with -source gitlab (default gitlab.com) or Gitea or Forgejo instance with -source gitea.")
+	uploadURLFlag := flag.String("upload-url", "", "Upload URL of a GitHub Enterprise Server (default derived from -api-url).")
 	publicFlag := flag.Bool("public", false, "Search public repositories.")
+	systemdUnitFlag := flag.Bool("systemd-unit", false, "Print a systemd user service unit for the downloaded binary.")
+	systemdInstallFlag := flag.Bool("systemd-install", false, "Write the generated systemd unit to ~/.config/systemd/user/.")
+	systemdRestartFlag := flag.String("systemd-restart", "on-failure", "Restart policy template for the systemd unit (always, on-failure, no).")
+	policyFlag := flag.String("policy", policyNotify, "Update policy recorded for the installed tool (auto, notify, pinned).")
+	minAgeFlag := flag.Duration("min-age", 0, "Only adopt releases published at least this long ago, e.g. 72h; recorded for later updates.")
+	emitScriptFlag := flag.String("emit-script", "", "Write a standalone POSIX shell installer for the selected asset to this file (- for standard output) instead of installing it.")
+	sourceFlag := flag.String("source", "github", "Where releases come from: github, gitlab, gitea (also Forgejo), or the name of a get-gh-release-<name> plugin on PATH.")
+	flag.StringVar(sourceFlag, "provider", "github", "Same as -source.")
+	postInstallFlag := flag.String("post-install", "", "Comma-separated get-gh-release-<name> plugins to run after every install.")
+	recordFlag := flag.String("record", "", "Record every GitHub API exchange to this cassette file.")
+	replayFlag := flag.String("replay", "", "Answer GitHub API requests from a cassette written by -record instead of the network.")
+	minSizeFlag := flag.Int64("min-size", releases.DefaultMinSize, "Skip assets smaller than this many bytes (-1 for no limit).")
+	explainFlag := flag.Bool("explain", false, "Print to standard error why each repository and asset was or was not picked.")
+	strictFlag := flag.Bool("strict", false, "Fail if any repository could not be searched, instead of only warning (default in -ci mode).")
+	ciFlag := flag.Bool("ci", detectCI(), "Behave for a CI pipeline: no colour or interactive screens, and -strict. Defaults to true when CI variables such as CI or GITHUB_ACTIONS are set.")
+	summaryFlag := flag.String("summary", "text", "Summary printed to standard error when the run ends (text, json or none).")
+	chunksFlag := flag.Int("chunks", 4, "Parallel range requests for assets of 64 MiB or more (1 to disable).")
+	sortFlag := flag.String("sort", "pushed", "Order in which repositories are searched: pushed, updated, created or full_name.")
+	maxReposFlag := flag.Int("max-repos", 0, "Search at most this many repositories, in -sort order (0 for all).")
+	pageSizeFlag := flag.Int("page-size", 100, "Repositories requested per page when listing them (1-100).")
+	requestIDFlag := flag.String("request-id", "", "Value of an X-Request-Id header sent with every request, to find this run in server logs.")
+	rawFlag := flag.Bool("raw", false, "Print release notes as published markdown instead of rendering them.")
+	versionFlag := flag.Bool("version", false, "Print the version and build details of this binary and exit.")
+	flag.BoolVar(&showNotes, "notes", false, "Print the notes of the release, with its tag and publication date, before downloading it or upgrading to it.")
+	withNotesFlag := flag.Bool("with-notes", false, "Save the release notes as <tool>-<tag>.md next to the installed binary.")
+	withSBOMFlag := flag.Bool("with-sbom", false, "Save the release's SBOM assets, if any, next to the installed binary.")
+	verifyFlag := flag.String("verify", "prefer", "Signature checking: required refuses assets without a valid GPG or cosign signature, prefer checks the signatures a release publishes when it can, off ignores them.")
+	gpgKeyringFlag := flag.String("gpg-keyring", os.Getenv("GET_GH_RELEASE_KEYRING"), "Keyring file to check GPG signatures against (default your own gpg keyring).")
+	sha256Flag := flag.String("sha256", "", "Refuse to install the asset unless the SHA-256 digest of its downloaded bytes is this, whatever checksum its release publishes.")
+	requireChecksumFlag := flag.Bool("require-checksum", false, "Refuse to install an asset unless its release publishes a checksum for it (checksums.txt, SHA256SUMS or <asset>.sha256).")
+	destFlag := flag.String("dest", "", "Directory to install into (default the project manifest's, $GET_GH_RELEASE_DEST, dest in the configuration file, or "+defaultInstallDir+").")
+	nameFlag := flag.String("name", "", "File name to install the binary as (default the asset name, or the repository name for archives).")
+	quietFlag := flag.Bool("quiet", false, "Do not show the progress of downloads.")
+	concurrencyFlag := flag.Int("concurrency", 4, "Number of repositories whose releases are looked up at once. Keep it modest to stay clear of GitHub's secondary rate limits.")
+	osFlag := flag.String("os", runtime.GOOS, "Operating system to select assets for, e.g. linux or darwin.")
+	archFlag := flag.String("arch", runtime.GOARCH, "Architecture to select assets for, e.g. amd64 or arm64.")
+	libcFlag := flag.String("libc", "auto", "C library to prefer Linux assets for: musl (also static builds), gnu, auto to detect this machine's, or none.")
+	preFlag := flag.Bool("pre", false, "Consider prereleases, both for the latest release and for a version constraint such as ^1.4.")
+	draftFlag := flag.Bool("draft", false, "Consider draft releases, which only collaborators with push access can see.")
+	noInteractiveFlag := flag.Bool("no-interactive", false, "Never prompt: when several assets match, list them and exit, as in scripts.")
+	keepArchiveFlag := flag.Bool("keep-archive", false, "Install archive assets as downloaded instead of unpacking the executable from them.")
+	deltaFlag := flag.Bool("delta", false, "Update by applying a published bsdiff patch to the cached previous release when there is one.")
+	ifNeededFlag := flag.Bool("if-needed", false, "Do nothing if the selected release is already installed at the destination and unmodified.")
+	reinstallFlag := flag.Bool("reinstall", false, "Install even if the selected release is already installed, also in apply, sync and import.")
+	var excludeAssetFlag, orgFlag stringList
+	flag.Var(&orgFlag, "org", "Search the repositories of this organization instead of your own (repeatable).")
+	assetFlag := flag.String("asset", "", "Select assets whose name matches this glob, or regular expression in /slashes/, instead of matching the platform; a pattern without wildcards matches names containing it.")
+	globFlag := flag.String("glob", "", "Select assets whose name matches this glob instead of matching the platform; also accepted as the third argument.")
+	flag.Var(&excludeAssetFlag, "exclude-asset", "Skip assets matching this glob, or regular expression in /slashes/ (repeatable).")
+	mirrorFlag := flag.String("mirror", os.Getenv("GET_GH_RELEASE_MIRROR"), "Base URL of a get_gh_release serve instance to fetch assets from before GitHub.")
+	cpuProfileFlag := flag.String("cpuprofile", "", "Write a CPU profile to this file.")
+	memProfileFlag := flag.String("memprofile", "", "Write a heap profile to this file when the run ends.")
+	traceFlag := flag.String("trace", "", "Write an execution trace to this file.")
+	dryRunFlag := flag.Bool("dry-run", false, "Find and match the asset, then print what would be downloaded and where it would be installed, without downloading or writing anything.")
+	listFlag := flag.Bool("list", false, "List every matching asset and exit without downloading, even when only one matches.")
+	longFlag := flag.Bool("long", false, "With -list, also show each asset's tag, size and publication date.")
+	timeoutFlag := flag.Duration("timeout", time.Minute, "Give up on a request when no response, or during a download no data, arrives for this long (0 to wait forever).")
+	flag.IntVar(&retries, "retries", retries, "Number of times to retry a request or download that fails with a server error, a reset connection or a cut-short response (0 to never retry).")
+	flag.DurationVar(&retryWait, "retry-wait", retryWait, "Wait before the first retry, doubled for each one after it, with random jitter.")
+	rateLimitWaitFlag := flag.Duration("rate-limit-wait", 2*time.Minute, "Longest time to wait for a GitHub rate limit to lift before retrying; longer limits fail at once (0 to never wait).")
+	verboseFlag := flag.Bool("verbose", false, "Print more detail: every API request and matching decision, as -log-level debug, and the GitHub API quota left at the end of the run.")
+	logLevelFlag := flag.String("log-level", "info", "Least severe log messages shown: error, warn, info or debug.")
+	logFormatFlag := flag.String("log-format", "text", "Format of log messages on standard error: text, or json for one object per line.")
+	noCacheFlag := flag.Bool("no-cache", false, "Do not answer GitHub API requests from the response cache, nor add to it.")
+	jsonFlag := flag.Bool("json", false, "Print the result of a download as JSON on standard output, including failures; other messages go to standard error.")
+	flag.Usage = usage
 	flag.Parse()
+	if *versionFlag {
+		printVersion()
+		return
+	}
+	if *jsonFlag {
+		startJSON()
+	}
+	// The user's configuration supplies defaults for the flags not given
+	cfg, err := loadConfig()
+	if err != nil {
+		fatalf("Error loading configuration: %v", err)
+	}
+	if err := cfg.apply(); err != nil {
+		fatalf("Invalid configuration: %v", err)
+	}
+	configPins = cfg.pins()
+	if *verboseFlag && !flagSet("log-level") {
+		*logLevelFlag = "debug"
+	}
+	if err := setupLogging(*logLevelFlag, *logFormatFlag); err != nil {
+		fatalf("Invalid logging flags: %v", err)
+	}
+	if err := startProfiling(*cpuProfileFlag, *memProfileFlag, *traceFlag); err != nil {
+		fatalf("Error starting profiling: %v", err)
+	}
+	defer func() { stopProfiling() }()
+	assetMirror = *mirrorFlag
+	rawMarkdown = *rawFlag
+	reinstall = *reinstallFlag
+	dryRun = *dryRunFlag
+	deltaUpdates = *deltaFlag
+	extractArchives = !*keepArchiveFlag
+	noInteractive = *noInteractiveFlag
+	quiet = *quietFlag
+	verbose = *verboseFlag
+	requireChecksum = *requireChecksumFlag
+	verifyMode = *verifyFlag
+	gpgKeyring = *gpgKeyringFlag
+	ciMode = *ciFlag
+	if ciMode && !flagSet("strict") {
+		*strictFlag = true
+	}
+	postInstallPlugins = splitList(*postInstallFlag)
+
+	if _, ok := restartTemplates[*systemdRestartFlag]; !ok {
+		fatalf("Unknown systemd restart policy %q (want always, on-failure or no)", *systemdRestartFlag)
+	}
+	if *assetFlag != "" {
+		if err := (releases.Rule{Include: *assetFlag}).Validate(); err != nil {
+			fatalf("Invalid -asset: %v", err)
+		}
+	}
+	for _, p := range excludeAssetFlag {
+		if err := (releases.Rule{Exclude: p}).Validate(); err != nil {
+			fatalf("Invalid -exclude-asset: %v", err)
+		}
+	}
+	if !validLibc(*libcFlag) {
+		fatalf("Unknown -libc %q (want auto, musl, gnu or none)", *libcFlag)
+	}
+	switch verifyMode {
+	case "required", "prefer", "off":
+	default:
+		fatalf("Unknown -verify mode %q (want required, prefer or off)", verifyMode)
+	}
+	if !validSummaryFormat(*summaryFlag) {
+		fatalf("Unknown summary format %q (want text, json or none)", *summaryFlag)
+	}
+	switch *sortFlag {
+	case "pushed", "updated", "created", "full_name":
+	default:
+		fatalf("Unknown repository order %q (want pushed, updated, created or full_name)", *sortFlag)
+	}
+	if jsonOutput && subcommands[flag.Arg(0)] {
+		fatalf("-json only applies to downloads, not to %s", flag.Arg(0))
+	}
+	if jsonOutput && *emitScriptFlag == "-" {
+		fatalf("-json cannot be combined with -emit-script -")
+	}
+	if dryRun && *emitScriptFlag != "" {
+		fatalf("-dry-run cannot be combined with -emit-script")
+	}
+	if *concurrencyFlag < 1 {
+		fatalf("Invalid -concurrency %d (want at least 1)", *concurrencyFlag)
+	}
+	if !validPolicy(*policyFlag) {
+		fatalf("Unknown update policy %q (want auto, notify or pinned)", *policyFlag)
+	}
+	var pinnedDigest string
+	if *sha256Flag != "" {
+		if pinnedDigest, err = parseSHA256(*sha256Flag); err != nil {
+			fatalf("Invalid -sha256: %v", err)
+		}
+	}
+	if *timeoutFlag < 0 {
+		fatalf("Invalid -timeout %s (want 0 or more)", *timeoutFlag)
+	}
+	if retries < 0 {
+		fatalf("Invalid -retries %d (want 0 or more)", retries)
+	}
+	if retryWait <= 0 {
+		fatalf("Invalid -retry-wait %s (want more than 0)", retryWait)
+	}
+	// Requests made outside the providers, to mirrors, registries and webhooks, time out and
+	// are retried too
+	var defaultTransport http.RoundTripper = http.DefaultTransport
+	if *timeoutFlag > 0 {
+		defaultTransport = timeoutTransport{base: defaultTransport, timeout: *timeoutFlag}
+	}
+	http.DefaultClient.Transport = retryTransport{base: defaultTransport, retries: retries}
+
+	// Ctrl-C or SIGTERM cancels the run, leaving no partial files behind; a second one kills it
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+	if _, _, ok := webRepo(flag.Arg(0)); *sourceFlag == "gitea" && *apiURLFlag == "" && !ok {
+		fatalf("-source gitea needs -api-url, the address of the Gitea or Forgejo instance")
+	}
+
+	// Commands that only work on local state need no token or client.
+	switch flag.Arg(0) {
+	case "export":
+		if err := runExport(flag.Args()[1:]); err != nil {
+			fatalf("Error exporting tools: %v", err)
+		}
+		return
+	case "allow":
+		if err := runAllow(flag.Args()[1:]); err != nil {
+			fatalf("Error allowing manifest: %v", err)
+		}
+		return
+	case "cache":
+		if err := runCache(flag.Args()[1:]); err != nil {
+			fatalf("Error clearing cache: %v", err)
+		}
+		return
+	case "login":
+		if err := runLogin(ctx, forgeAPIURL(*sourceFlag, *apiURLFlag), flag.Args()[1:]); err != nil {
+			fatalf("Error logging in: %v", err)
+		}
+		return
+	case "init":
+		if err := runInit(flag.Args()[1:]); err != nil {
+			fatalf("Error initialising manifest: %v", err)
+		}
+		return
+	case "verify":
+		if err := runVerify(flag.Args()[1:]); err != nil {
+			fatalf("Error verifying installed tools: %v", err)
+		}
+		return
+	case "completion":
+		if err := runCompletion(flag.Args()[1:]); err != nil {
+			fatalf("Error generating completion: %v", err)
+		}
+		return
+	case "list":
+		if err := runList(flag.Args()[1:]); err != nil {
+			fatalf("Error listing installed tools: %v", err)
+		}
+		return
+	case "uninstall":
+		if err := runUninstall(flag.Args()[1:]); err != nil {
+			fatalf("Error uninstalling: %v", err)
+		}
+		return
+	case "serve":
+		if err := runServe(ctx, flag.Args()[1:]); err != nil {
+			fatalf("Error serving cache: %v", err)
+		}
+		return
+	case "bundle":
+		if flag.Arg(1) == "install" {
+			if err := runBundleInstall(ctx, flag.Args()[2:]); err != nil {
+				fatalf("Error installing bundle: %v", err)
+			}
+			return
+		}
+	}
+
+	// A project-local manifest supplies defaults and version pins, which take the place of
+	// those in the configuration.
+	project, err := loadProjectManifest()
+	if err != nil {
+		fatalf("Error loading project manifest: %v", err)
+	}
+	pins := cfg.pins()
+	if project != nil {
+		maps.Copy(pins, project.pins())
+		if project.Defaults.Policy != "" && !flagSet("policy") {
+			*policyFlag = project.Defaults.Policy
+		}
+		if project.Defaults.MinAge != "" && !flagSet("min-age") {
+			*minAgeFlag, _ = time.ParseDuration(project.Defaults.MinAge)
+		}
+	}
 
 	repoPattern := ""
 	if len(flag.Args()) > 0 {
-		repoPattern = strings.ToLower(flag.Args()[0])
+		repoPattern = flag.Args()[0]
+	}
+	// A repository given by its web address, such as gitlab.com/owner/repo, picks the forge
+	if host, repo, ok := webRepo(repoPattern); ok && !subcommands[flag.Arg(0)] {
+		if *sourceFlag, *apiURLFlag, err = sourceForHost(host, *sourceFlag, *apiURLFlag); err != nil {
+			fatalf("Error: %v", err)
+		}
+		repoPattern = repo
 	}
 
 	versionPattern := ""
@@ -45,201 +315,710 @@ func main() {
 		versionPattern = strings.ToLower(flag.Args()[1])
 	}
 
-	// 2. Token Acquisition
-	token := getToken(*tokenFlag)
-	if token == "" {
-		fmt.Println("GitHub token not found. Provide one via -token flag or GH_TOKEN env var.")
-		return
+	if releases.IsConstraint(versionPattern) && !subcommands[flag.Arg(0)] {
+		if _, err := releases.ParseConstraint(versionPattern); err != nil {
+			fatalf("Invalid version constraint: %v", err)
+		}
 	}
 
-	// 3. Platform Verification
-	platformArch := runtime.GOARCH
-	platformOS := runtime.GOOS
-	if platformOS != "linux" || (platformArch != "amd64" && platformArch != "arm64") {
-		fmt.Fprintf(os.Stderr, "This program is designed to run only on linux/amd64 or linux/arm64. Detected: %s/%s\n", platformOS, platformArch)
-		os.Exit(1)
+	// An asset glob replaces the platform check entirely
+	assetGlob := *globFlag
+	if len(flag.Args()) > 2 && !subcommands[flag.Arg(0)] {
+		assetGlob = flag.Args()[2]
+	}
+	if _, err := path.Match(assetGlob, ""); err != nil {
+		fatalf("Invalid asset glob %q: %v", assetGlob, err)
 	}
 
-	// 4. GitHub Client Initialization
-	ctx := context.Background()
-	// Create a new token source
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-	// Create a new HTTP client with the token source
-	tc := oauth2.NewClient(ctx, ts)
-	// Create a new GitHub client
-	client := github.NewClient(tc)
-
-	// 5. Find Release Candidates
-
-	candidates, err := findReleaseCandidates(ctx, client, repoPattern, versionPattern, platformOS, platformArch, *publicFlag)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error finding releases: %v\n", err)
-		os.Exit(1)
+	// 2. Token Acquisition
+	var token string
+	if *tokenFlag == "" && !*noAuthFlag {
+		if *tokenFlag, err = cfg.token(); err != nil {
+			fatalf("Error reading token: %v", err)
+		}
+	}
+	if *noAuthFlag {
+		if _, _, ok := strings.Cut(repoPattern, "/"); !ok && !subcommands[flag.Arg(0)] {
+			fatalf("-no-auth needs an owner/repo argument: listing repositories requires a token")
+		}
+	} else if *sourceFlag == "gitlab" {
+		token = forgeToken(*tokenFlag, "GITLAB_TOKEN", apiHost(forgeAPIURL(*sourceFlag, *apiURLFlag)))
+	} else if *sourceFlag == "gitea" {
+		token = forgeToken(*tokenFlag, "GITEA_TOKEN", apiHost(forgeAPIURL(*sourceFlag, *apiURLFlag)))
+	} else {
+		token = getToken(*tokenFlag, apiHost(enterpriseAPIURL(*apiURLFlag)))
+		if token == "" && *sourceFlag == "github" && *replayFlag == "" {
+			fmt.Println("GitHub token not found. Provide one via -token flag or GH_TOKEN env var, store one with get_gh_release login (-device to authorise in a browser), or log in with gh auth login. Use -no-auth for public repositories.")
+			return
+		}
 	}
 
-	// 6. Action based on number of candidates
-	switch len(candidates) {
-	case 0:
-		fmt.Println("No matching release artifacts found for your platform.")
-	case 1:
-		c := candidates[0]
-		fmt.Printf("%s/%s: %s\n", c.RepoOwner, c.RepoName, c.AssetName)
-		if err := downloadAndPrepare(ctx, client, tc, c); err != nil {
-			fmt.Println("failed")
-			fmt.Fprintf(os.Stderr, "Error downloading and preparing artifact: %v\n", err)
-			os.Exit(1)
+	// 3. Platform Selection: this machine unless -os or -arch fetch for another one
+	platformOS := *osFlag
+	platformArch := *archFlag
+	platformLibc = resolveLibc(*libcFlag, platformOS)
+
+	// 4. GitHub Client Initialization
+	transport := http.DefaultTransport
+	if *timeoutFlag > 0 {
+		transport = timeoutTransport{base: transport, timeout: *timeoutFlag}
+	}
+	transport = retryTransport{base: transport, retries: retries}
+	// Record or replay the exchanges with GitHub if asked to
+	switch {
+	case *replayFlag != "":
+		replay, err := loadReplayTransport(*replayFlag)
+		if err != nil {
+			fatalf("Error loading cassette: %v", err)
+		}
+		transport = replay
+	case *recordFlag != "":
+		rec := &recordingTransport{base: transport, file: *recordFlag}
+		stopRecording = func() {
+			if err := rec.save(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
 		}
+		defer func() { stopRecording() }()
+		transport = rec
 	default:
+		// Revalidate API responses seen before, unless cassettes need to see every exchange or a
+		// dry run must leave the disk alone
+		if dir, err := apiCacheDir(); err == nil && !*noCacheFlag && !dryRun {
+			transport = &etagTransport{base: transport, dir: dir}
+		}
+	}
+	// Identify the tool, and the run if asked, to the server
+	header := http.Header{"User-Agent": {userAgent()}}
+	if *requestIDFlag != "" {
+		header.Set("X-Request-Id", *requestIDFlag)
+	}
+	transport = &headerTransport{base: transport, header: header}
+	if debugEnabled() {
+		transport = logTransport{base: transport}
+	}
+	// Record the rate limit headers of every response for the metrics endpoint
+	rateLimits := &rateLimitTransport{base: transport}
+	if token == "" {
+		// Anonymous requests have a small budget, so say when it is running out
+		rateLimits.low = 10
+	}
+	transport = rateLimits
+	// Create a client for the forge releases come from, authenticated with the token
+	var provider releases.ReleaseProvider
+	switch *sourceFlag {
+	case "github":
+		gh := releases.NewGitHub(token, releases.WithTransport(transport))
+		if apiURL := enterpriseAPIURL(*apiURLFlag); apiURL != "" {
+			var err error
+			if gh, err = releases.NewGitHubEnterprise(apiURL, *uploadURLFlag, token, releases.WithTransport(transport)); err != nil {
+				fatalf("Invalid GitHub Enterprise URL: %v", err)
+			}
+			useEnterprise(gh.Client.BaseURL)
+		}
+		gh.Public = *publicFlag
+		gh.Orgs = orgFlag
+		gh.Chunks = *chunksFlag
+		gh.Sort = *sortFlag
+		gh.PerPage = *pageSizeFlag
+		gh.MaxRepos = *maxReposFlag
+		provider = gh
+	case "gitlab":
+		gl, err := releases.NewGitLab(forgeAPIURL(*sourceFlag, *apiURLFlag), token, releases.WithTransport(transport))
+		if err != nil {
+			fatalf("Invalid GitLab URL: %v", err)
+		}
+		useForge(gl.BaseURL)
+		gl.Public = *publicFlag
+		gl.Orgs = orgFlag
+		gl.Chunks = *chunksFlag
+		gl.Sort = *sortFlag
+		gl.PerPage = *pageSizeFlag
+		gl.MaxRepos = *maxReposFlag
+		provider = gl
+	case "gitea":
+		gt, err := releases.NewGitea(*apiURLFlag, token, releases.WithTransport(transport))
+		if err != nil {
+			fatalf("Invalid Gitea URL: %v", err)
+		}
+		useForge(gt.BaseURL)
+		gt.Public = *publicFlag
+		gt.Orgs = orgFlag
+		gt.Chunks = *chunksFlag
+		gt.Sort = *sortFlag
+		gt.PerPage = *pageSizeFlag
+		gt.MaxRepos = *maxReposFlag
+		provider = gt
+	default:
+		provider = pluginProvider{name: *sourceFlag}
+	}
+	provider = releases.RateLimitRetry{
+		ReleaseProvider: provider,
+		MaxWait:         *rateLimitWaitFlag,
+		Waiting: func(err *releases.RateLimitError, wait time.Duration) {
+			fmt.Fprintf(os.Stderr, "Rate limited by GitHub; retrying in %s\n", wait.Round(time.Second))
+		},
+	}
+	provider = countingProvider{provider}
+	defer stats.write(os.Stderr, *summaryFlag)
+	if verbose {
+		defer printQuota(os.Stderr)
+	}
 
-		for _, c := range candidates {
-			fmt.Printf("%s/%s: %s\n", c.RepoOwner, c.RepoName, c.AssetName)
+	// Asset selection follows the project manifest and the asset flags
+	finder := releases.Finder{
+		Provider:    provider,
+		Matcher:     releases.Matcher{OS: platformOS, Arch: platformArch, Libc: platformLibc, Glob: assetGlob, MinSize: *minSizeFlag},
+		Filter:      releases.ReleaseFilter{MinAge: *minAgeFlag, Prereleases: *preFlag, Drafts: *draftFlag},
+		Concurrency: *concurrencyFlag,
+		Skipped: func(r releases.Repo, s releases.Skip) {
+			fmt.Fprintf(os.Stderr, "Skipping %s/%s %s: %s\n", r.Owner, r.Name, s.Release.Tag, s.Reason)
+		},
+	}
+	finder.Matcher.Rules = cfg.rules()
+	finder.RepoRules = cfg.repoRules(*assetFlag == "")
+	if project != nil {
+		finder.Matcher.Rules = append(finder.Matcher.Rules, project.Defaults.Match...)
+		for repo, rules := range project.repoRules() {
+			finder.RepoRules[repo] = append(finder.RepoRules[repo], rules...)
 		}
+		finder.RepoBlocked = project.blocked()
+	}
+	if *assetFlag != "" {
+		finder.Matcher.Rules = append(finder.Matcher.Rules, releases.Rule{Include: *assetFlag})
+	}
+	for _, p := range excludeAssetFlag {
+		finder.Matcher.Rules = append(finder.Matcher.Rules, releases.Rule{Exclude: p})
+	}
+	if *explainFlag {
+		finder.Explain = printExplanation
+	} else if debugEnabled() {
+		finder.Explain = logExplanation
+	}
+
+	// 5. Subcommand Dispatch
+	switch flag.Arg(0) {
+	case "search":
+		if err := runSearch(ctx, finder, flag.Args()[1:]); err != nil {
+			fatalf("Error searching releases: %v", err)
+		}
+		return
+	case "info":
+		if err := runInfo(ctx, provider, releases.ReleaseFilter{Prereleases: *preFlag, Drafts: *draftFlag}, flag.Args()[1:]); err != nil {
+			fatalf("Error showing release: %v", err)
+		}
+		return
+	case "tui":
+		if err := runDashboard(ctx, provider, platformOS, platformArch, flag.Args()[1:]); err != nil {
+			fatalf("Error running dashboard: %v", err)
+		}
+		return
+	case "daemon":
+		if err := runDaemon(ctx, provider, platformOS, platformArch, flag.Args()[1:]); err != nil {
+			fatalf("Error running daemon: %v", err)
+		}
+		return
+	case "apply":
+		if err := runApply(ctx, provider, platformOS, platformArch, flag.Args()[1:]); err != nil {
+			fatalf("Error applying manifest: %v", err)
+		}
+		return
+	case "import":
+		if err := runImport(ctx, provider, platformOS, platformArch, flag.Args()[1:]); err != nil {
+			fatalf("Error importing tools: %v", err)
+		}
+		return
+	case "bundle":
+		if flag.Arg(1) != "create" {
+			fatalf("usage: get_gh_release bundle create|install ...")
+		}
+		if err := runBundleCreate(ctx, provider, platformOS, platformArch, flag.Args()[2:]); err != nil {
+			fatalf("Error creating bundle: %v", err)
+		}
+		return
+	case "mirror":
+		if err := runMirror(ctx, provider, platformOS, platformArch, flag.Args()[1:]); err != nil {
+			fatalf("Error mirroring releases: %v", err)
+		}
+		return
+	case "push":
+		if err := runPush(ctx, provider, platformOS, platformArch, flag.Args()[1:]); err != nil {
+			fatalf("Error pushing to registry: %v", err)
+		}
+		return
+	case "webhook":
+		if err := runWebhook(ctx, provider, platformOS, platformArch, flag.Args()[1:]); err != nil {
+			fatalf("Error running webhook listener: %v", err)
+		}
+		return
+	case "sync":
+		if err := runSync(ctx, provider, platformOS, platformArch, flag.Args()[1:]); err != nil {
+			fatalf("Error syncing manifest: %v", err)
+		}
+		return
+	case "inventory":
+		if err := runInventory(ctx, provider, platformOS, platformArch, flag.Args()[1:]); err != nil {
+			fatalf("Error generating inventory: %v", err)
+		}
+		return
+	case "report":
+		if err := runReport(ctx, provider, flag.Args()[1:]); err != nil {
+			fatalf("Error generating report: %v", err)
+		}
+		return
+	case "upgrade":
+		if err := runUpgrade(ctx, provider, platformOS, platformArch, flag.Args()[1:]); err != nil {
+			fatalf("Error upgrading tools: %v", err)
+		}
+		return
+	case "self-update":
+		if err := runSelfUpdate(ctx, provider, platformOS, platformArch, releases.ReleaseFilter{Prereleases: *preFlag}, flag.Args()[1:]); err != nil {
+			fatalf("Error updating get_gh_release: %v", err)
+		}
+		return
 	}
-}
 
-// getToken resolves the GitHub token from flag, environment variable, or static constant.
-func getToken(tokenFlag string) string {
-	if tokenFlag != "" {
-		return tokenFlag
+	// 6. Find Release Candidates
+
+	endGroup := actionsGroup("Find releases matching " + repoPattern)
+	endPhase := stats.phase("find")
+	candidates, err := finder.Find(ctx, repoPattern, versionPattern, pins)
+	endPhase()
+	endGroup()
+	var partial *releases.PartialError
+	if errors.As(err, &partial) {
+		fmt.Fprintf(os.Stderr, "Warning: %v:\n", partial)
+		for _, e := range partial.Errors {
+			fmt.Fprintf(os.Stderr, "  %v\n", e)
+		}
+		if !*strictFlag {
+			err = nil
+		}
 	}
-	if token := os.Getenv("GH_TOKEN"); token != "" {
-		return token
+	if err == nil {
+		// Repositories cut short by Ctrl-C look like ones without a matching release
+		err = ctx.Err()
 	}
-	if staticToken != "" {
-		return staticToken
+	if err != nil {
+		fatalf("Error finding releases: %v", err)
+	}
+	// Say when a pin from the configuration keeps a newer release from being installed
+	for _, c := range candidates {
+		repo := releases.Repo{Owner: c.RepoOwner, Name: c.RepoName}
+		if pin := configPin(c.RepoOwner, c.RepoName); versionPattern == "" && pin != "" && pins[strings.ToLower(c.RepoOwner+"/"+c.RepoName)] == pin {
+			if held := heldBack(ctx, provider, finder.FilterFor(repo), c.RepoOwner, c.RepoName, c.Tag); held != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %s/%s is pinned to %s by the configuration, holding back %s\n", c.RepoOwner, c.RepoName, pin, held.Tag)
+			}
+		}
 	}
-	return ""
-}
 
-// findReleaseCandidates searches through repositories to find matching release assets.
-func findReleaseCandidates(ctx context.Context, client *github.Client, pattern, versionPattern, os, arch string, public bool) ([]releaseCandidate, error) {
-	var candidates []releaseCandidate
-	var repos []*github.Repository
+	if *listFlag {
+		if err := printCandidates(os.Stdout, candidates, *longFlag); err != nil {
+			fatalf("Error listing assets: %v", err)
+		}
+		result.Candidates = candidates
+		result.finish(actionList)
+		return
+	}
 
-	if public {
-		user, _, err := client.Users.Get(ctx, "")
+	// 7. Action based on number of candidates, letting a person at a terminal pick one
+	if len(candidates) > 1 && interactive() {
+		p := &prompter{r: bufio.NewReader(os.Stdin), w: os.Stderr}
+		c, err := pickCandidate(p, candidates)
 		if err != nil {
-			return nil, err
+			fatalf("Error: %v", err)
+		}
+		candidates = []releases.Candidate{c}
+	}
+	switch len(candidates) {
+	case 0:
+		fmt.Println("No matching release artifacts found for your platform.")
+		if ciMode {
+			fatalf("Error: %v", releases.ErrNoCandidates)
+		}
+		result.finish(actionNone)
+	case 1:
+		c := candidates[0]
+		result.selected(c)
+		if *emitScriptFlag != "-" {
+			fmt.Printf("%s/%s: %s\n", c.RepoOwner, c.RepoName, c.AssetName)
 		}
-		opts := &github.RepositoryListByUserOptions{
-			ListOptions: github.ListOptions{PerPage: 100},
+		dir, name := installTarget(c, project, cfg, *destFlag, *nameFlag)
+		dest := filepath.Join(expandHome(dir), name)
+		result.Path = dest
+		settings := toolSettings{Policy: *policyFlag, Pre: *preFlag}
+		settings.selectAssets(finder.MatcherFor(releases.Repo{Owner: c.RepoOwner, Name: c.RepoName}))
+		if *minAgeFlag > 0 {
+			settings.MinAge = minAgeFlag.String()
 		}
-		for {
-			r, resp, err := client.Repositories.ListByUser(ctx, user.GetLogin(), opts)
+		if project != nil {
+			if t := project.toolFor(c.RepoOwner, c.RepoName); t != nil {
+				settings.Hooks, settings.Block = t.Hooks, t.Block
+			}
+		}
+		if showNotes && *emitScriptFlag != "-" {
+			release, err := provider.ReleaseByTag(ctx, c.RepoOwner, c.RepoName, c.Tag)
 			if err != nil {
-				return nil, err
+				fatalf("Error fetching release notes: %v", err)
 			}
-			repos = append(repos, r...)
-			if resp.NextPage == 0 {
-				break
+			if prev := previousTag(dest); prev != "" && prev != c.Tag {
+				printChangelog(ctx, os.Stdout, provider, name, c.RepoOwner, c.RepoName, prev, release)
+			} else {
+				printReleaseNotes(os.Stdout, c.RepoOwner, c.RepoName, release)
 			}
-			opts.Page = resp.NextPage
 		}
-	} else {
-		opts := &github.RepositoryListOptions{
-			Visibility:  "private",
-			ListOptions: github.ListOptions{PerPage: 100},
+		if dryRun {
+			plan := planInstall(c, dest, *ifNeededFlag && !reinstall)
+			if err := plan.print(os.Stdout, settings.Hooks); err != nil {
+				fatalf("Error printing plan: %v", err)
+			}
+			result.Path, result.Overwrite = plan.dest, plan.overwrite
+			result.finish(actionDryRun)
+			return
 		}
-		for {
-			r, resp, err := client.Repositories.List(ctx, "", opts)
+		if *emitScriptFlag != "" {
+			endPhase := stats.phase("checksum")
+			// A pinned digest is what the script must check, and saves downloading the asset
+			digest := pinnedDigest
+			if digest == "" {
+				var err error
+				if digest, err = assetDigest(ctx, provider, c); err != nil {
+					fatalf("Error computing asset checksum: %v", err)
+				}
+			}
+			endPhase()
+			// The script installs the asset as published, so an archive keeps its own name
+			if name == installName(c) {
+				name = c.AssetName
+			}
+			script, err := renderInstallScript(c, digest, dir, name)
 			if err != nil {
-				return nil, err
+				fatalf("Error generating install script: %v", err)
 			}
-			repos = append(repos, r...)
-			if resp.NextPage == 0 {
-				break
+			if err := writeInstallScript(*emitScriptFlag, script); err != nil {
+				fatalf("Error writing install script: %v", err)
 			}
-			opts.Page = resp.NextPage
+			result.Path, result.SHA256 = *emitScriptFlag, digest
+			result.finish(actionScript)
+			return
 		}
-	}
-
-	for _, repo := range repos {
-		repoName := repo.GetName()
-		repoOwner := repo.GetOwner().GetLogin()
-
-		// Filter by repository name pattern if provided
-		if pattern != "" && !strings.Contains(strings.ToLower(repoName), pattern) {
-			continue
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			fatalf("Error creating install directory: %v", err)
 		}
-
-		// Get the release for the repository
-		var release *github.RepositoryRelease
-		if versionPattern == "" {
-			// If no version pattern is provided, get the latest release
+		warnNotOnPath(filepath.Dir(dest))
+		if *ifNeededFlag && !reinstall {
+			if rec := installedAt(dest); rec.current(c) && (pinnedDigest == "" || rec.SHA256 == pinnedDigest) {
+				fmt.Printf("%s: up to date (%s)\n", dest, c.Tag)
+				result.SHA256 = rec.SHA256
+				result.finish(actionUpToDate)
+				return
+			}
+		}
+		var digest string
+		endGroup := actionsGroup("Install " + c.RepoOwner + "/" + c.RepoName + " " + c.Tag)
+		endPhase := stats.phase("install")
+		err := withHooks(ctx, settings.Hooks, c, dest, previousTag(dest), func() error {
 			var err error
-			release, _, err = client.Repositories.GetLatestRelease(ctx, repoOwner, repoName)
+			digest, err = downloadVerified(ctx, provider, c, dest, pinnedDigest, installStages(c))
 			if err != nil {
-				// This often returns 404 if no releases exist. We can safely ignore it.
-				continue
+				fmt.Println("failed")
+				return err
 			}
-		} else {
-			// If a version pattern is provided, find the matching release
-			releases, _, err := client.Repositories.ListReleases(ctx, repoOwner, repoName, nil)
-			if err != nil {
-				continue
+			if err := recordInstall(c, dest, digest, settings); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not record install: %v\n", err)
 			}
-			for _, r := range releases {
-				if strings.Contains(strings.ToLower(r.GetTagName()), versionPattern) {
-					release = r
-					break
-				}
+			return nil
+		})
+		endPhase()
+		endGroup()
+		if err != nil {
+			fatalf("Error downloading and preparing artifact: %v", err)
+		}
+		if *withNotesFlag || *withSBOMFlag {
+			if err := saveExtras(ctx, provider, c, dest, *withNotesFlag, *withSBOMFlag); err != nil {
+				fatalf("Error saving release metadata: %v", err)
 			}
-			if release == nil {
-				continue
+		}
+		if abs, err := filepath.Abs(dest); err == nil {
+			dest = abs
+		}
+		result.Path, result.SHA256 = dest, digest
+		if err := actionsOutput([]string{"repo", "version", "asset", "sha256", "path"}, map[string]string{
+			"repo":    c.RepoOwner + "/" + c.RepoName,
+			"version": c.Tag,
+			"asset":   c.AssetName,
+			"sha256":  digest,
+			"path":    dest,
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		actionsAnnotate("notice", fmt.Sprintf("Installed %s/%s %s to %s", c.RepoOwner, c.RepoName, c.Tag, dest))
+		if *systemdUnitFlag || *systemdInstallFlag {
+			if err := emitSystemdUnit(c, dest, *systemdRestartFlag, *systemdInstallFlag); err != nil {
+				fatalf("Error generating systemd unit: %v", err)
 			}
 		}
+		result.finish(actionInstalled)
+	default:
+		result.Candidates = candidates
+		printCandidates(os.Stdout, candidates, false)
+		if ciMode {
+			fatalf("Error: %v", releases.ErrMultipleCandidates)
+		}
+		result.finish(actionMultiple)
+	}
+}
 
-		// Find a matching asset in the release
-		for _, asset := range release.Assets {
-			assetName := strings.ToLower(asset.GetName())
-			if strings.Contains(assetName, os) && strings.Contains(assetName, arch) {
+// subcommands are the first arguments that name a command rather than a repository pattern.
+var subcommands = map[string]bool{
+	"init": true, "login": true, "cache": true, "export": true, "verify": true, "serve": true, "bundle": true, "search": true, "info": true, "tui": true, "daemon": true,
+	"apply": true, "import": true, "mirror": true, "push": true, "webhook": true,
+	"sync": true, "inventory": true, "report": true, "allow": true, "self-update": true,
+	"list": true, "upgrade": true, "uninstall": true, "completion": true,
+}
 
-				candidates = append(candidates, releaseCandidate{
-					RepoOwner:   repoOwner,
-					RepoName:    repoName,
-					AssetName:   asset.GetName(),
-					DownloadURL: asset.GetBrowserDownloadURL(),
-					AssetID:     asset.GetID(),
-				})
-				break // Found a match for this repo, move to the next one
-			}
+// Exit statuses for failures whose cause is known; anything else exits with 1.
+const (
+	exitNoCandidates       = 2
+	exitMultipleCandidates = 3
+	exitRateLimited        = 4
+	exitVerificationFailed = 5
+	exitTokenScope         = 6
+	exitInterrupted        = 130 // as a shell reports death by SIGINT
+)
+
+// exitCode maps an error to the process exit status.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, releases.ErrNoCandidates):
+		return exitNoCandidates
+	case errors.Is(err, releases.ErrMultipleCandidates):
+		return exitMultipleCandidates
+	case errors.Is(err, releases.ErrRateLimited):
+		return exitRateLimited
+	case errors.Is(err, releases.ErrVerificationFailed):
+		return exitVerificationFailed
+	case errors.Is(err, releases.ErrTokenScope):
+		return exitTokenScope
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	}
+	return 1
+}
+
+// fatalf prints an error message to stderr, annotates it when running in GitHub Actions,
+// and exits. The exit status is chosen by exitCode from the first error among args.
+// With -json the failure is also printed as the result.
+func fatalf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	fmt.Fprintln(os.Stderr, msg)
+	actionsAnnotate("error", msg)
+	stopProfiling()
+	stopRecording()
+	code := 1
+	for _, a := range args {
+		if err, ok := a.(error); ok {
+			code = exitCode(err)
+			break
 		}
 	}
+	result.fail(msg, code)
+	os.Exit(code)
+}
 
-	return candidates, nil
+// stringList is a flag that may be given several times, collecting every value.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// flagSet reports whether the named flag was given on the command line.
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// downloadAndPrepare downloads the given asset through stages to dest, makes it executable,
+// and returns the hex-encoded SHA-256 digest of the downloaded bytes. When the release
+// publishes a checksum or signatures for the asset, a download that does not match them
+// never reaches dest. A download cut short is tried again, up to -retries times, resuming
+// where it stopped when the server allows.
+func downloadAndPrepare(ctx context.Context, provider releases.ReleaseProvider, c releases.Candidate, dest string, stages []releases.Stage) (string, error) {
+	return downloadVerified(ctx, provider, c, dest, "", stages)
 }
 
-// downloadAndPrepare downloads the given asset, saves it, and makes it executable.
-func downloadAndPrepare(ctx context.Context, client *github.Client, httpClient *http.Client, c releaseCandidate) error {
-	// 1. Download the asset content using the authenticated client
-	rc, _, err := client.Repositories.DownloadReleaseAsset(ctx, c.RepoOwner, c.RepoName, c.AssetID, httpClient)
+// downloadVerified is downloadAndPrepare for an asset whose SHA-256 digest is pinned to
+// want, as by -sha256, a manifest or a lockfile: a download that does not match it never
+// reaches dest, and an existing file there is left untouched. The checksum the release
+// publishes is not consulted, so a pin holds even when the release's checksums file was
+// changed along with the asset. An empty want pins nothing.
+func downloadVerified(ctx context.Context, provider releases.ReleaseProvider, c releases.Candidate, dest, want string, stages []releases.Stage) (string, error) {
+	// Look up the checksum and signatures the release publishes for the asset, if any
+	published := want
+	if want == "" {
+		var err error
+		if published, err = releaseChecksum(ctx, provider, c); err != nil {
+			return "", err
+		}
+	}
+	sigs, err := fetchSignatures(ctx, provider, c)
 	if err != nil {
-		return fmt.Errorf("could not download asset content: %w", err)
+		return "", err
 	}
-	defer rc.Close()
+	defer sigs.cleanup()
+
+	for n := 0; ; n++ {
+		digest, err := downloadOnce(ctx, provider, c, dest, stages, published, want != "", sigs)
+		if err == nil || n >= retries || !transient(err) {
+			return digest, err
+		}
+		wait := backoff(n)
+		fmt.Fprintf(os.Stderr, "Download of %s failed (%v); retrying in %s\n", c.AssetName, err, wait.Round(time.Millisecond))
+		if err := sleep(ctx, wait); err != nil {
+			return "", err
+		}
+	}
+}
 
-	// 2. Create the output file
-	out, err := os.Create(c.AssetName)
+// downloadOnce makes one attempt at downloadVerified, checking the download against the
+// published checksum, or the pinned one when pinned is set, and signatures, if any.
+func downloadOnce(ctx context.Context, provider releases.ReleaseProvider, c releases.Candidate, dest string, stages []releases.Stage, published string, pinned bool, sigs *signatures) (string, error) {
+	// 1. Use a cached copy if there is one
+	if blob, want, ok := cacheLookup(c); ok && (published == "" || strings.EqualFold(want, published)) {
+		if sigs != nil {
+			if err := sigs.check(ctx, blob); err != nil {
+				return "", err
+			}
+		}
+		f, err := os.Open(blob)
+		if err == nil {
+			defer f.Close()
+			// A pinned install never leaves a bad copy at dest, even from a damaged cache
+			out := dest
+			if pinned {
+				out = dest + ".download"
+			}
+			digest, err := releases.InstallStream(&countingReader{r: f, source: "cache"}, out, c.Size, stages)
+			if err == nil && digest == want && (out == dest || os.Rename(out, dest) == nil) {
+				metrics.add("get_gh_release_downloads_total", 1, "source", "cache")
+				fmt.Println("copied from cache")
+				return digest, nil
+			}
+			if out != dest {
+				os.Remove(out)
+			}
+		}
+	}
+
+	// 2. With -delta, patch the previous release instead of downloading this one. A patched
+	// asset never exists as published, so signatures and pins rule this out.
+	if deltaUpdates && sigs == nil && !pinned {
+		digest, err := installDelta(ctx, provider, c, dest, stages)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Not using a delta update: %v\n", err)
+		} else if digest != "" {
+			metrics.add("get_gh_release_downloads_total", 1, "source", "delta")
+			return digest, nil
+		}
+	}
+
+	// 3. Download the asset from the mirror, falling back to the authenticated client, and
+	// pick up where an interrupted download stopped. The part already downloaded lives in
+	// the cache, which is filled in the same pass as the install.
+	d, err := openAsset(ctx, provider, c, func(format string, args ...any) {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	})
 	if err != nil {
-		return fmt.Errorf("could not create file %s: %w", c.AssetName, err)
+		return "", err
+	}
+	defer d.Close()
+	if d.offset > 0 && d.offset < c.Size {
+		fmt.Printf("resuming download at %s\n", formatSize(d.offset))
 	}
-	defer out.Close()
 
-	// 3. Write the body to the file
-	_, err = io.Copy(out, rc)
+	// 4. Save it as an executable, adding what arrives to the cache. With a checksum or
+	// signatures to match, it is written next to dest and only moved into place once it
+	// does; signatures are checked against a copy of the asset as published.
+	r := d.content(&countingReader{r: withProgress(d, c.AssetName, c.Size, d.offset), source: d.source})
+	out := dest
+	if published != "" || sigs != nil {
+		out = dest + ".download"
+	}
+	var taps []io.Writer
+	var signed *os.File
+	if sigs != nil {
+		if signed, err = os.Create(filepath.Join(sigs.dir, "asset")); err != nil {
+			return "", err
+		}
+		defer signed.Close()
+		taps = append(taps, signed)
+	}
+	digest, err := releases.InstallStream(r, out, c.Size, stages, taps...)
+	switch {
+	case err != nil:
+	case d.want != "" && !strings.EqualFold(d.want, digest):
+		err = fmt.Errorf("%w: mirror copy of %s is corrupt: expected %s, got %s", releases.ErrVerificationFailed, c.AssetName, d.want, digest)
+	case pinned && !strings.EqualFold(published, digest):
+		err = fmt.Errorf("%w: %s does not match its pinned SHA-256 digest: expected %s, got %s", releases.ErrVerificationFailed, c.AssetName, published, digest)
+	case published != "" && !strings.EqualFold(published, digest):
+		err = fmt.Errorf("%w: %s does not match the checksum published with the release: expected %s, got %s", releases.ErrVerificationFailed, c.AssetName, published, digest)
+	case sigs != nil:
+		err = sigs.check(ctx, signed.Name())
+	}
+	if err == nil && out != dest {
+		if err = os.Rename(out, dest); err != nil {
+			err = fmt.Errorf("could not move %s into place: %w", dest, err)
+		}
+	}
 	if err != nil {
-		return fmt.Errorf("could not write to file: %w", err)
+		if out != dest {
+			os.Remove(out)
+		}
+		d.abandon(c, err)
+		metrics.add("get_gh_release_download_failures_total", 1)
+		return "", err
 	}
+	metrics.add("get_gh_release_downloads_total", 1, "source", d.source)
 	fmt.Println("downloaded")
-
-	// 4. Make the file executable (chmod +x)
-	// 0755 is rwxr-xr-x
-	if err := os.Chmod(c.AssetName, 0755); err != nil {
-		return fmt.Errorf("could not make file executable: %w", err)
+	if published != "" {
+		fmt.Println("verified checksum")
 	}
 	fmt.Println("made executable")
 
-	return nil
+	// 5. Keep the copy in the cache for later installs and for serving
+	if d.blob != nil {
+		if err := d.blob.commit(c, digest); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not cache %s: %v\n", c.AssetName, err)
+		}
+	}
+	return digest, nil
+}
+
+// installVerified has write produce the file next to dest and only moves it into place if
+// the digest write returns matches want.
+func installVerified(dest, want string, write func(tmp string) (string, error)) (string, error) {
+	tmp := dest + ".download"
+	digest, err := write(tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := releases.Verify(filepath.Base(dest), digest, want); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("could not move %s into place: %w", dest, err)
+	}
+	return digest, nil
 }
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+	"gopkg.in/yaml.v3"
+)
+
+// manifest declares a set of tools to install.
+type manifest struct {
+	Defaults manifestDefaults `yaml:"defaults,omitempty"`
+	Tools    []manifestTool   `yaml:"tools"`
+
+	base  string // directory relative install paths are resolved against
+	file  string // absolute path of the manifest, or "" when read from standard input
+	exact bool   // versions are exact release tags, as export writes them
+}
+
+// manifestDefaults apply to every tool that does not override them.
+type manifestDefaults struct {
+	Dest   string          `yaml:"dest,omitempty"`
+	Policy string          `yaml:"policy,omitempty"`
+	MinAge string          `yaml:"min_age,omitempty"`
+	Match  []releases.Rule `yaml:"match,omitempty"` // asset rules applied to every tool
+}
+
+// manifestTool describes one tool in a manifest.
+type manifestTool struct {
+	Repo    string          `yaml:"repo"`              // owner/repo
+	Version string          `yaml:"version,omitempty"` // substring of the release tag or semver constraint; empty means latest
+	Pre     bool            `yaml:"pre,omitempty"`     // consider prereleases
+	Asset   string          `yaml:"asset,omitempty"`   // glob over asset names, replacing platform matching
+	Dest    string          `yaml:"dest,omitempty"`    // install directory
+	Name    string          `yaml:"name,omitempty"`    // installed file name; defaults to the asset name
+	Policy  string          `yaml:"policy,omitempty"`  // update policy recorded for the install
+	MinAge  string          `yaml:"min_age,omitempty"` // minimum age of adopted releases, e.g. 72h
+	Block   []string        `yaml:"block,omitempty"`   // known-bad tags that are never installed
+	Hooks   *toolHooks      `yaml:"hooks,omitempty"`   // commands run before and after installing
+	Match   []releases.Rule `yaml:"match,omitempty"`   // asset rules run after the default ones
+	SHA256  string          `yaml:"sha256,omitempty"`  // digest the asset must have, whatever its release publishes
+}
+
+// wantSHA256 returns the digest the tool's asset must have: the one in the manifest or the
+// one locked in pin, which must then agree, or "" if neither gives one.
+func (t manifestTool) wantSHA256(pin *lockedTool) (string, error) {
+	if pin == nil || pin.SHA256 == "" {
+		return t.SHA256, nil
+	}
+	if t.SHA256 != "" && !strings.EqualFold(t.SHA256, pin.SHA256) {
+		return "", fmt.Errorf("manifest gives SHA-256 %s but the lockfile %s", t.SHA256, pin.SHA256)
+	}
+	return strings.ToLower(pin.SHA256), nil
+}
+
+// ownerRepo splits the tool's repo field into owner and name.
+func (t manifestTool) ownerRepo() (string, string) {
+	owner, repo, _ := strings.Cut(t.Repo, "/")
+	return owner, repo
+}
+
+// loadManifest reads and validates a manifest file; "-" reads from standard input. Files
+// ending in .toml are TOML, anything else YAML.
+func loadManifest(file string) (*manifest, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read manifest: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(file), ".toml") {
+		if data, err = tomlToYAML(data); err != nil {
+			return nil, fmt.Errorf("could not parse manifest %s: %w", file, err)
+		}
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var m manifest
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("could not parse manifest %s: %w", file, err)
+	}
+	m.base = "."
+	if file != "-" {
+		m.base = filepath.Dir(file)
+		if m.file, err = filepath.Abs(file); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.Defaults.Policy != "" && !validPolicy(m.Defaults.Policy) {
+		return nil, fmt.Errorf("manifest defaults: unknown policy %q", m.Defaults.Policy)
+	}
+	if err := validMinAge(m.Defaults.MinAge); err != nil {
+		return nil, fmt.Errorf("manifest defaults: %w", err)
+	}
+	for _, r := range m.Defaults.Match {
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("manifest defaults: %w", err)
+		}
+	}
+	for i, t := range m.Tools {
+		owner, repo := t.ownerRepo()
+		if owner == "" || repo == "" || strings.Contains(repo, "/") {
+			return nil, fmt.Errorf("manifest tool %d: repo %q is not of the form owner/repo", i+1, t.Repo)
+		}
+		if t.Policy != "" && !validPolicy(t.Policy) {
+			return nil, fmt.Errorf("manifest tool %s: unknown policy %q", t.Repo, t.Policy)
+		}
+		if err := validMinAge(t.MinAge); err != nil {
+			return nil, fmt.Errorf("manifest tool %s: %w", t.Repo, err)
+		}
+		if releases.IsConstraint(t.Version) {
+			if _, err := releases.ParseConstraint(t.Version); err != nil {
+				return nil, fmt.Errorf("manifest tool %s: %w", t.Repo, err)
+			}
+		}
+		if t.Asset != "" {
+			if _, err := path.Match(t.Asset, ""); err != nil {
+				return nil, fmt.Errorf("manifest tool %s: bad asset pattern %q: %w", t.Repo, t.Asset, err)
+			}
+		}
+		for _, r := range t.Match {
+			if err := r.Validate(); err != nil {
+				return nil, fmt.Errorf("manifest tool %s: %w", t.Repo, err)
+			}
+		}
+		if t.SHA256 != "" {
+			if m.Tools[i].SHA256, err = parseSHA256(t.SHA256); err != nil {
+				return nil, fmt.Errorf("manifest tool %s: %w", t.Repo, err)
+			}
+		}
+	}
+	if err := m.distrust(data); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// tomlToYAML converts a TOML document to YAML, so that both formats share the YAML field
+// names and the same strict decoding.
+func tomlToYAML(data []byte) ([]byte, error) {
+	var doc map[string]any
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
+
+// destDir returns the install directory for t. Relative directories are taken relative to
+// the manifest itself.
+func (m *manifest) destDir(t manifestTool) string {
+	dir := t.Dest
+	if dir == "" {
+		dir = m.Defaults.Dest
+	}
+	dir = expandHome(dir)
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(m.base, dir)
+}
+
+// toolFor returns the entry for owner/repo, or nil.
+func (m *manifest) toolFor(owner, repo string) *manifestTool {
+	for i := range m.Tools {
+		if strings.EqualFold(m.Tools[i].Repo, owner+"/"+repo) {
+			return &m.Tools[i]
+		}
+	}
+	return nil
+}
+
+// policy returns the update policy for t.
+func (m *manifest) policy(t manifestTool) string {
+	if t.Policy != "" {
+		return t.Policy
+	}
+	if m.Defaults.Policy != "" {
+		return m.Defaults.Policy
+	}
+	return policyNotify
+}
+
+// settings returns the settings recorded when t is installed.
+func (m *manifest) settings(t manifestTool) toolSettings {
+	minAge := t.MinAge
+	if minAge == "" {
+		minAge = m.Defaults.MinAge
+	}
+	s := toolSettings{Policy: m.policy(t), Hooks: t.Hooks, MinAge: minAge, Block: t.Block, Pre: t.Pre, Manifest: m.file}
+	s.selectAssets(m.matcher(t, "", ""))
+	return s
+}
+
+// validMinAge checks a min_age setting; empty means none.
+func validMinAge(s string) error {
+	if s == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(s); err != nil || d < 0 {
+		return fmt.Errorf("bad min_age %q", s)
+	}
+	return nil
+}
+
+// matcher returns the asset matcher for t: the default rules followed by the tool's own.
+func (m *manifest) matcher(t manifestTool, platformOS, platformArch string) releases.Matcher {
+	return releases.Matcher{
+		OS:    platformOS,
+		Arch:  platformArch,
+		Libc:  platformLibc,
+		Glob:  t.Asset,
+		Rules: append(slices.Clone(m.Defaults.Match), t.Match...),
+	}
+}
+
+// repoRules returns the per-tool asset rules keyed by lower-case owner/repo.
+func (m *manifest) repoRules() map[string][]releases.Rule {
+	rules := map[string][]releases.Rule{}
+	for _, t := range m.Tools {
+		if len(t.Match) > 0 {
+			rules[strings.ToLower(t.Repo)] = t.Match
+		}
+	}
+	return rules
+}
+
+// blocked returns the blocked tags of each tool that has any, keyed by lower-case
+// owner/repo.
+func (m *manifest) blocked() map[string][]string {
+	blocked := map[string][]string{}
+	for _, t := range m.Tools {
+		if len(t.Block) > 0 {
+			blocked[strings.ToLower(t.Repo)] = t.Block
+		}
+	}
+	return blocked
+}
+
+// expandHome replaces a leading ~ with the user's home directory.
+func expandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") && !(runtime.GOOS == "windows" && strings.HasPrefix(p, `~\`)) {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, strings.TrimPrefix(p, "~"))
+}
+
+// runApply installs every tool listed in a manifest and records the resolved versions in
+// its lockfile, or with -locked installs exactly what the lockfile records. -frozen also
+// refuses to start when the lockfile no longer matches the manifest.
+func runApply(ctx context.Context, provider releases.ReleaseProvider, platformOS, platformArch string, args []string) error {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	locked := fs.Bool("locked", false, "Install strictly the tags, assets and digests recorded in the lockfile.")
+	frozen := fs.Bool("frozen", false, "Like -locked, but fail before installing anything if the lockfile does not match the manifest.")
+	lockFlag := fs.String("lockfile", "", "Lockfile to read and write instead of the one next to the manifest.")
+	maxFailures := fs.Int("max-failures", 0, "Number of tools that may fail to install before the command fails.")
+	jobs := fs.Int("jobs", 4, "Number of assets downloaded at once before installing them in order; 1 downloads each as it is installed.")
+	fs.Parse(args)
+	file, err := manifestArg(fs)
+	if err != nil {
+		return fmt.Errorf("usage: get_gh_release apply [-locked|-frozen] [-lockfile file] [-max-failures n] [-jobs n] [manifest.yaml]: %w", err)
+	}
+
+	m, err := loadManifest(file)
+	if err != nil {
+		return err
+	}
+	lockFile := lockPath(file)
+	if *lockFlag != "" {
+		lockFile = *lockFlag
+	}
+
+	lock, err := manifestLock(m, lockFile, *locked, *frozen)
+	if err != nil {
+		return err
+	}
+
+	// Resolve every tool, download the assets together, then install them in order
+	steps := make([]manifestStep, len(m.Tools))
+	for i, t := range m.Tools {
+		s := &steps[i]
+		s.tool = t
+		if lock != nil {
+			if s.pin = lock.find(t.Repo); s.pin == nil {
+				s.err = fmt.Errorf("not present in lockfile %s", lockFile)
+				continue
+			}
+		}
+		s.c, s.dest, s.err = resolveManifestTool(ctx, provider, m, t, s.pin, platformOS, platformArch)
+	}
+	prefetch(ctx, provider, steps, *jobs)
+
+	var resolved lockfile
+	b := batch{maxFailures: *maxFailures}
+	for _, s := range steps {
+		if s.err != nil {
+			b.add(s.tool.Repo, s.c.Tag, s.err)
+			continue
+		}
+		fmt.Printf("%s/%s: %s %s -> %s\n", s.c.RepoOwner, s.c.RepoName, s.c.Tag, s.c.AssetName, s.dest)
+		lt, err := installResolved(ctx, provider, m, s.tool, s.pin, s.c, s.dest)
+		b.add(s.tool.Repo, s.c.Tag, err)
+		if err == nil {
+			resolved.Tools = append(resolved.Tools, lt)
+		}
+	}
+	b.printTable(os.Stdout)
+
+	if lock != nil || lockFile == "" {
+		return b.err()
+	}
+	if b.failed() > 0 {
+		// A lockfile missing the failed tools would drop their pins
+		fmt.Printf("%s not updated\n", lockFile)
+		return b.err()
+	}
+	if err := resolved.save(lockFile); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s\n", lockFile)
+	return nil
+}
+
+// resolveManifestTool picks the release asset for a manifest entry and the path it installs
+// to. When pin is non-nil the release and asset it records are used instead of resolving.
+func resolveManifestTool(ctx context.Context, provider releases.ReleaseProvider, m *manifest, t manifestTool, pin *lockedTool, platformOS, platformArch string) (releases.Candidate, string, error) {
+	owner, repo := t.ownerRepo()
+
+	var release *releases.Release
+	var asset *releases.Asset
+	var err error
+	if pin != nil {
+		release, err = provider.ReleaseByTag(ctx, owner, repo, pin.Tag)
+		if err != nil {
+			return releases.Candidate{}, "", fmt.Errorf("could not fetch locked release %s: %w", pin.Tag, err)
+		}
+		for i := range release.Assets {
+			if release.Assets[i].Name == pin.Asset {
+				asset = &release.Assets[i]
+				break
+			}
+		}
+		if asset == nil {
+			return releases.Candidate{}, "", fmt.Errorf("%w: release %s no longer has locked asset %s", releases.ErrNoCandidates, pin.Tag, pin.Asset)
+		}
+		if pin.AssetID != 0 && asset.ID != 0 && asset.ID != pin.AssetID {
+			return releases.Candidate{}, "", fmt.Errorf("%w: locked asset %s of %s was replaced since it was locked", releases.ErrVerificationFailed, pin.Asset, pin.Tag)
+		}
+		if reason := m.settings(t).filter().Reject(release); reason != "" {
+			return releases.Candidate{}, "", fmt.Errorf("locked release %s: %s", pin.Tag, reason)
+		}
+	} else {
+		filter := m.settings(t).filter()
+		var skipped []releases.Skip
+		if m.exact && t.Version != "" {
+			release, err = provider.ReleaseByTag(ctx, owner, repo, t.Version)
+			if err == nil {
+				if reason := filter.Reject(release); reason != "" {
+					skipped, release = []releases.Skip{{Release: release, Reason: reason}}, nil
+				}
+			}
+		} else {
+			release, skipped, err = filter.Resolve(ctx, provider, owner, repo, t.Version)
+		}
+		if err != nil {
+			return releases.Candidate{}, "", fmt.Errorf("could not resolve release: %w", err)
+		}
+		for _, s := range skipped {
+			fmt.Printf("%s: skipping %s: %s\n", t.Repo, s.Release.Tag, s.Reason)
+		}
+		if release == nil {
+			return releases.Candidate{}, "", fmt.Errorf("%w: no acceptable release matching %q", releases.ErrNoCandidates, t.Version)
+		}
+		asset = m.matcher(t, platformOS, platformArch).Match(release)
+		if asset == nil {
+			return releases.Candidate{}, "", fmt.Errorf("%w: release %s has no matching asset", releases.ErrNoCandidates, release.Tag)
+		}
+	}
+	c := releases.NewCandidate(owner, repo, release, *asset)
+	dest, err := m.installPath(t, c)
+	if err != nil {
+		return releases.Candidate{}, "", err
+	}
+	return c, dest, nil
+}
+
+// installPath returns the absolute path the candidate of t installs to.
+func (m *manifest) installPath(t manifestTool, c releases.Candidate) (string, error) {
+	name := t.Name
+	if name == "" {
+		name = installName(c)
+	}
+	return filepath.Abs(filepath.Join(m.destDir(t), name))
+}
+
+// installResolved downloads an already resolved manifest entry to dest and records it.
+func installResolved(ctx context.Context, provider releases.ReleaseProvider, m *manifest, t manifestTool, pin *lockedTool, c releases.Candidate, dest string) (lockedTool, error) {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return lockedTool{}, fmt.Errorf("could not create %s: %w", filepath.Dir(dest), err)
+	}
+	want, err := t.wantSHA256(pin)
+	if err != nil {
+		return lockedTool{}, err
+	}
+	var digest string
+	err = withHooks(ctx, t.Hooks, c, dest, previousTag(dest), func() error {
+		var err error
+		if digest, err = downloadVerified(ctx, provider, c, dest, want, installStages(c)); err != nil {
+			return err
+		}
+		return recordInstall(c, dest, digest, m.settings(t))
+	})
+	if err != nil {
+		return lockedTool{}, err
+	}
+	return lockedCandidate(t.Repo, c, digest), nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// rawMarkdown prints release notes as published instead of rendering them. It is set from
+// the -raw flag.
+var rawMarkdown bool
+
+// ANSI styles used when rendering to a terminal.
+const (
+	styleBold      = "\x1b[1m"
+	styleDim       = "\x1b[2m"
+	styleUnderline = "\x1b[4m"
+	styleCode      = "\x1b[36m"
+	styleReset     = "\x1b[0m"
+)
+
+var (
+	mdComment = regexp.MustCompile(`(?s)<!--.*?-->`)
+	mdImage   = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)[^)]*\)`)
+	mdLink    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)[^)]*\)`)
+	mdCode    = regexp.MustCompile("`([^`]+)`")
+	mdBold    = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	mdBullet  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdRule    = regexp.MustCompile(`^(?:-\s*){3,}$|^(?:\*\s*){3,}$|^(?:_\s*){3,}$`)
+	mdAutoURL = regexp.MustCompile(`<(https?://[^>\s]+)>`)
+)
+
+// isTerminal reports whether f is a terminal that accepts colour, honouring NO_COLOR and
+// -ci.
+func isTerminal(f *os.File) bool {
+	if ciMode || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// printNotes writes release notes to f, rendered for reading unless -raw was given. Styles
+// are only used when f is a terminal.
+func printNotes(f *os.File, body string) {
+	if rawMarkdown {
+		fmt.Fprintln(f, strings.TrimRight(body, "\n"))
+		return
+	}
+	renderMarkdown(f, body, isTerminal(f))
+}
+
+// showNotes makes downloads and upgrades print the notes of the release they are about to
+// install. It is set from -notes or show_notes in the configuration.
+var showNotes bool
+
+// printReleaseNotes writes the tag, publication date and notes of release to f, as shown
+// before installing it.
+func printReleaseNotes(f *os.File, owner, repo string, release *releases.Release) {
+	fmt.Fprintf(f, "%s/%s %s", owner, repo, release.Tag)
+	if !release.PublishedAt.IsZero() {
+		fmt.Fprintf(f, ", published %s", release.PublishedAt.Local().Format("2006-01-02"))
+	}
+	fmt.Fprintln(f)
+	if strings.TrimSpace(release.Body) == "" {
+		fmt.Fprintln(f, "(no release notes)")
+	} else {
+		printNotes(f, release.Body)
+	}
+	fmt.Fprintln(f)
+}
+
+// renderMarkdown writes the common parts of GitHub-flavoured markdown as plain text:
+// headings, lists, quotes, code blocks, emphasis and links. With styled set, ANSI escapes
+// mark headings, emphasis, code and link text.
+func renderMarkdown(w io.Writer, body string, styled bool) {
+	style := func(s, code string) string {
+		if !styled {
+			return s
+		}
+		return code + s + styleReset
+	}
+	inline := func(s string) string {
+		s = mdImage.ReplaceAllString(s, "[image: $1]")
+		s = mdAutoURL.ReplaceAllString(s, "$1")
+		// Links go first: the escapes inserted for styles contain brackets
+		s = mdLink.ReplaceAllStringFunc(s, func(m string) string {
+			sub := mdLink.FindStringSubmatch(m)
+			if sub[1] == sub[2] {
+				return style(sub[2], styleUnderline)
+			}
+			return style(sub[1], styleUnderline) + " (" + sub[2] + ")"
+		})
+		s = mdCode.ReplaceAllStringFunc(s, func(m string) string {
+			return style(mdCode.FindStringSubmatch(m)[1], styleCode)
+		})
+		return mdBold.ReplaceAllStringFunc(s, func(m string) string {
+			sub := mdBold.FindStringSubmatch(m)
+			return style(sub[1]+sub[2], styleBold)
+		})
+	}
+
+	body = mdComment.ReplaceAllString(strings.ReplaceAll(body, "\r\n", "\n"), "")
+	body = strings.TrimSpace(body)
+	inFence := false
+	blank := true
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			fmt.Fprintln(w, "    "+style(line, styleCode))
+			blank = false
+			continue
+		}
+		if trimmed == "" {
+			// Collapse runs of blank lines
+			if !blank {
+				fmt.Fprintln(w)
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		switch {
+		case mdHeading.MatchString(trimmed):
+			sub := mdHeading.FindStringSubmatch(trimmed)
+			code := styleBold
+			if len(sub[1]) <= 2 {
+				code += styleUnderline
+			}
+			fmt.Fprintln(w, style(inline(sub[2]), code))
+		case mdRule.MatchString(trimmed):
+			fmt.Fprintln(w, style(strings.Repeat("─", 40), styleDim))
+		case mdBullet.MatchString(line):
+			sub := mdBullet.FindStringSubmatch(line)
+			fmt.Fprintln(w, sub[1]+"  • "+inline(sub[2]))
+		case strings.HasPrefix(trimmed, ">"):
+			fmt.Fprintln(w, style("│ ", styleDim)+inline(strings.TrimSpace(strings.TrimLeft(trimmed, ">"))))
+		default:
+			fmt.Fprintln(w, inline(trimmed))
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricInfo describes one exported metric family.
+type metricInfo struct {
+	name, kind, help string
+}
+
+// metricFamilies lists every metric, in exposition order.
+var metricFamilies = []metricInfo{
+	{"get_gh_release_downloads_total", "counter", "Assets fetched, by source (github, mirror, cache)."},
+	{"get_gh_release_download_bytes_total", "counter", "Bytes of assets fetched, by source."},
+	{"get_gh_release_download_failures_total", "counter", "Asset fetches that failed."},
+	{"get_gh_release_update_checks_total", "counter", "Per-tool update checks by the daemon, by result."},
+	{"get_gh_release_last_update_pass_timestamp_seconds", "gauge", "Unix time the daemon last completed an update pass."},
+	{"get_gh_release_webhook_events_total", "counter", "Webhook deliveries, by result."},
+	{"get_gh_release_served_requests_total", "counter", "Cache requests served, by status code."},
+	{"get_gh_release_served_bytes_total", "counter", "Bytes served from the cache."},
+	{"get_gh_release_api_requests_total", "counter", "Requests sent to the release API."},
+	{"get_gh_release_api_cache_hits_total", "counter", "API requests answered from the response cache after a 304."},
+	{"get_gh_release_repos_scanned_total", "counter", "Repositories whose releases were looked up."},
+	{"get_gh_release_rate_limit_remaining", "gauge", "GitHub API requests remaining in the current rate limit window."},
+	{"get_gh_release_rate_limit_reset_timestamp_seconds", "gauge", "Unix time the GitHub rate limit window resets."},
+}
+
+// metricRegistry holds the current value of every labelled series.
+type metricRegistry struct {
+	mu     sync.Mutex
+	values map[string]map[string]float64 // family -> rendered labels -> value
+}
+
+// metrics is the process-wide registry exposed on /metrics.
+var metrics = &metricRegistry{values: map[string]map[string]float64{}}
+
+// labelString renders key/value pairs as {k="v",...}.
+func labelString(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		parts = append(parts, labels[i]+"="+strconv.Quote(labels[i+1]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// add increases a counter series by v. labels are key/value pairs.
+func (r *metricRegistry) add(name string, v float64, labels ...string) {
+	r.update(name, labels, func(old float64) float64 { return old + v })
+}
+
+// set replaces the value of a gauge series. labels are key/value pairs.
+func (r *metricRegistry) set(name string, v float64, labels ...string) {
+	r.update(name, labels, func(float64) float64 { return v })
+}
+
+// get returns the value of one series. labels are key/value pairs.
+func (r *metricRegistry) get(name string, labels ...string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.values[name][labelString(labels)]
+}
+
+// lookup is get that also reports whether the series has been set.
+func (r *metricRegistry) lookup(name string, labels ...string) (float64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.values[name][labelString(labels)]
+	return v, ok
+}
+
+// total returns the sum of every series of a family.
+func (r *metricRegistry) total(name string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sum float64
+	for _, v := range r.values[name] {
+		sum += v
+	}
+	return sum
+}
+
+func (r *metricRegistry) update(name string, labels []string, f func(float64) float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	series, ok := r.values[name]
+	if !ok {
+		series = map[string]float64{}
+		r.values[name] = series
+	}
+	key := labelString(labels)
+	series[key] = f(series[key])
+}
+
+// write renders every series in the Prometheus text exposition format.
+func (r *metricRegistry) write(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range metricFamilies {
+		series := r.values[m.name]
+		if len(series) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		keys := make([]string, 0, len(series))
+		for k := range series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, "%s%s %s\n", m.name, k, strconv.FormatFloat(series[k], 'g', -1, 64))
+		}
+	}
+}
+
+// ServeHTTP exposes the registry.
+func (r *metricRegistry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.write(w)
+}
+
+// verbose makes a run report more detail; it is set from -verbose.
+var verbose bool
+
+// printQuota reports the GitHub API quota left as of the last response.
+func printQuota(w io.Writer) {
+	remaining, ok := metrics.lookup("get_gh_release_rate_limit_remaining")
+	if !ok {
+		return
+	}
+	fmt.Fprintf(w, "GitHub API quota: %.0f requests left", remaining)
+	if reset, ok := metrics.lookup("get_gh_release_rate_limit_reset_timestamp_seconds"); ok {
+		fmt.Fprintf(w, ", resets at %s", time.Unix(int64(reset), 0).Format("15:04:05"))
+	}
+	fmt.Fprintln(w)
+}
+
+// rateLimitTransport counts API requests and records the GitHub rate limit headers of every
+// response. When low is set it warns, once, as soon as no more than low requests remain.
+type rateLimitTransport struct {
+	base http.RoundTripper
+	low  int
+
+	warn sync.Once
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	metrics.add("get_gh_release_api_requests_total", 1)
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	remaining, err := strconv.ParseFloat(resp.Header.Get("X-RateLimit-Remaining"), 64)
+	if err == nil {
+		metrics.set("get_gh_release_rate_limit_remaining", remaining)
+	}
+	reset, rerr := strconv.ParseFloat(resp.Header.Get("X-RateLimit-Reset"), 64)
+	if rerr == nil {
+		metrics.set("get_gh_release_rate_limit_reset_timestamp_seconds", reset)
+	}
+	if err == nil && rerr == nil && t.low > 0 && remaining <= float64(t.low) {
+		t.warn.Do(func() {
+			fmt.Fprintf(os.Stderr, "Warning: only %.0f GitHub API requests left until %s; a token raises the limit\n",
+				remaining, time.Unix(int64(reset), 0).Format(time.Kitchen))
+		})
+	}
+	return resp, nil
+}
+
+// countingReader counts the bytes read through it into a download metric.
+type countingReader struct {
+	r      io.Reader
+	source string
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	metrics.add("get_gh_release_download_bytes_total", float64(n), "source", c.source)
+	return n, err
+}
+
+// serveMetrics starts a /metrics endpoint on addr in the background. An empty addr disables it.
+func serveMetrics(addr string) {
+	if addr == "" {
+		return
+	}
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", metrics)
+		srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		log.Printf("metrics endpoint stopped: %v", srv.ListenAndServe())
+	}()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// mirrorMeta is uploaded next to every mirrored asset.
+type mirrorMeta struct {
+	Repo       string    `json:"repo"`
+	Tag        string    `json:"tag"`
+	Asset      string    `json:"asset"`
+	SHA256     string    `json:"sha256"`
+	Size       int64     `json:"size"`
+	SourceURL  string    `json:"source_url"`
+	MirroredAt time.Time `json:"mirrored_at"`
+}
+
+// runMirror uploads the assets a manifest resolves to into an object-storage bucket, using the
+// same releases/<owner>/<repo>/<tag>/<asset> layout as serve, so the bucket can also be used
+// as a -mirror.
+func runMirror(ctx context.Context, provider releases.ReleaseProvider, platformOS, platformArch string, args []string) error {
+	fs := flag.NewFlagSet("mirror", flag.ExitOnError)
+	to := fs.String("to", "", "Destination bucket: s3://bucket/prefix, gs://bucket/prefix, azblob://account/container/prefix or file:///dir.")
+	locked := fs.Bool("locked", false, "Mirror the tags, assets and digests recorded in the lockfile.")
+	fs.Parse(args)
+	file, err := manifestArg(fs)
+	if err != nil || *to == "" {
+		return fmt.Errorf("usage: get_gh_release mirror -to <url> [-locked] [manifest.yaml]")
+	}
+
+	store, err := openStore(*to)
+	if err != nil {
+		return err
+	}
+	m, err := loadManifest(file)
+	if err != nil {
+		return err
+	}
+	var lock *lockfile
+	if *locked {
+		if lock, err = loadLockfile(lockPath(file)); err != nil {
+			return err
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "get_gh_release-mirror-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for i, t := range m.Tools {
+		var pin *lockedTool
+		if lock != nil {
+			if pin = lock.find(t.Repo); pin == nil {
+				return fmt.Errorf("%s: not present in lockfile", t.Repo)
+			}
+		}
+		c, _, err := resolveManifestTool(ctx, provider, m, t, pin, platformOS, platformArch)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Repo, err)
+		}
+		tmp := filepath.Join(tmpDir, fmt.Sprintf("%d", i))
+		want, err := t.wantSHA256(pin)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Repo, err)
+		}
+		digest, err := downloadVerified(ctx, provider, c, tmp, want, nil)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Repo, err)
+		}
+		if err := mirrorAsset(ctx, store, c, tmp, digest); err != nil {
+			return fmt.Errorf("%s: %w", t.Repo, err)
+		}
+		fmt.Printf("%s: mirrored %s %s\n", t.Repo, c.Tag, c.AssetName)
+	}
+	return nil
+}
+
+// mirrorAsset uploads the asset at local plus a .sha256 checksum file and a .json metadata file.
+func mirrorAsset(ctx context.Context, store blobStore, c releases.Candidate, local, digest string) error {
+	key := path.Join("releases", releaseKey(c))
+
+	f, err := os.Open(local)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := store.put(ctx, key, f, info.Size(), digest, "application/octet-stream"); err != nil {
+		return err
+	}
+
+	sums := []byte(fmt.Sprintf("%s  %s\n", digest, c.AssetName))
+	if err := putBytes(ctx, store, key+".sha256", sums, "text/plain"); err != nil {
+		return err
+	}
+	meta, err := json.MarshalIndent(mirrorMeta{
+		Repo:       c.RepoOwner + "/" + c.RepoName,
+		Tag:        c.Tag,
+		Asset:      c.AssetName,
+		SHA256:     digest,
+		Size:       info.Size(),
+		SourceURL:  c.DownloadURL,
+		MirroredAt: time.Now().UTC(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return putBytes(ctx, store, key+".json", meta, "application/json")
+}
+
+// putBytes uploads an in-memory object.
+func putBytes(ctx context.Context, store blobStore, key string, data []byte, contentType string) error {
+	sum := sha256.Sum256(data)
+	return store.put(ctx, key, bytes.NewReader(data), int64(len(data)), hex.EncodeToString(sum[:]), contentType)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// releaseNotice announces a new release of an installed tool.
+type releaseNotice struct {
+	Tool      string `json:"tool"`
+	Repo      string `json:"repo"`
+	Current   string `json:"current"`
+	Tag       string `json:"tag"`
+	URL       string `json:"url"`
+	Summary   string `json:"summary"`
+	Installed bool   `json:"installed"`
+}
+
+// title is the one-line headline of the notice.
+func (n releaseNotice) title() string {
+	if n.Installed {
+		return fmt.Sprintf("%s updated to %s", n.Tool, n.Tag)
+	}
+	return fmt.Sprintf("%s %s is available (installed: %s)", n.Tool, n.Tag, n.Current)
+}
+
+// notifier delivers release notices to the desktop and/or a webhook.
+type notifier struct {
+	desktop    bool
+	webhookURL string
+}
+
+// enabled reports whether any notification channel is configured.
+func (n *notifier) enabled() bool {
+	return n.desktop || n.webhookURL != ""
+}
+
+// notify sends the notice on every configured channel, returning the first error.
+func (n *notifier) notify(ctx context.Context, notice releaseNotice) error {
+	var firstErr error
+	if n.desktop {
+		cmd := exec.CommandContext(ctx, "notify-send", "--app-name=get_gh_release", notice.title(), notice.Summary)
+		if err := cmd.Run(); err != nil {
+			firstErr = fmt.Errorf("desktop notification failed: %w", err)
+		}
+	}
+	if n.webhookURL != "" {
+		if err := n.post(ctx, notice); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// post sends the notice to the webhook URL: Slack incoming webhooks get a message with a text
+// field, any other URL the notice as JSON.
+func (n *notifier) post(ctx context.Context, notice releaseNotice) error {
+	var payload any = notice
+	if u, err := url.Parse(n.webhookURL); err == nil && u.Host == "hooks.slack.com" {
+		text := fmt.Sprintf("*%s* (<%s|%s>)", notice.title(), notice.URL, notice.Repo)
+		if notice.Summary != "" {
+			text += "\n" + notice.Summary
+		}
+		payload = map[string]string{"text": text}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook notification failed: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook notification failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// summarizeNotes reduces a markdown release body to a short plain-text summary.
+func summarizeNotes(body string, limit int) string {
+	var lines []string
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#*->`"))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	s := strings.Join(lines, " · ")
+	if len(s) > limit {
+		s = strings.TrimSpace(s[:limit]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// blobStore is an object-storage bucket that mirrored assets are uploaded to.
+type blobStore interface {
+	// put uploads size bytes from body under key. digest is the hex SHA-256 of the body.
+	put(ctx context.Context, key string, body io.ReadSeeker, size int64, digest, contentType string) error
+}
+
+// openStore returns the store addressed by rawURL:
+//
+//	s3://bucket/prefix         AWS S3 (or an S3-compatible endpoint via AWS_ENDPOINT_URL_S3)
+//	gs://bucket/prefix         Google Cloud Storage through its S3-compatible API and HMAC keys
+//	azblob://account/container/prefix   Azure Blob Storage with AZURE_STORAGE_SAS_TOKEN
+//	file:///dir                a local or mounted directory
+func openStore(rawURL string) (blobStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("bad store URL %q: %w", rawURL, err)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	switch u.Scheme {
+	case "s3":
+		region := firstEnv("AWS_REGION", "AWS_DEFAULT_REGION")
+		if region == "" {
+			region = "us-east-1"
+		}
+		endpoint := firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL")
+		return &s3Store{
+			bucket:       u.Host,
+			prefix:       prefix,
+			region:       region,
+			endpoint:     endpoint,
+			accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	case "gs":
+		return &s3Store{
+			bucket:    u.Host,
+			prefix:    prefix,
+			region:    "auto",
+			endpoint:  "https://storage.googleapis.com",
+			accessKey: os.Getenv("GCS_HMAC_ACCESS_KEY_ID"),
+			secretKey: os.Getenv("GCS_HMAC_SECRET"),
+		}, nil
+	case "azblob":
+		container, rest, _ := strings.Cut(prefix, "/")
+		if container == "" {
+			return nil, fmt.Errorf("azblob URL must be azblob://account/container[/prefix]")
+		}
+		sas := strings.TrimPrefix(os.Getenv("AZURE_STORAGE_SAS_TOKEN"), "?")
+		if sas == "" {
+			return nil, fmt.Errorf("AZURE_STORAGE_SAS_TOKEN is not set")
+		}
+		return &azureStore{account: u.Host, container: container, prefix: rest, sas: sas}, nil
+	case "file":
+		return &dirStore{dir: filepath.FromSlash(u.Path)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported store scheme %q (want s3, gs, azblob or file)", u.Scheme)
+	}
+}
+
+// firstEnv returns the first non-empty environment variable of names.
+func firstEnv(names ...string) string {
+	for _, n := range names {
+		if v := os.Getenv(n); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// uploadRequest sends a PUT and turns a non-2xx response into an error.
+func uploadRequest(req *http.Request) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("PUT %s: %s: %s", req.URL.Redacted(), resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// dirStore writes objects as files below dir.
+type dirStore struct {
+	dir string
+}
+
+func (d *dirStore) put(ctx context.Context, key string, body io.ReadSeeker, size int64, digest, contentType string) error {
+	dst := filepath.Join(d.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	tmp := dst + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// azureStore uploads block blobs using a shared access signature.
+type azureStore struct {
+	account, container, prefix, sas string
+}
+
+func (a *azureStore) put(ctx context.Context, key string, body io.ReadSeeker, size int64, digest, contentType string) error {
+	u := fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s?%s", a.account, a.container, uriEncodePath(path.Join(a.prefix, key)), a.sas)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("x-ms-version", "2020-10-02")
+	req.Header.Set("x-ms-meta-sha256", digest)
+	return uploadRequest(req)
+}
+
+// s3Store uploads objects with AWS Signature Version 4.
+type s3Store struct {
+	bucket, prefix, region, endpoint   string
+	accessKey, secretKey, sessionToken string
+}
+
+func (s *s3Store) put(ctx context.Context, key string, body io.ReadSeeker, size int64, digest, contentType string) error {
+	if s.accessKey == "" || s.secretKey == "" {
+		return fmt.Errorf("no credentials for bucket %s", s.bucket)
+	}
+	objectPath := "/" + uriEncodePath(path.Join(s.prefix, key))
+	var host string
+	if s.endpoint == "" {
+		host = fmt.Sprintf("%s.s3.%s.amazonaws.com", s.bucket, s.region)
+	} else {
+		e, err := url.Parse(s.endpoint)
+		if err != nil {
+			return fmt.Errorf("bad endpoint %q: %w", s.endpoint, err)
+		}
+		host = e.Host
+		objectPath = "/" + s.bucket + objectPath
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, "https://"+host+objectPath, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("x-amz-meta-sha256", digest)
+	s.sign(req, objectPath, digest, time.Now().UTC())
+	return uploadRequest(req)
+}
+
+// sign adds a SigV4 Authorization header for a request whose payload hashes to payloadHash.
+func (s *s3Store) sign(req *http.Request, canonicalPath, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("x-amz-security-token", s.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, "x-amz-") || lk == "content-type" {
+			headers[lk] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var canonicalQuery []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			canonicalQuery = append(canonicalQuery, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method, canonicalPath, strings.Join(canonicalQuery, "&"), canonicalHeaders.String(), signedHeaders, payloadHash,
+	}, "\n")
+	scope := day + "/" + s.region + "/s3/aws4_request"
+	crHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(crHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), day)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 returns HMAC-SHA256(key, data).
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// uriEncodePath percent-encodes everything but unreserved characters and slashes, as SigV4
+// and Azure expect for object keys.
+func uriEncodePath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
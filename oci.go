@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// Media types of the artifacts pushed to OCI registries. The layout follows ORAS conventions,
+// so `oras pull` restores the asset under its original file name.
+const (
+	ociManifestType = "application/vnd.oci.image.manifest.v1+json"
+	ociEmptyType    = "application/vnd.oci.empty.v1+json"
+	ociArtifactType = "application/vnd.get-gh-release.asset.v1"
+	ociLayerType    = "application/octet-stream"
+	ociEmptyDigest  = "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+	ociTitleKey     = "org.opencontainers.image.title"
+)
+
+var (
+	// ociInvalidTag matches runs of characters not allowed in an OCI tag.
+	ociInvalidTag = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+	// challengeParam matches one key="value" pair of a WWW-Authenticate header.
+	challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+)
+
+// ociDescriptor references a blob in a registry.
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ociManifest is an OCI image manifest carrying a single artifact layer.
+type ociManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType"`
+	Config        ociDescriptor     `json:"config"`
+	Layers        []ociDescriptor   `json:"layers"`
+	Annotations   map[string]string `json:"annotations"`
+}
+
+// runPush re-publishes the assets a manifest resolves to as OCI artifacts. Each tool is pushed
+// to <registry>/<namespace>/<repo>:<tag>, with the asset as the only layer so its digest is
+// preserved, and the release provenance recorded as annotations.
+func runPush(ctx context.Context, provider releases.ReleaseProvider, platformOS, platformArch string, args []string) error {
+	fs := flag.NewFlagSet("push", flag.ExitOnError)
+	to := fs.String("to", "", "Registry and namespace to push to, e.g. harbor.example.com/tools.")
+	locked := fs.Bool("locked", false, "Push the tags, assets and digests recorded in the lockfile.")
+	plainHTTP := fs.Bool("plain-http", false, "Talk to the registry over plain HTTP.")
+	fs.Parse(args)
+	file, err := manifestArg(fs)
+	if err != nil || *to == "" {
+		return fmt.Errorf("usage: get_gh_release push -to <registry>/<namespace> [-locked] [manifest.yaml]")
+	}
+
+	host, namespace, _ := strings.Cut(strings.TrimPrefix(*to, "oci://"), "/")
+	reg := newOCIRegistry(host, *plainHTTP)
+
+	m, err := loadManifest(file)
+	if err != nil {
+		return err
+	}
+	var lock *lockfile
+	if *locked {
+		if lock, err = loadLockfile(lockPath(file)); err != nil {
+			return err
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "get_gh_release-push-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for i, t := range m.Tools {
+		var pin *lockedTool
+		if lock != nil {
+			if pin = lock.find(t.Repo); pin == nil {
+				return fmt.Errorf("%s: not present in lockfile", t.Repo)
+			}
+		}
+		c, _, err := resolveManifestTool(ctx, provider, m, t, pin, platformOS, platformArch)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Repo, err)
+		}
+		tmp := filepath.Join(tmpDir, fmt.Sprintf("%d", i))
+		want, err := t.wantSHA256(pin)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Repo, err)
+		}
+		digest, err := downloadVerified(ctx, provider, c, tmp, want, nil)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Repo, err)
+		}
+
+		name := strings.ToLower(strings.Trim(namespace+"/"+c.RepoName, "/"))
+		tag := ociInvalidTag.ReplaceAllString(c.Tag, "-")
+		manifestDigest, err := reg.pushAsset(ctx, name, tag, c, tmp, digest)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Repo, err)
+		}
+		fmt.Printf("%s: pushed %s/%s:%s@%s\n", t.Repo, host, name, tag, manifestDigest)
+	}
+	return nil
+}
+
+// ociRegistry is a minimal client for the OCI distribution API.
+type ociRegistry struct {
+	base               string
+	username, password string
+	token              string
+}
+
+// newOCIRegistry returns a client for host using credentials from OCI_USERNAME/OCI_PASSWORD or
+// the Docker config file.
+func newOCIRegistry(host string, plainHTTP bool) *ociRegistry {
+	scheme := "https"
+	if plainHTTP {
+		scheme = "http"
+	}
+	r := &ociRegistry{base: scheme + "://" + host}
+	r.username, r.password = os.Getenv("OCI_USERNAME"), os.Getenv("OCI_PASSWORD")
+	if r.username == "" {
+		r.username, r.password = dockerCredentials(host)
+	}
+	return r
+}
+
+// dockerCredentials reads the credentials for host from ~/.docker/config.json, if any.
+func dockerCredentials(host string) (string, string) {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		dir = expandHome("~/.docker")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return "", ""
+	}
+	var cfg struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if json.Unmarshal(data, &cfg) != nil {
+		return "", ""
+	}
+	for _, key := range []string{host, "https://" + host} {
+		if a, ok := cfg.Auths[key]; ok {
+			raw, err := base64.StdEncoding.DecodeString(a.Auth)
+			if err != nil {
+				continue
+			}
+			user, pass, _ := strings.Cut(string(raw), ":")
+			return user, pass
+		}
+	}
+	return "", ""
+}
+
+// pushAsset uploads the asset and an empty config blob, then the manifest tying them together,
+// and returns the manifest digest.
+func (r *ociRegistry) pushAsset(ctx context.Context, name, tag string, c releases.Candidate, local, digest string) (string, error) {
+	info, err := os.Stat(local)
+	if err != nil {
+		return "", err
+	}
+	layer := ociDescriptor{
+		MediaType:   ociLayerType,
+		Digest:      "sha256:" + digest,
+		Size:        info.Size(),
+		Annotations: map[string]string{ociTitleKey: c.AssetName},
+	}
+	if err := r.pushBlob(ctx, name, layer.Digest, info.Size(), func() (io.Reader, error) { return os.Open(local) }); err != nil {
+		return "", fmt.Errorf("could not push asset: %w", err)
+	}
+	empty := []byte("{}")
+	if err := r.pushBlob(ctx, name, ociEmptyDigest, 2, func() (io.Reader, error) { return bytes.NewReader(empty), nil }); err != nil {
+		return "", fmt.Errorf("could not push config: %w", err)
+	}
+
+	m := ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestType,
+		ArtifactType:  ociArtifactType,
+		Config:        ociDescriptor{MediaType: ociEmptyType, Digest: ociEmptyDigest, Size: 2},
+		Layers:        []ociDescriptor{layer},
+		Annotations: map[string]string{
+			"org.opencontainers.image.created":  time.Now().UTC().Format(time.RFC3339),
+			"org.opencontainers.image.source":   githubWeb + c.RepoOwner + "/" + c.RepoName,
+			"org.opencontainers.image.version":  c.Tag,
+			"com.github.release.asset":          c.AssetName,
+			"com.github.release.download-url":   c.DownloadURL,
+			"com.github.release.asset-sha256":   digest,
+			"com.github.release.repository":     c.RepoOwner + "/" + c.RepoName,
+			"com.github.release.tag":            c.Tag,
+			"com.github.release.republished-by": "get_gh_release",
+		},
+	}
+	body, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	resp, err := r.do(ctx, name, "push,pull", func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, r.base+"/v2/"+name+"/manifests/"+tag, bytes.NewReader(body))
+		if err == nil {
+			req.Header.Set("Content-Type", ociManifestType)
+		}
+		return req, err
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not push manifest: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("could not push manifest: %s", resp.Status)
+	}
+	sum := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// pushBlob uploads a blob unless the registry already has it. open is called for each attempt.
+func (r *ociRegistry) pushBlob(ctx context.Context, name, digest string, size int64, open func() (io.Reader, error)) error {
+	resp, err := r.do(ctx, name, "push,pull", func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodHead, r.base+"/v2/"+name+"/blobs/"+digest, nil)
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	resp, err = r.do(ctx, name, "push,pull", func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodPost, r.base+"/v2/"+name+"/blobs/uploads/", nil)
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("starting upload: %s", resp.Status)
+	}
+	loc, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("bad upload location: %w", err)
+	}
+	q := loc.Query()
+	q.Set("digest", digest)
+	loc.RawQuery = q.Encode()
+
+	resp, err = r.do(ctx, name, "push,pull", func() (*http.Request, error) {
+		body, err := open()
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, loc.String(), body)
+		if err != nil {
+			return nil, err
+		}
+		req.ContentLength = size
+		req.Header.Set("Content-Type", "application/octet-stream")
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("uploading blob: %s", resp.Status)
+	}
+	return nil
+}
+
+// do sends the request built by newReq, authenticating and retrying once if the registry
+// answers 401.
+func (r *ociRegistry) do(ctx context.Context, name, actions string, newReq func() (*http.Request, error)) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+		if r.token != "" {
+			req.Header.Set("Authorization", "Bearer "+r.token)
+		} else if r.username != "" {
+			req.SetBasicAuth(r.username, r.password)
+		}
+		return http.DefaultClient.Do(req)
+	}
+	resp, err := send()
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	if err := r.authenticate(ctx, challenge, "repository:"+name+":"+actions); err != nil {
+		return nil, err
+	}
+	return send()
+}
+
+// authenticate obtains a bearer token as described by a WWW-Authenticate challenge.
+func (r *ociRegistry) authenticate(ctx context.Context, challenge, scope string) error {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		if r.username == "" {
+			return errors.New("registry requires credentials: set OCI_USERNAME and OCI_PASSWORD or run docker login")
+		}
+		return errors.New("registry rejected the credentials")
+	}
+	attrs := map[string]string{}
+	for _, m := range challengeParam.FindAllStringSubmatch(params, -1) {
+		attrs[m[1]] = m[2]
+	}
+	u, err := url.Parse(attrs["realm"])
+	if err != nil || attrs["realm"] == "" {
+		return fmt.Errorf("bad auth challenge %q", challenge)
+	}
+	q := u.Query()
+	if attrs["service"] != "" {
+		q.Set("service", attrs["service"])
+	}
+	q.Set("scope", scope)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("token request failed: %s", resp.Status)
+	}
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return fmt.Errorf("could not parse token response: %w", err)
+	}
+	r.token = tok.Token
+	if r.token == "" {
+		r.token = tok.AccessToken
+	}
+	if r.token == "" {
+		return errors.New("token response contained no token")
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// fakeRegistry is an in-memory OCI registry that hands out bearer tokens like Docker Hub and
+// GHCR do.
+type fakeRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	scopes    []string
+	uploads   int
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path == "/token" {
+		f.scopes = append(f.scopes, r.URL.Query().Get("scope"))
+		json.NewEncoder(w).Encode(map[string]string{"token": "secret"})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="http://`+r.Host+`/token",service="fake"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, "/v2/me/tool/")
+	switch {
+	case !ok:
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == http.MethodHead && strings.HasPrefix(rest, "blobs/sha256:"):
+		if _, ok := f.blobs[strings.TrimPrefix(rest, "blobs/")]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case r.Method == http.MethodPost && rest == "blobs/uploads/":
+		w.Header().Set("Location", "/v2/me/tool/blobs/uploads/1?state=x")
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPut && strings.HasPrefix(rest, "blobs/uploads/"):
+		data, _ := io.ReadAll(r.Body)
+		sum := sha256.Sum256(data)
+		digest := r.URL.Query().Get("digest")
+		if digest != "sha256:"+hex.EncodeToString(sum[:]) || r.URL.Query().Get("state") != "x" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.blobs[digest] = data
+		f.uploads++
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && strings.HasPrefix(rest, "manifests/"):
+		data, _ := io.ReadAll(r.Body)
+		f.manifests[strings.TrimPrefix(rest, "manifests/")] = data
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestPushAsset(t *testing.T) {
+	t.Setenv("OCI_USERNAME", "")
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	f := &fakeRegistry{blobs: map[string][]byte{}, manifests: map[string][]byte{}}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	local := filepath.Join(t.TempDir(), "tool.tar.gz")
+	content := []byte("release asset")
+	if err := os.WriteFile(local, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+	c := releases.Candidate{RepoOwner: "me", RepoName: "tool", AssetName: "tool_linux_amd64.tar.gz", Tag: "v1.0.0"}
+
+	r := newOCIRegistry(strings.TrimPrefix(srv.URL, "http://"), true)
+	manifestDigest, err := r.pushAsset(context.Background(), "me/tool", "v1.0.0", c, local, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(f.blobs["sha256:"+digest]) != string(content) {
+		t.Error("asset was not uploaded")
+	}
+	if _, ok := f.blobs[ociEmptyDigest]; !ok {
+		t.Error("config blob was not uploaded")
+	}
+	if len(f.scopes) != 1 || f.scopes[0] != "repository:me/tool:push,pull" {
+		t.Errorf("got token scopes %q, want one for repository:me/tool:push,pull", f.scopes)
+	}
+
+	body := f.manifests["v1.0.0"]
+	manifestSum := sha256.Sum256(body)
+	if manifestDigest != "sha256:"+hex.EncodeToString(manifestSum[:]) {
+		t.Errorf("returned digest %s does not match the manifest pushed", manifestDigest)
+	}
+	var m ociManifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Layers) != 1 || m.Layers[0].Digest != "sha256:"+digest || m.Layers[0].Annotations[ociTitleKey] != c.AssetName {
+		t.Errorf("manifest does not carry the asset as its layer: %+v", m.Layers)
+	}
+	if m.Annotations["com.github.release.tag"] != "v1.0.0" {
+		t.Errorf("manifest does not record the release tag: %v", m.Annotations)
+	}
+
+	// Pushing again finds both blobs in the registry and only replaces the manifest.
+	if _, err := r.pushAsset(context.Background(), "me/tool", "latest", c, local, digest); err != nil {
+		t.Fatal(err)
+	}
+	if f.uploads != 2 {
+		t.Errorf("got %d blob uploads, want 2", f.uploads)
+	}
+	if _, ok := f.manifests["latest"]; !ok {
+		t.Error("second tag was not pushed")
+	}
+}
+
+func TestDockerCredentials(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	config := `{"auths": {"https://ghcr.io": {"auth": "bWU6cGFzczp3b3Jk"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if user, pass := dockerCredentials("ghcr.io"); user != "me" || pass != "pass:word" {
+		t.Errorf("got %q, %q, want me, pass:word", user, pass)
+	}
+	if user, _ := dockerCredentials("registry.example.com"); user != "" {
+		t.Errorf("got credentials %q for an unknown registry", user)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// pickCandidate lists candidates with numbers and asks which one to install.
+func pickCandidate(p *prompter, candidates []releases.Candidate) (releases.Candidate, error) {
+	fmt.Fprintf(p.w, "%d assets match:\n", len(candidates))
+	for i, c := range candidates {
+		fmt.Fprintf(p.w, "  %2d) %s/%s %s: %s", i+1, c.RepoOwner, c.RepoName, c.Tag, c.AssetName)
+		if c.Size > 0 {
+			fmt.Fprintf(p.w, " (%s)", formatSize(c.Size))
+		}
+		fmt.Fprintln(p.w)
+	}
+	answer, err := p.ask(fmt.Sprintf("Install which one (1-%d, q to quit)", len(candidates)), "", func(s string) error {
+		if s == "q" {
+			return nil
+		}
+		if n, err := strconv.Atoi(s); err != nil || n < 1 || n > len(candidates) {
+			return fmt.Errorf("answer a number from 1 to %d", len(candidates))
+		}
+		return nil
+	})
+	if err != nil {
+		return releases.Candidate{}, err
+	}
+	if answer == "q" {
+		return releases.Candidate{}, fmt.Errorf("%w: none picked", releases.ErrMultipleCandidates)
+	}
+	n, _ := strconv.Atoi(answer)
+	return candidates[n-1], nil
+}
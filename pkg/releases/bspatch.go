@@ -0,0 +1,101 @@
+package releases
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// bsdiffMagic starts a patch in the BSDIFF40 format of Colin Percival's bsdiff.
+const bsdiffMagic = "BSDIFF40"
+
+// ErrBadPatch means a delta patch is malformed or does not fit the file it is applied to.
+var ErrBadPatch = errors.New("malformed bsdiff patch")
+
+// Bspatch applies the BSDIFF40 patch of size patchSize to old and writes the new file to w.
+// Both inputs are read in place and the output is written as it is produced, so memory use
+// does not depend on the size of either file.
+func Bspatch(old io.ReaderAt, oldSize int64, patch io.ReaderAt, patchSize int64, w io.Writer) error {
+	// 1. Header: magic, lengths of the compressed control and diff blocks, new size
+	var header [32]byte
+	if _, err := patch.ReadAt(header[:], 0); err != nil {
+		return fmt.Errorf("%w: %v", ErrBadPatch, err)
+	}
+	if string(header[:8]) != bsdiffMagic {
+		return fmt.Errorf("%w: not a BSDIFF40 patch", ErrBadPatch)
+	}
+	ctrlLen, diffLen, newSize := offtin(header[8:]), offtin(header[16:]), offtin(header[24:])
+	if ctrlLen < 0 || diffLen < 0 || newSize < 0 || 32+ctrlLen+diffLen > patchSize {
+		return fmt.Errorf("%w: bad header", ErrBadPatch)
+	}
+
+	// 2. The three blocks are separate bzip2 streams, read side by side
+	ctrl := bzip2.NewReader(io.NewSectionReader(patch, 32, ctrlLen))
+	diff := bufio.NewReader(bzip2.NewReader(io.NewSectionReader(patch, 32+ctrlLen, diffLen)))
+	extra := bufio.NewReader(bzip2.NewReader(io.NewSectionReader(patch, 32+ctrlLen+diffLen, patchSize-32-ctrlLen-diffLen)))
+
+	// 3. Each control triple adds diff bytes to old bytes, copies extra bytes, then seeks old
+	buf := make([]byte, 64<<10)
+	oldBuf := make([]byte, len(buf))
+	var triple [24]byte
+	var oldPos, newPos int64
+	for newPos < newSize {
+		if _, err := io.ReadFull(ctrl, triple[:]); err != nil {
+			return fmt.Errorf("%w: control block: %v", ErrBadPatch, err)
+		}
+		add, copyLen, seek := offtin(triple[0:]), offtin(triple[8:]), offtin(triple[16:])
+		if add < 0 || copyLen < 0 || newPos+add+copyLen > newSize {
+			return fmt.Errorf("%w: control data out of range", ErrBadPatch)
+		}
+		for done := int64(0); done < add; {
+			n := min(int64(len(buf)), add-done)
+			if _, err := io.ReadFull(diff, buf[:n]); err != nil {
+				return fmt.Errorf("%w: diff block: %v", ErrBadPatch, err)
+			}
+			if err := readOld(old, oldSize, oldPos+done, oldBuf[:n]); err != nil {
+				return err
+			}
+			for i := range n {
+				buf[i] += oldBuf[i]
+			}
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+			done += n
+		}
+		newPos += add
+		oldPos += add
+		if _, err := io.CopyN(w, extra, copyLen); err != nil {
+			return fmt.Errorf("%w: extra block: %v", ErrBadPatch, err)
+		}
+		newPos += copyLen
+		oldPos += seek
+	}
+	return nil
+}
+
+// readOld fills p from old at off. Bytes outside old read as zero, as bspatch treats them.
+func readOld(old io.ReaderAt, oldSize, off int64, p []byte) error {
+	clear(p)
+	start, end := max(off, 0), min(off+int64(len(p)), oldSize)
+	if start >= end {
+		return nil
+	}
+	if _, err := old.ReadAt(p[start-off:end-off], start); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("could not read old file: %w", err)
+	}
+	return nil
+}
+
+// offtin decodes bsdiff's sign-and-magnitude little-endian 64-bit integer.
+func offtin(b []byte) int64 {
+	v := binary.LittleEndian.Uint64(b)
+	n := int64(v &^ (1 << 63))
+	if v&(1<<63) != 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,63 @@
+package releases
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+// testPatch is a BSDIFF40 patch turning testOld into testNew. Its first control triple
+// changes the version line and inserts a line of extra data, its second copies the rest of
+// the old file unchanged.
+const (
+	testOld   = "get_gh_release v1.0.0\nhello from the old build\n"
+	testNew   = "get_gh_release v1.1.0\nnow with deltas\nhello from the old build\n"
+	testPatch = "QlNESUZGNDAuAAAAAAAAACsAAAAAAAAAPwAAAAAAAABCWmg5MUFZJlNZ96aFlwAACGAASAhJICAAMQwBGRmpYclkYeLuSKcKEh700LLgQlpoOTFBWSZTWdtr3RAAAABgAGABAEAgACGMgzTRCV04u5IpwoSG217ogEJaaDkxQVkmU1nWXrdCAAAE0YAAEEAAJmWMgCAAIpgBkIBoA2SYpWuhgB3hdyRThQkNZet0IA=="
+)
+
+func TestBspatch(t *testing.T) {
+	patch, err := base64.StdEncoding.DecodeString(testPatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := Bspatch(bytes.NewReader([]byte(testOld)), int64(len(testOld)), bytes.NewReader(patch), int64(len(patch)), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != testNew {
+		t.Errorf("got %q, want %q", out.String(), testNew)
+	}
+}
+
+func TestBspatchRejectsBadPatches(t *testing.T) {
+	patch, err := base64.StdEncoding.DecodeString(testPatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string][]byte{
+		"wrong magic": append([]byte("BSDIFF41"), patch[8:]...),
+		"truncated":   patch[:len(patch)-20],
+		"no header":   patch[:16],
+	}
+	for name, p := range tests {
+		var out bytes.Buffer
+		err := Bspatch(bytes.NewReader([]byte(testOld)), int64(len(testOld)), bytes.NewReader(p), int64(len(p)), &out)
+		if !errors.Is(err, ErrBadPatch) {
+			t.Errorf("%s: got %v, want ErrBadPatch", name, err)
+		}
+	}
+}
+
+func TestOfftin(t *testing.T) {
+	tests := map[[8]byte]int64{
+		{0x2a}:                         42,
+		{0x2a, 0, 0, 0, 0, 0, 0, 0x80}: -42,
+		{0, 0, 0, 0, 0, 0, 0, 0x80}:    0,
+	}
+	for b, want := range tests {
+		if got := offtin(b[:]); got != want {
+			t.Errorf("offtin(%x) = %d, want %d", b, got, want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package releases
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Chunked downloads split large assets into ranges fetched in parallel and read back in
+// order. Only a few more ranges than there are parallel requests are held in memory at a
+// time, whatever the asset size.
+const (
+	// ChunkedMinSize is the smallest asset downloaded in chunks.
+	ChunkedMinSize = 64 << 20
+	// chunkSize is the length of each range request.
+	chunkSize = 8 << 20
+)
+
+// chunkResult is the outcome of fetching one range.
+type chunkResult struct {
+	data []byte
+	err  error
+}
+
+// chunkedReader reads an asset of known size as consecutive ranges fetched ahead of the
+// reader by a bounded number of goroutines.
+type chunkedReader struct {
+	cancel context.CancelFunc
+	queue  chan chan chunkResult // pending ranges, in order
+	cur    *bytes.Reader
+	err    error
+}
+
+// supportsRanges reports whether the server at url answers range requests for an object of
+// the given size.
+func supportsRanges(ctx context.Context, client *http.Client, url string, size int64) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusPartialContent && resp.Header.Get("Content-Range") == fmt.Sprintf("bytes 0-0/%d", size)
+}
+
+// openChunked starts downloading url in ranges with up to workers requests in flight.
+func openChunked(ctx context.Context, client *http.Client, url string, size int64, workers int) io.ReadCloser {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &chunkedReader{cancel: cancel, queue: make(chan chan chunkResult, workers-1)}
+	go func() {
+		defer close(r.queue)
+		for start := int64(0); start < size; start += chunkSize {
+			end := min(start+chunkSize, size) - 1
+			ch := make(chan chunkResult, 1)
+			go func() {
+				data, err := fetchRange(ctx, client, url, start, end)
+				ch <- chunkResult{data: data, err: err}
+			}()
+			select {
+			case r.queue <- ch:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return r
+}
+
+// fetchRange downloads bytes start to end inclusive.
+func fetchRange(ctx context.Context, client *http.Client, url string, start, end int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("range %d-%d: unexpected status %s", start, end, resp.Status)
+	}
+	data := make([]byte, end-start+1)
+	if _, err := io.ReadFull(resp.Body, data); err != nil {
+		return nil, fmt.Errorf("range %d-%d: %w", start, end, err)
+	}
+	return data, nil
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	for r.err == nil && (r.cur == nil || r.cur.Len() == 0) {
+		ch, ok := <-r.queue
+		if !ok {
+			r.err = io.EOF
+			break
+		}
+		res := <-ch
+		if res.err != nil {
+			r.err = fmt.Errorf("could not download asset content: %w", res.err)
+			break
+		}
+		r.cur = bytes.NewReader(res.data)
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.cur.Read(p)
+}
+
+func (r *chunkedReader) Close() error {
+	r.cancel()
+	if r.err == nil {
+		r.err = errors.New("read after close")
+	}
+	// Let the producer see the cancellation and release the fetched ranges
+	for range r.queue {
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package releases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Downloader fetches release assets from a provider.
+type Downloader struct {
+	Provider ReleaseProvider
+
+	// Stages transform the content of an asset as Install writes it, such as Decompress.
+	Stages []Stage
+}
+
+// Open returns the content of the asset of c.
+func (d Downloader) Open(ctx context.Context, c Candidate) (io.ReadCloser, error) {
+	return d.Provider.DownloadAsset(ctx, c)
+}
+
+// Digest streams the asset of c and returns its hex-encoded SHA-256 digest without keeping
+// a copy.
+func (d Downloader) Digest(ctx context.Context, c Candidate) (string, error) {
+	rc, err := d.Open(ctx, c)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", fmt.Errorf("could not read asset content: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Install downloads the asset of c through the stages to dest, makes it executable, and
+// returns the hex-encoded SHA-256 digest of the downloaded bytes.
+func (d Downloader) Install(ctx context.Context, c Candidate, dest string) (string, error) {
+	rc, err := d.Open(ctx, c)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	return InstallStream(rc, dest, c.Size, d.Stages)
+}
+
+// copyBuffers holds reusable copy buffers. They are larger than io.Copy's default to cut
+// the number of system calls on large assets.
+var copyBuffers = sync.Pool{New: func() any {
+	b := make([]byte, 1<<20)
+	return &b
+}}
+
+// WriteExecutable copies r into a new executable file at dest and returns the hex-encoded
+// SHA-256 digest of the bytes written. The file is written under a temporary name next to
+// dest and renamed into place once complete, so a failed write leaves any file already at
+// dest untouched.
+func WriteExecutable(r io.Reader, dest string) (string, error) {
+	tmp, digest, err := writeTemp(r, dest, 0)
+	if err != nil {
+		return "", err
+	}
+	if err := commitTemp(tmp, dest); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+// writeTemp copies r into a new executable file in the directory of dest and returns its
+// name and the hex-encoded SHA-256 digest of the bytes written. size is the expected size
+// of the content, used to preallocate the file; 0 means unknown. The file is synced to disk
+// before it is closed, so it is complete by the time commitTemp renames it into place. On
+// error the file is removed.
+func writeTemp(r io.Reader, dest string, size int64) (tmp string, digest string, err error) {
+	// 1. Create the output file, reserving its space up front
+	out, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".tmp-*")
+	if err != nil {
+		return "", "", fmt.Errorf("could not create file %s: %w", dest, err)
+	}
+	defer func() {
+		out.Close()
+		if err != nil {
+			os.Remove(out.Name())
+		}
+	}()
+	if size > 0 {
+		preallocate(out, size)
+	}
+
+	// 2. Write the body to the file, hashing it on the way through
+	h := sha256.New()
+	buf := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buf)
+	n, err := io.CopyBuffer(io.MultiWriter(out, h), r, *buf)
+	if err != nil {
+		return "", "", fmt.Errorf("could not write to file: %w", err)
+	}
+	// The content may be shorter than preallocated, for instance after decompression
+	if size > 0 && n != size {
+		if err := out.Truncate(n); err != nil {
+			return "", "", fmt.Errorf("could not write to file: %w", err)
+		}
+	}
+
+	// 3. Make the file executable (chmod +x) before it can be seen at dest. Windows has no
+	// execute permission; the .exe suffix makes a file executable there.
+	// 0755 is rwxr-xr-x
+	if runtime.GOOS != "windows" {
+		if err := out.Chmod(0755); err != nil {
+			return "", "", fmt.Errorf("could not make file executable: %w", err)
+		}
+	}
+	if err := out.Sync(); err != nil {
+		return "", "", fmt.Errorf("could not write to file: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return "", "", fmt.Errorf("could not write to file: %w", err)
+	}
+	return out.Name(), hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// commitTemp renames tmp, written by writeTemp, to dest in one step, replacing any file
+// there. It removes tmp if that fails.
+func commitTemp(tmp, dest string) error {
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("could not move %s into place: %w", dest, err)
+	}
+	return nil
+}
+
+// Verify checks a hex-encoded SHA-256 digest against the expected one, ignoring case. A
+// mismatch wraps ErrVerificationFailed.
+func Verify(name, digest, want string) error {
+	if !strings.EqualFold(digest, want) {
+		return fmt.Errorf("%w: digest mismatch for %s: expected %s, got %s", ErrVerificationFailed, name, want, digest)
+	}
+	return nil
+}
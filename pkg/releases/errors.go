@@ -0,0 +1,118 @@
+package releases
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// Errors returned by this package wrap one of these sentinels when the cause is known, so
+// callers can branch with errors.Is.
+var (
+	// ErrNoCandidates means no release or asset matched.
+	ErrNoCandidates = errors.New("no matching release asset")
+	// ErrMultipleCandidates means more than one asset matched where one was required.
+	ErrMultipleCandidates = errors.New("multiple matching release assets")
+	// ErrRateLimited means GitHub refused the request because a rate limit was exceeded.
+	ErrRateLimited = errors.New("GitHub rate limit exceeded")
+	// ErrVerificationFailed means downloaded content did not match its expected digest.
+	ErrVerificationFailed = errors.New("verification failed")
+	// ErrTokenScope means the token is missing, invalid or lacks access to the resource.
+	ErrTokenScope = errors.New("token lacks the required access")
+	// ErrNotFound means the resource does not exist, such as the latest release of a
+	// repository that has none.
+	ErrNotFound = errors.New("not found")
+)
+
+// MultipleCandidatesError lists the candidates of an ambiguous search. It matches
+// ErrMultipleCandidates.
+type MultipleCandidatesError struct {
+	Candidates []Candidate
+}
+
+func (e *MultipleCandidatesError) Error() string {
+	return fmt.Sprintf("%d matching release assets", len(e.Candidates))
+}
+
+func (e *MultipleCandidatesError) Is(target error) bool {
+	return target == ErrMultipleCandidates
+}
+
+// RateLimitError reports a rate limited request and when it may be retried. It matches
+// ErrRateLimited.
+type RateLimitError struct {
+	Reset      time.Time     // when the limit lifts
+	RetryAfter time.Duration // time left until Reset when the error occurred
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v (retry in %s): %v", ErrRateLimited, e.RetryAfter.Round(time.Second), e.Err)
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// apiError tags a GitHub API error with the sentinel describing its cause; now is the time
+// the error occurred.
+func apiError(err error, now time.Time) error {
+	var rle *github.RateLimitError
+	var abuse *github.AbuseRateLimitError
+	var resp *github.ErrorResponse
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &rle):
+		reset := rle.Rate.Reset.Time
+		return &RateLimitError{Reset: reset, RetryAfter: max(reset.Sub(now), 0), Err: err}
+	case errors.As(err, &abuse):
+		wait := abuse.GetRetryAfter()
+		return &RateLimitError{Reset: now.Add(wait), RetryAfter: wait, Err: err}
+	case errors.As(err, &resp) && resp.Response != nil &&
+		(resp.Response.StatusCode == http.StatusUnauthorized || resp.Response.StatusCode == http.StatusForbidden):
+		return fmt.Errorf("%w: %w", ErrTokenScope, err)
+	case errors.As(err, &resp) && resp.Response != nil && resp.Response.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	return err
+}
+
+// RepoError is a failure to look up the releases of one repository.
+type RepoError struct {
+	Owner, Repo string
+	Err         error
+}
+
+func (e *RepoError) Error() string {
+	return fmt.Sprintf("%s/%s: %v", e.Owner, e.Repo, e.Err)
+}
+
+func (e *RepoError) Unwrap() error {
+	return e.Err
+}
+
+// PartialError reports the repositories a search could not look into. The candidates found
+// in the others are returned alongside it.
+type PartialError struct {
+	Errors []*RepoError
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("%d repositories could not be searched", len(e.Errors))
+}
+
+func (e *PartialError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
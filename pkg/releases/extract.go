@@ -0,0 +1,210 @@
+package releases
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// ErrNoExecutable means an archive asset holds no file that looks like an executable.
+var ErrNoExecutable = errors.New("archive contains no executable")
+
+// executableMagic are the leading bytes of ELF, Mach-O and PE executables.
+var executableMagic = [][]byte{
+	[]byte("\x7fELF"),
+	{0xfe, 0xed, 0xfa, 0xce}, {0xfe, 0xed, 0xfa, 0xcf}, {0xce, 0xfa, 0xed, 0xfe}, {0xcf, 0xfa, 0xed, 0xfe},
+	[]byte("MZ"),
+}
+
+// fatMagic starts a Mach-O universal binary, and also a Java class file.
+var fatMagic = []byte{0xca, 0xfe, 0xba, 0xbe}
+
+// maxFatArchs bounds the architecture count that follows fatMagic in a universal binary. A
+// class file has its version there instead, which is 45 or more.
+const maxFatArchs = 20
+
+// executableHead is the number of leading bytes isExecutable needs.
+const executableHead = 8
+
+// isExecutable reports whether content starting with head is a native executable.
+func isExecutable(head []byte) bool {
+	if bytes.HasPrefix(head, fatMagic) {
+		if len(head) < executableHead {
+			return false
+		}
+		n := binary.BigEndian.Uint32(head[4:executableHead])
+		return n > 0 && n < maxFatArchs
+	}
+	for _, m := range executableMagic {
+		if bytes.HasPrefix(head, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Extract returns a Stage that unpacks the executable from a tar or zip archive, compressed
+// with gzip or bzip2 or not at all, recognised by magic bytes. Of the executables in the
+// archive, the one called name (ignoring case and a .exe suffix) is chosen, otherwise the
+// largest. Content that is not an archive passes through, decompressed if it was.
+//
+// Archive members are staged in temporary files, so memory use does not grow with the
+// archive. They are removed once the returned reader is closed.
+func Extract(name string) Stage {
+	return func(r io.Reader) (io.Reader, error) {
+		content, err := Decompress(r)
+		if err != nil {
+			return nil, err
+		}
+		br := bufio.NewReaderSize(content, 4096)
+		head, _ := br.Peek(262)
+		switch {
+		case len(head) >= 262 && string(head[257:262]) == "ustar":
+			return extractTar(br, name)
+		case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+			return extractZip(br, name)
+		case bytes.HasPrefix(head, []byte("\xfd7zXZ\x00")), bytes.HasPrefix(head, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+			return nil, fmt.Errorf("cannot unpack xz or zstd compressed assets")
+		}
+		return br, nil
+	}
+}
+
+// tempFile is a temporary file that is removed when closed.
+type tempFile struct {
+	*os.File
+}
+
+func (t tempFile) Close() error {
+	err := t.File.Close()
+	os.Remove(t.Name())
+	return err
+}
+
+// preferred reports whether the member called member is the executable called name.
+func preferred(member, name string) bool {
+	base := strings.TrimSuffix(strings.ToLower(path.Base(member)), ".exe")
+	return name != "" && base == strings.ToLower(name)
+}
+
+// extractTar copies the executables of a tar stream to temporary files and returns the
+// chosen one.
+func extractTar(r io.Reader, name string) (_ io.Reader, err error) {
+	var best tempFile
+	var bestName string
+	var bestSize int64
+	defer func() {
+		if err != nil && best.File != nil {
+			best.Close()
+		}
+	}()
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read tar archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if best.File != nil && preferred(bestName, name) || !preferred(hdr.Name, name) && hdr.Size <= bestSize {
+			continue
+		}
+		br := bufio.NewReader(tr)
+		head, _ := br.Peek(executableHead)
+		if !isExecutable(head) {
+			continue
+		}
+		f, err := os.CreateTemp("", "get_gh_release-*-"+path.Base(hdr.Name))
+		if err != nil {
+			return nil, err
+		}
+		if best.File != nil {
+			best.Close()
+		}
+		best, bestName, bestSize = tempFile{f}, hdr.Name, hdr.Size
+		if _, err := io.Copy(f, br); err != nil {
+			return nil, fmt.Errorf("could not extract %s: %w", hdr.Name, err)
+		}
+	}
+	if best.File == nil {
+		return nil, ErrNoExecutable
+	}
+	if _, err := best.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return best, nil
+}
+
+// zipMember reads one member of a zip archive kept in a temporary file, and removes the file
+// when closed.
+type zipMember struct {
+	io.ReadCloser
+	archive tempFile
+}
+
+func (z zipMember) Close() error {
+	z.ReadCloser.Close()
+	return z.archive.Close()
+}
+
+// extractZip stages a zip stream in a temporary file, which zip needs for random access,
+// and returns a reader of the chosen executable.
+func extractZip(r io.Reader, name string) (_ io.Reader, err error) {
+	f, err := os.CreateTemp("", "get_gh_release-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	archive := tempFile{f}
+	defer func() {
+		if err != nil {
+			archive.Close()
+		}
+	}()
+	size, err := io.Copy(f, r)
+	if err != nil {
+		return nil, fmt.Errorf("could not read zip archive: %w", err)
+	}
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return nil, fmt.Errorf("could not read zip archive: %w", err)
+	}
+	var best *zip.File
+	for _, zf := range zr.File {
+		if !zf.Mode().IsRegular() {
+			continue
+		}
+		if best != nil && (preferred(best.Name, name) || !preferred(zf.Name, name) && zf.UncompressedSize64 <= best.UncompressedSize64) {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			continue
+		}
+		head := make([]byte, executableHead)
+		n, _ := io.ReadFull(rc, head)
+		rc.Close()
+		if isExecutable(head[:n]) {
+			best = zf
+		}
+	}
+	if best == nil {
+		return nil, ErrNoExecutable
+	}
+	rc, err := best.Open()
+	if err != nil {
+		return nil, fmt.Errorf("could not extract %s: %w", best.Name, err)
+	}
+	return zipMember{ReadCloser: rc, archive: archive}, nil
+}
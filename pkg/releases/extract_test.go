@@ -0,0 +1,168 @@
+package releases
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+// member is a file in a test archive.
+type member struct {
+	name, content string
+}
+
+const (
+	elf       = "\x7fELF"
+	fatBinary = "\xca\xfe\xba\xbe\x00\x00\x00\x02"
+	javaClass = "\xca\xfe\xba\xbe\x00\x00\x00\x41"
+)
+
+func tarGz(t *testing.T, members ...member) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	for _, m := range members {
+		if err := tw.WriteHeader(&tar.Header{Name: m.name, Mode: 0755, Size: int64(len(m.content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, m.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func zipArchive(t *testing.T, members ...member) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for _, m := range members {
+		w, err := zw.Create(m.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, m.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// extract runs Extract(name) over data and returns what it produced, checking that no
+// temporary file is left behind once the result is closed.
+func extract(t *testing.T, name string, data []byte) (string, error) {
+	t.Helper()
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	r, err := Extract(name)(bytes.NewReader(data))
+	if err == nil {
+		var out []byte
+		out, err = io.ReadAll(r)
+		if c, ok := r.(io.Closer); ok {
+			c.Close()
+		}
+		if err == nil {
+			data = out
+		}
+	}
+	if left, _ := os.ReadDir(tmp); len(left) > 0 {
+		t.Errorf("temporary files left behind: %v", left)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr error
+	}{
+		{
+			name: "tar member named after the tool",
+			data: tarGz(t, member{"tool-1.0/README", "read me"}, member{"tool-1.0/helper", elf + "a much larger helper"}, member{"tool-1.0/tool", elf + "tool"}),
+			want: elf + "tool",
+		},
+		{
+			name: "largest tar executable otherwise",
+			data: tarGz(t, member{"bin/a", elf + "a"}, member{"bin/b", elf + "bbbb"}, member{"LICENSE", "a license longer than both"}),
+			want: elf + "bbbb",
+		},
+		{
+			name: "zip member named after the tool with .exe",
+			data: zipArchive(t, member{"Tool.exe", "MZtool"}, member{"other.exe", "MZ a larger executable"}),
+			want: "MZtool",
+		},
+		{
+			name: "universal binary",
+			data: zipArchive(t, member{"tool", fatBinary + "arm64 and amd64"}),
+			want: fatBinary + "arm64 and amd64",
+		},
+		{
+			name:    "java class is not an executable",
+			data:    tarGz(t, member{"Tool.class", javaClass + "bytecode"}),
+			wantErr: ErrNoExecutable,
+		},
+		{
+			name:    "tar without executables",
+			data:    tarGz(t, member{"README", "read me"}),
+			wantErr: ErrNoExecutable,
+		},
+		{
+			name:    "zip without executables",
+			data:    zipArchive(t, member{"README", "read me"}),
+			wantErr: ErrNoExecutable,
+		},
+		{
+			name: "bare binary passes through",
+			data: []byte(elf + "tool"),
+			want: elf + "tool",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extract(t, "tool", tt.data)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("got %q, %v, want error %v", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("got %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractCorruptArchive(t *testing.T) {
+	data := tarGz(t, member{"tool", elf + "tool"}, member{"other", elf + "a larger executable"})
+	// Cut the stream inside the second member, after the first has been staged
+	if _, err := extract(t, "", data[:len(data)/2]); err == nil {
+		t.Error("got no error for a truncated archive")
+	}
+}
+
+func TestExtractRejectsXz(t *testing.T) {
+	if _, err := extract(t, "tool", []byte("\xfd7zXZ\x00 compressed")); err == nil {
+		t.Error("got no error for xz content")
+	}
+}
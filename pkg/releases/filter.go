@@ -0,0 +1,97 @@
+package releases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ReleaseFilter holds back releases that should not be adopted yet. The zero value accepts
+// whatever the provider resolves.
+type ReleaseFilter struct {
+	// MinAge skips releases published less than this long ago, so that releases yanked or
+	// hotfixed soon after publication are never picked up.
+	MinAge time.Duration
+	// Blocked lists tags known to be bad, compared case-insensitively. They are skipped in
+	// favour of the next acceptable release.
+	Blocked []string
+	// Prereleases lets prereleases be picked, both as the latest release and by a version
+	// constraint. Drafts does the same for drafts, which only collaborators with push
+	// access to the repository can see.
+	Prereleases bool
+	Drafts      bool
+	// Now returns the current time; nil means time.Now.
+	Now func() time.Time
+}
+
+// Skip is a release a ReleaseFilter passed over, and why.
+type Skip struct {
+	Release *Release
+	Reason  string
+}
+
+// active reports whether f can reject anything.
+func (f ReleaseFilter) active() bool {
+	return f.MinAge > 0 || len(f.Blocked) > 0 || f.Prereleases || f.Drafts
+}
+
+// Reject returns why r may not be adopted, or "" if it may.
+func (f ReleaseFilter) Reject(r *Release) string {
+	now := time.Now
+	if f.Now != nil {
+		now = f.Now
+	}
+	for _, tag := range f.Blocked {
+		if strings.EqualFold(tag, r.Tag) {
+			return "blocklisted"
+		}
+	}
+	if age := now().Sub(r.PublishedAt); f.MinAge > 0 && age < f.MinAge {
+		return fmt.Sprintf("published %s ago, less than the minimum age of %s", age.Round(time.Minute), f.MinAge)
+	}
+	return ""
+}
+
+// Resolve is ReleaseProvider.ResolveRelease subject to the filter: it returns the newest
+// release of owner/repo that ResolveRelease could have returned and the filter accepts,
+// together with the newer releases it skipped. A nil release with a nil error means nothing
+// was acceptable.
+//
+// When versionPattern is a constraint (see IsConstraint), the highest version satisfying it
+// is resolved instead of the newest release.
+func (f ReleaseFilter) Resolve(ctx context.Context, p ReleaseProvider, owner, repo, versionPattern string) (*Release, []Skip, error) {
+	var constraint Constraint
+	if IsConstraint(versionPattern) {
+		var err error
+		if constraint, err = ParseConstraint(versionPattern); err != nil {
+			return nil, nil, err
+		}
+	} else if !f.active() {
+		r, err := p.ResolveRelease(ctx, owner, repo, versionPattern)
+		return r, nil, err
+	}
+	list, err := p.ListReleases(ctx, owner, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+	if constraint != nil {
+		list = constraint.Select(list, f.Prereleases, f.Drafts)
+	}
+	var skipped []Skip
+	for _, r := range list {
+		if constraint == nil && versionPattern == "" && (r.Draft && !f.Drafts || r.Prerelease && !f.Prereleases) {
+			// Not candidates for the latest release in the first place
+			continue
+		}
+		if constraint == nil && !MatchVersion(versionPattern, r.Tag) {
+			continue
+		}
+		if reason := f.Reject(r); reason != "" {
+			skipped = append(skipped, Skip{Release: r, Reason: reason})
+			continue
+		}
+		return r, skipped, nil
+	}
+	return nil, skipped, nil
+}
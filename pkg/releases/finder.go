@@ -0,0 +1,251 @@
+package releases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Finder discovers release assets in the repositories a provider exposes.
+type Finder struct {
+	Provider ReleaseProvider
+	Matcher  Matcher
+
+	// RepoRules holds extra matcher rules for individual repositories, keyed by lower-case
+	// owner/repo. They run after the matcher's own rules.
+	RepoRules map[string][]Rule
+
+	// Filter holds back releases that should not be adopted; Skipped, if set, is told
+	// about each release it passes over.
+	Filter  ReleaseFilter
+	Skipped func(Repo, Skip)
+
+	// RepoBlocked holds extra blocked tags for individual repositories, keyed by lower-case
+	// owner/repo.
+	RepoBlocked map[string][]string
+
+	// Explain, if set, is told why each repository and asset was or was not picked.
+	Explain func(Explanation)
+
+	// Concurrency is the number of repositories whose releases are looked up at once; 0
+	// means one at a time. Results, skips and explanations are reported in repository
+	// order regardless.
+	Concurrency int
+}
+
+// Explanation is one search decision. Asset and Tag are empty for decisions about a whole
+// repository.
+type Explanation struct {
+	Repo     Repo
+	Tag      string
+	Asset    string
+	Selected bool
+	Reason   string
+}
+
+// explain reports a decision if the finder explains itself.
+func (f Finder) explain(e Explanation) {
+	if f.Explain != nil {
+		f.Explain(e)
+	}
+}
+
+// RepoRelease is a release found in a repository.
+type RepoRelease struct {
+	Repo    Repo
+	Release *Release
+}
+
+// Releases resolves the release of each repository whose name contains pattern, ignoring
+// case (all if empty). A pattern of the form owner/repo names a single repository, which
+// is looked up directly instead of listing the provider's repositories. Without a version
+// pattern, repositories listed in pins (keyed by lower-case owner/repo) resolve to their
+// pinned version instead of the latest release. Repositories without a matching release
+// are skipped. If the releases of some repositories could not be looked up, Releases
+// returns the others together with a *PartialError; a rate limit aborts the search.
+func (f Finder) Releases(ctx context.Context, pattern, versionPattern string, pins map[string]string) ([]RepoRelease, error) {
+	repos, direct := directRepo(pattern)
+	if !direct {
+		var err error
+		if repos, err = f.Provider.ListRepos(ctx); err != nil {
+			return nil, err
+		}
+	}
+	// Look up the release of each repository, several at a time
+	results := make([]repoResult, len(repos))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sem := make(chan struct{}, max(f.Concurrency, 1))
+	var wg sync.WaitGroup
+	for i, repo := range repos {
+		// Filter by repository name pattern if provided
+		if !direct && pattern != "" && !strings.Contains(strings.ToLower(repo.Name), strings.ToLower(pattern)) {
+			results[i].excluded = true
+			continue
+		}
+		version := versionPattern
+		if version == "" {
+			version = pins[strings.ToLower(repo.Owner+"/"+repo.Name)]
+		}
+		results[i].version = version
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results[i].err = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+			r := &results[i]
+			r.release, r.skipped, r.err = f.FilterFor(repo).Resolve(ctx, f.Provider, repo.Owner, repo.Name, version)
+			if errors.Is(r.err, ErrRateLimited) {
+				// Stop the other lookups; the search is over
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Report the results in repository order. Lookups cut short by a rate limit elsewhere
+	// are not failures of their own.
+	rateLimited := slices.ContainsFunc(results, func(r repoResult) bool { return errors.Is(r.err, ErrRateLimited) })
+	var found []RepoRelease
+	var failed []*RepoError
+	for i, repo := range repos {
+		r := results[i]
+		if r.excluded {
+			f.explain(Explanation{Repo: repo, Reason: fmt.Sprintf("name does not contain %q", pattern)})
+			continue
+		}
+		if f.Skipped != nil {
+			for _, s := range r.skipped {
+				f.Skipped(repo, s)
+			}
+		}
+		if errors.Is(r.err, ErrRateLimited) {
+			return nil, r.err
+		}
+		if rateLimited && errors.Is(r.err, context.Canceled) {
+			continue
+		}
+		if r.err != nil && !errors.Is(r.err, ErrNotFound) {
+			f.explain(Explanation{Repo: repo, Reason: fmt.Sprintf("releases could not be read: %v", r.err)})
+			failed = append(failed, &RepoError{Owner: repo.Owner, Repo: repo.Name, Err: r.err})
+			continue
+		}
+		if r.release == nil {
+			// A repository without releases is not an error
+			if direct && r.err != nil {
+				f.explain(Explanation{Repo: repo, Reason: "repository not found"})
+			} else if r.version != "" {
+				f.explain(Explanation{Repo: repo, Reason: fmt.Sprintf("no release matching %q", r.version)})
+			} else {
+				f.explain(Explanation{Repo: repo, Reason: "no release"})
+			}
+			continue
+		}
+		found = append(found, RepoRelease{Repo: repo, Release: r.release})
+	}
+	if len(failed) > 0 {
+		return found, &PartialError{Errors: failed}
+	}
+	return found, nil
+}
+
+// repoResult is the outcome of looking up the release of one repository.
+type repoResult struct {
+	excluded bool // name does not match the pattern
+	version  string
+	release  *Release
+	skipped  []Skip
+	err      error
+}
+
+// directRepo returns the repository an owner/repo pattern names. The owner may itself
+// contain slashes, as GitLab subgroups do.
+func directRepo(pattern string) ([]Repo, bool) {
+	i := strings.LastIndex(pattern, "/")
+	if i < 0 {
+		return nil, false
+	}
+	owner, name := pattern[:i], pattern[i+1:]
+	if name == "" || slices.Contains(strings.Split(owner, "/"), "") {
+		return nil, false
+	}
+	return []Repo{{Owner: owner, Name: name}}, true
+}
+
+// Find searches the releases found by Releases for assets the matcher accepts. Each
+// repository contributes its best ranked asset, or all of them if several rank equally, so
+// that the caller can choose. Errors are as for Releases.
+func (f Finder) Find(ctx context.Context, pattern, versionPattern string, pins map[string]string) ([]Candidate, error) {
+	found, err := f.Releases(ctx, pattern, versionPattern, pins)
+	var partial *PartialError
+	if err != nil && !errors.As(err, &partial) {
+		return nil, err
+	}
+	var candidates []Candidate
+	for _, rr := range found {
+		repo := rr.Repo
+		// Find the best matching assets in the release
+		matcher := f.MatcherFor(repo)
+		if f.Explain != nil {
+			if len(rr.Release.Assets) == 0 {
+				f.explain(Explanation{Repo: repo, Tag: rr.Release.Tag, Reason: "release has no assets"})
+			}
+			for _, v := range matcher.Explain(rr.Release) {
+				f.explain(Explanation{Repo: repo, Tag: rr.Release.Tag, Asset: v.Asset.Name, Selected: v.Selected, Reason: v.Reason})
+			}
+		}
+		assets := matcher.MatchAll(rr.Release)
+		for _, asset := range assets {
+			if rank(asset.Name) != rank(assets[0].Name) {
+				break
+			}
+			candidates = append(candidates, NewCandidate(repo.Owner, repo.Name, rr.Release, *asset))
+		}
+	}
+	return candidates, err
+}
+
+// FilterFor returns the release filter with the tags blocked for repo added.
+func (f Finder) FilterFor(repo Repo) ReleaseFilter {
+	filter := f.Filter
+	if blocked := f.RepoBlocked[strings.ToLower(repo.Owner+"/"+repo.Name)]; len(blocked) > 0 {
+		filter.Blocked = append(slices.Clone(filter.Blocked), blocked...)
+	}
+	return filter
+}
+
+// MatcherFor returns the matcher with the rules for repo added.
+func (f Finder) MatcherFor(repo Repo) Matcher {
+	matcher := f.Matcher
+	if rules := f.RepoRules[strings.ToLower(repo.Owner+"/"+repo.Name)]; len(rules) > 0 {
+		matcher.Rules = append(slices.Clone(matcher.Rules), rules...)
+	}
+	return matcher
+}
+
+// FindOne is Find for callers that need exactly one asset. It fails with ErrNoCandidates when
+// nothing matches and with a *MultipleCandidatesError when the match is ambiguous. Failures
+// to search some repositories are only reported when nothing matched elsewhere.
+func (f Finder) FindOne(ctx context.Context, pattern, versionPattern string, pins map[string]string) (Candidate, error) {
+	candidates, err := f.Find(ctx, pattern, versionPattern, pins)
+	var partial *PartialError
+	if err != nil && (!errors.As(err, &partial) || len(candidates) == 0) {
+		return Candidate{}, err
+	}
+	switch len(candidates) {
+	case 0:
+		return Candidate{}, ErrNoCandidates
+	case 1:
+		return candidates[0], nil
+	default:
+		return Candidate{}, &MultipleCandidatesError{Candidates: candidates}
+	}
+}
@@ -0,0 +1,307 @@
+package releases
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Gitea is the ReleaseProvider for Gitea and Forgejo instances, such as Codeberg. Their
+// releases API is modelled on GitHub's, so releases and assets map across directly.
+type Gitea struct {
+	// BaseURL is the root of the REST API, such as https://codeberg.org/api/v1/.
+	BaseURL *url.URL
+	// Client sends every request. NewGitea gives it a transport that adds the token to
+	// requests for the instance's host only; nil means http.DefaultClient.
+	Client *http.Client
+
+	// Public makes ListRepos return the public repositories of the authenticated user;
+	// otherwise it returns every repository the user can access.
+	Public bool
+	// Orgs makes ListRepos return the repositories of these organizations instead, all that
+	// the token can see, or only the public ones when Public is set.
+	Orgs []string
+
+	// Sort orders ListRepos by pushed, updated, created or full_name, as for GitHub; Gitea
+	// does not record pushes apart from updates, so pushed is updated. Empty means
+	// full_name. The instance lists repositories unsorted, so they are all listed before
+	// being sorted and cut to MaxRepos.
+	Sort string
+	// PerPage is the number of repositories requested per page, up to the instance's
+	// maximum, which is 50 unless configured otherwise; 0 means 50.
+	PerPage int
+	// MaxRepos limits ListRepos to this many repositories; 0 means no limit.
+	MaxRepos int
+
+	// Clock is used to work out how long a rate limit lasts; nil means the system clock.
+	Clock Clock
+
+	// Chunks is the number of parallel range requests used for assets of ChunkedMinSize
+	// or more, where the server supports them. Below 2, assets are downloaded in a single
+	// request.
+	Chunks int
+}
+
+// NewGitea returns a Gitea provider for the Gitea or Forgejo instance whose API is served
+// from baseURL, such as https://git.example.com/api/v1/, authenticated with token, which may
+// be empty for anonymous access to public repositories. A URL without a path has /api/v1/
+// added.
+func NewGitea(baseURL, token string, opts ...Option) (Gitea, error) {
+	o := options{transport: http.DefaultTransport}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return Gitea{}, err
+	}
+	if base.Scheme == "" || base.Host == "" {
+		return Gitea{}, fmt.Errorf("API URL %q is not an absolute URL", baseURL)
+	}
+	if base.Path == "" || base.Path == "/" {
+		base.Path = "/api/v1/"
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	rt := o.transport
+	if token != "" {
+		rt = &hostTokenTransport{host: base.Host, token: token, base: rt}
+	}
+	return Gitea{
+		BaseURL: base,
+		Client:  &http.Client{Transport: rt},
+		Clock:   o.clock,
+	}, nil
+}
+
+func (g Gitea) client() *http.Client {
+	if g.Client == nil {
+		return http.DefaultClient
+	}
+	return g.Client
+}
+
+// get fetches an API path, relative to BaseURL, and decodes the JSON response into v. It
+// returns the URL of the next page, or "" on the last one.
+func (g Gitea) get(ctx context.Context, path string, query url.Values, v any) (string, error) {
+	u, err := g.BaseURL.Parse(path)
+	if err != nil {
+		return "", err
+	}
+	u.RawQuery = query.Encode()
+	return g.getURL(ctx, u, v)
+}
+
+func (g Gitea) getURL(ctx context.Context, u *url.URL, v any) (string, error) {
+	header, err := getJSON(ctx, g.client(), u, v, g.Clock)
+	if err != nil {
+		return "", err
+	}
+	return nextLink(header), nil
+}
+
+// linkNext matches the next page in a Link header.
+var linkNext = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="next"`)
+
+// nextLink returns the URL of the next page a Link header gives, or "".
+func nextLink(header http.Header) string {
+	for _, l := range header.Values("Link") {
+		if m := linkNext.FindStringSubmatch(l); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// giteaRepo is the part of a Gitea repository the provider reads.
+type giteaRepo struct {
+	Name     string    `json:"name"`
+	FullName string    `json:"full_name"`
+	Private  bool      `json:"private"`
+	Created  time.Time `json:"created_at"`
+	Updated  time.Time `json:"updated_at"`
+	Owner    struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+}
+
+func (g Gitea) ListRepos(ctx context.Context) ([]Repo, error) {
+	limit := g.PerPage
+	if limit <= 0 {
+		limit = 50
+	}
+	query := url.Values{"limit": {strconv.Itoa(limit)}}
+
+	var lists []string
+	switch {
+	case len(g.Orgs) > 0:
+		for _, org := range g.Orgs {
+			lists = append(lists, "orgs/"+url.PathEscape(org)+"/repos")
+		}
+	case g.Public:
+		var user struct {
+			Login string `json:"login"`
+		}
+		if _, err := g.get(ctx, "user", nil, &user); err != nil {
+			return nil, err
+		}
+		lists = []string{"users/" + url.PathEscape(user.Login) + "/repos"}
+	default:
+		lists = []string{"user/repos"}
+	}
+
+	var repos []giteaRepo
+	for i, list := range lists {
+		var page []giteaRepo
+		next, err := g.get(ctx, list, query, &page)
+		for err == nil {
+			repos = append(repos, page...)
+			if next == "" {
+				break
+			}
+			var u *url.URL
+			if u, err = g.BaseURL.Parse(next); err == nil {
+				page = nil
+				next, err = g.getURL(ctx, u, &page)
+			}
+		}
+		if err != nil {
+			if len(g.Orgs) > 0 {
+				return nil, fmt.Errorf("listing repositories of %s: %w", g.Orgs[i], err)
+			}
+			return nil, err
+		}
+	}
+	if g.Public {
+		repos = slices.DeleteFunc(repos, func(r giteaRepo) bool { return r.Private })
+	}
+
+	switch g.Sort {
+	case "pushed", "updated":
+		slices.SortStableFunc(repos, func(a, b giteaRepo) int { return b.Updated.Compare(a.Updated) })
+	case "created":
+		slices.SortStableFunc(repos, func(a, b giteaRepo) int { return b.Created.Compare(a.Created) })
+	default:
+		slices.SortStableFunc(repos, func(a, b giteaRepo) int {
+			return cmp.Compare(strings.ToLower(a.FullName), strings.ToLower(b.FullName))
+		})
+	}
+	if g.MaxRepos > 0 && len(repos) > g.MaxRepos {
+		repos = repos[:g.MaxRepos]
+	}
+	out := make([]Repo, 0, len(repos))
+	for _, r := range repos {
+		out = append(out, Repo{Owner: r.Owner.Login, Name: r.Name})
+	}
+	return out, nil
+}
+
+// giteaRelease is a release as the Gitea API returns it.
+type giteaRelease struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"`
+	HTMLURL     string    `json:"html_url"`
+	PublishedAt time.Time `json:"published_at"`
+	Prerelease  bool      `json:"prerelease"`
+	Draft       bool      `json:"draft"`
+	Assets      []struct {
+		ID                 int64  `json:"id"`
+		Name               string `json:"name"`
+		Size               int64  `json:"size"`
+		DownloadCount      int    `json:"download_count"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func (r giteaRelease) release() *Release {
+	out := &Release{
+		Tag:         r.TagName,
+		Name:        r.Name,
+		Body:        r.Body,
+		URL:         r.HTMLURL,
+		PublishedAt: r.PublishedAt,
+		Prerelease:  r.Prerelease,
+		Draft:       r.Draft,
+	}
+	for _, a := range r.Assets {
+		out.Assets = append(out.Assets, Asset{
+			ID:        a.ID,
+			Name:      a.Name,
+			URL:       a.BrowserDownloadURL,
+			Size:      a.Size,
+			Downloads: a.DownloadCount,
+		})
+	}
+	return out
+}
+
+// repoPath is the API path of the repository owner/repo.
+func repoPath(owner, repo string) string {
+	return "repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+}
+
+func (g Gitea) ResolveRelease(ctx context.Context, owner, repo, versionPattern string) (*Release, error) {
+	if versionPattern == "" {
+		var r giteaRelease
+		if _, err := g.get(ctx, repoPath(owner, repo)+"/releases/latest", nil, &r); err != nil {
+			return nil, err
+		}
+		return r.release(), nil
+	}
+	list, err := g.ListReleases(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	versionPattern = strings.ToLower(versionPattern)
+	for _, r := range list {
+		if strings.Contains(strings.ToLower(r.Tag), versionPattern) {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListReleases returns the most recent releases, as many as the instance returns in one
+// page: 50 unless configured otherwise.
+func (g Gitea) ListReleases(ctx context.Context, owner, repo string) ([]*Release, error) {
+	var list []giteaRelease
+	if _, err := g.get(ctx, repoPath(owner, repo)+"/releases", url.Values{"limit": {"50"}}, &list); err != nil {
+		return nil, err
+	}
+	out := make([]*Release, 0, len(list))
+	for _, r := range list {
+		out = append(out, r.release())
+	}
+	return out, nil
+}
+
+func (g Gitea) ReleaseByTag(ctx context.Context, owner, repo, tag string) (*Release, error) {
+	var r giteaRelease
+	if _, err := g.get(ctx, repoPath(owner, repo)+"/releases/tags/"+url.PathEscape(tag), nil, &r); err != nil {
+		return nil, err
+	}
+	return r.release(), nil
+}
+
+func (g Gitea) DownloadAsset(ctx context.Context, c Candidate) (io.ReadCloser, error) {
+	if c.DownloadURL == "" {
+		return nil, fmt.Errorf("asset %s has no download link", c.AssetName)
+	}
+	return downloadLink(ctx, g.client(), c.DownloadURL, c.Size, g.Chunks, g.Clock)
+}
+
+// DownloadAssetFrom returns the content of the asset of c from byte offset on. It fails if
+// the server does not serve that range.
+func (g Gitea) DownloadAssetFrom(ctx context.Context, c Candidate, offset int64) (io.ReadCloser, error) {
+	return downloadLinkFrom(ctx, g.client(), c.DownloadURL, offset)
+}
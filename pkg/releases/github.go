@@ -0,0 +1,278 @@
+package releases
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// GitHub is the ReleaseProvider for github.com and GitHub Enterprise.
+type GitHub struct {
+	Client *github.Client
+	// HTTPClient fetches assets from the storage location the API redirects to. That
+	// location is pre-signed, so HTTPClient must not add GitHub credentials; nil means
+	// http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Public makes ListRepos return the authenticated user's own repositories; otherwise
+	// it returns the private repositories the token can access.
+	Public bool
+	// Orgs makes ListRepos return the repositories of these organizations instead, all that
+	// the token can see, or only the public ones when Public is set.
+	Orgs []string
+
+	// Sort orders ListRepos by pushed, updated, created or full_name; pushed, updated and
+	// created list the most recent first. Empty means full_name.
+	Sort string
+	// PerPage is the number of repositories requested per page, up to 100; 0 means 100.
+	PerPage int
+	// MaxRepos stops ListRepos after this many repositories; 0 means no limit.
+	MaxRepos int
+
+	// Clock is used to work out how long a rate limit lasts; nil means the system clock.
+	Clock Clock
+
+	// Chunks is the number of parallel range requests used for assets of ChunkedMinSize
+	// or more, where the storage server supports them. Below 2, assets are downloaded in a
+	// single request.
+	Chunks int
+}
+
+// apiError classifies an API error, timing rate limits with the provider's clock.
+func (g GitHub) apiError(err error) error {
+	now := time.Now()
+	if g.Clock != nil {
+		now = g.Clock.Now()
+	}
+	return apiError(err, now)
+}
+
+func (g GitHub) ListRepos(ctx context.Context) ([]Repo, error) {
+	list := github.ListOptions{PerPage: g.PerPage}
+	if list.PerPage <= 0 || list.PerPage > 100 {
+		list.PerPage = 100
+	}
+	direction := ""
+	if g.Sort != "" && g.Sort != "full_name" {
+		direction = "desc"
+	}
+	full := func(repos []*github.Repository) bool {
+		return g.MaxRepos > 0 && len(repos) >= g.MaxRepos
+	}
+
+	var repos []*github.Repository
+	if len(g.Orgs) > 0 {
+		repoType := "all"
+		if g.Public {
+			repoType = "public"
+		}
+		for _, org := range g.Orgs {
+			opts := &github.RepositoryListByOrgOptions{
+				Type:        repoType,
+				Sort:        g.Sort,
+				Direction:   direction,
+				ListOptions: list,
+			}
+			for !full(repos) {
+				r, resp, err := g.Client.Repositories.ListByOrg(ctx, org, opts)
+				if err != nil {
+					return nil, fmt.Errorf("listing repositories of %s: %w", org, g.apiError(err))
+				}
+				repos = append(repos, r...)
+				if resp.NextPage == 0 {
+					break
+				}
+				opts.Page = resp.NextPage
+			}
+		}
+	} else if g.Public {
+		user, _, err := g.Client.Users.Get(ctx, "")
+		if err != nil {
+			return nil, g.apiError(err)
+		}
+		opts := &github.RepositoryListByUserOptions{
+			Sort:        g.Sort,
+			Direction:   direction,
+			ListOptions: list,
+		}
+		for !full(repos) {
+			r, resp, err := g.Client.Repositories.ListByUser(ctx, user.GetLogin(), opts)
+			if err != nil {
+				return nil, g.apiError(err)
+			}
+			repos = append(repos, r...)
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+	} else {
+		opts := &github.RepositoryListOptions{
+			Visibility:  "private",
+			Sort:        g.Sort,
+			Direction:   direction,
+			ListOptions: list,
+		}
+		for !full(repos) {
+			r, resp, err := g.Client.Repositories.List(ctx, "", opts)
+			if err != nil {
+				return nil, g.apiError(err)
+			}
+			repos = append(repos, r...)
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+	}
+	if full(repos) {
+		repos = repos[:g.MaxRepos]
+	}
+	out := make([]Repo, 0, len(repos))
+	for _, r := range repos {
+		out = append(out, Repo{Owner: r.GetOwner().GetLogin(), Name: r.GetName()})
+	}
+	return out, nil
+}
+
+func (g GitHub) ResolveRelease(ctx context.Context, owner, repo, versionPattern string) (*Release, error) {
+	if versionPattern == "" {
+		release, _, err := g.Client.Repositories.GetLatestRelease(ctx, owner, repo)
+		if err != nil {
+			return nil, g.apiError(err)
+		}
+		return FromGitHub(release), nil
+	}
+	versionPattern = strings.ToLower(versionPattern)
+	releases, _, err := g.Client.Repositories.ListReleases(ctx, owner, repo, nil)
+	if err != nil {
+		return nil, g.apiError(err)
+	}
+	for _, r := range releases {
+		if strings.Contains(strings.ToLower(r.GetTagName()), versionPattern) {
+			return FromGitHub(r), nil
+		}
+	}
+	return nil, nil
+}
+
+// ListReleases returns the 100 most recent releases.
+func (g GitHub) ListReleases(ctx context.Context, owner, repo string) ([]*Release, error) {
+	list, _, err := g.Client.Repositories.ListReleases(ctx, owner, repo, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return nil, g.apiError(err)
+	}
+	out := make([]*Release, 0, len(list))
+	for _, r := range list {
+		out = append(out, FromGitHub(r))
+	}
+	return out, nil
+}
+
+func (g GitHub) ReleaseByTag(ctx context.Context, owner, repo, tag string) (*Release, error) {
+	release, _, err := g.Client.Repositories.GetReleaseByTag(ctx, owner, repo, tag)
+	if err != nil {
+		return nil, g.apiError(err)
+	}
+	return FromGitHub(release), nil
+}
+
+func (g GitHub) DownloadAsset(ctx context.Context, c Candidate) (io.ReadCloser, error) {
+	// Ask the API where the asset is stored without following the redirect, so that the
+	// authenticated client never talks to the storage host
+	rc, url, err := g.Client.Repositories.DownloadReleaseAsset(ctx, c.RepoOwner, c.RepoName, c.AssetID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not download asset content: %w", g.apiError(err))
+	}
+	if rc != nil {
+		// The API served the content itself
+		return rc, nil
+	}
+	return g.openStorage(ctx, url, c.Size)
+}
+
+// DownloadAssetFrom returns the content of the asset of c from byte offset on. It fails if
+// the storage host does not serve that range.
+func (g GitHub) DownloadAssetFrom(ctx context.Context, c Candidate, offset int64) (io.ReadCloser, error) {
+	rc, url, err := g.Client.Repositories.DownloadReleaseAsset(ctx, c.RepoOwner, c.RepoName, c.AssetID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not download asset content: %w", g.apiError(err))
+	}
+	if rc != nil {
+		rc.Close()
+		return nil, fmt.Errorf("asset is not served from storage that supports ranges")
+	}
+	client := g.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not download asset content: %w", err)
+	}
+	if resp.StatusCode != http.StatusPartialContent || !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("storage returned %s to a range request", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// openStorage fetches an asset of the given size from its storage location.
+func (g GitHub) openStorage(ctx context.Context, url string, size int64) (io.ReadCloser, error) {
+	client := g.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if g.Chunks > 1 && size >= ChunkedMinSize && supportsRanges(ctx, client, url, size) {
+		return openChunked(ctx, client, url, size, g.Chunks), nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "*/*")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not download asset content: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("could not download asset content: storage returned %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// FromGitHub converts a release returned by the GitHub API or carried by a webhook event.
+func FromGitHub(r *github.RepositoryRelease) *Release {
+	out := &Release{
+		Tag:         r.GetTagName(),
+		Name:        r.GetName(),
+		Body:        r.GetBody(),
+		URL:         r.GetHTMLURL(),
+		PublishedAt: r.GetPublishedAt().Time,
+		Prerelease:  r.GetPrerelease(),
+		Draft:       r.GetDraft(),
+	}
+	for _, a := range r.Assets {
+		out.Assets = append(out.Assets, Asset{
+			ID:          a.GetID(),
+			Name:        a.GetName(),
+			URL:         a.GetBrowserDownloadURL(),
+			ContentType: a.GetContentType(),
+			Size:        int64(a.GetSize()),
+			Downloads:   a.GetDownloadCount(),
+		})
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package releases
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// newTestGitHub returns a GitHub provider whose API is served by api.
+func newTestGitHub(t *testing.T, api http.Handler) GitHub {
+	t.Helper()
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	client := github.NewClient(nil).WithAuthToken("secret")
+	base, err := url.Parse(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.BaseURL = base
+	return GitHub{Client: client}
+}
+
+func readAll(t *testing.T, rc io.ReadCloser, err error) string {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestDownloadAssetFollowsRedirectWithoutCredentials(t *testing.T) {
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("storage got Authorization %q", auth)
+		}
+		if r.URL.Query().Get("sig") != "abc" {
+			t.Errorf("storage got query %q", r.URL.RawQuery)
+		}
+		io.WriteString(w, "binary")
+	}))
+	defer storage.Close()
+	g := newTestGitHub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/o/r/releases/assets/7" {
+			t.Errorf("API got %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Accept"); got != "application/octet-stream" {
+			t.Errorf("API got Accept %q", got)
+		}
+		http.Redirect(w, r, storage.URL+"/asset?sig=abc", http.StatusFound)
+	}))
+
+	rc, err := g.DownloadAsset(context.Background(), Candidate{RepoOwner: "o", RepoName: "r", AssetID: 7, Size: 6})
+	if got := readAll(t, rc, err); got != "binary" {
+		t.Errorf("got %q, want %q", got, "binary")
+	}
+}
+
+func TestDownloadAssetServedByAPI(t *testing.T) {
+	g := newTestGitHub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "direct")
+	}))
+
+	rc, err := g.DownloadAsset(context.Background(), Candidate{RepoOwner: "o", RepoName: "r", AssetID: 7})
+	if got := readAll(t, rc, err); got != "direct" {
+		t.Errorf("got %q, want %q", got, "direct")
+	}
+}
+
+func TestDownloadAssetStorageError(t *testing.T) {
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "expired", http.StatusForbidden)
+	}))
+	defer storage.Close()
+	g := newTestGitHub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, storage.URL+"/asset", http.StatusFound)
+	}))
+
+	_, err := g.DownloadAsset(context.Background(), Candidate{RepoOwner: "o", RepoName: "r", AssetID: 7})
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("got error %v, want the storage status", err)
+	}
+}
+
+func TestDownloadAssetAPIError(t *testing.T) {
+	g := newTestGitHub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+	}))
+
+	_, err := g.DownloadAsset(context.Background(), Candidate{RepoOwner: "o", RepoName: "r", AssetID: 7})
+	if err == nil {
+		t.Fatal("got no error for a missing asset")
+	}
+}
+
+func TestDownloadAssetFrom(t *testing.T) {
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "asset", time.Time{}, strings.NewReader("0123456789"))
+	}))
+	defer storage.Close()
+	g := newTestGitHub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, storage.URL+"/asset", http.StatusFound)
+	}))
+
+	rc, err := g.DownloadAssetFrom(context.Background(), Candidate{RepoOwner: "o", RepoName: "r", AssetID: 7, Size: 10}, 4)
+	if got := readAll(t, rc, err); got != "456789" {
+		t.Errorf("got %q, want %q", got, "456789")
+	}
+}
+
+func TestDownloadAssetFromWithoutRanges(t *testing.T) {
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "0123456789")
+	}))
+	defer storage.Close()
+	g := newTestGitHub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, storage.URL+"/asset", http.StatusFound)
+	}))
+
+	_, err := g.DownloadAssetFrom(context.Background(), Candidate{RepoOwner: "o", RepoName: "r", AssetID: 7, Size: 10}, 4)
+	if err == nil {
+		t.Fatal("got no error from storage that ignores ranges")
+	}
+}
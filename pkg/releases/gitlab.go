@@ -0,0 +1,285 @@
+package releases
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GitLab is the ReleaseProvider for gitlab.com and self-managed GitLab instances.
+// Repositories are GitLab projects: Owner is the full path of the project's namespace, which
+// may include subgroups, as in group/subgroup, and Name is the project path. Assets are the
+// links attached to a release; GitLab does not report their size, so it is left unknown.
+type GitLab struct {
+	// BaseURL is the root of the REST API, such as https://gitlab.com/api/v4/.
+	BaseURL *url.URL
+	// Client sends every request. NewGitLab gives it a transport that adds the token to
+	// requests for the GitLab host only, so asset links to other hosts, and redirects to
+	// them, are fetched without it; nil means http.DefaultClient.
+	Client *http.Client
+
+	// Public makes ListRepos return the public projects the authenticated user owns;
+	// otherwise it returns every project the user is a member of.
+	Public bool
+	// Orgs makes ListRepos return the projects of these groups and their subgroups instead,
+	// all that the token can see, or only the public ones when Public is set.
+	Orgs []string
+
+	// Sort orders ListRepos by pushed, updated, created or full_name, as for GitHub; pushed
+	// is the last activity in the project. Empty means full_name.
+	Sort string
+	// PerPage is the number of projects requested per page, up to 100; 0 means 100.
+	PerPage int
+	// MaxRepos stops ListRepos after this many projects; 0 means no limit.
+	MaxRepos int
+
+	// Clock is used to work out how long a rate limit lasts; nil means the system clock.
+	Clock Clock
+
+	// Chunks is the number of parallel range requests used for assets of ChunkedMinSize
+	// or more, where the server supports them. Below 2, assets are downloaded in a single
+	// request.
+	Chunks int
+}
+
+// DefaultGitLabURL is the API root of gitlab.com.
+const DefaultGitLabURL = "https://gitlab.com/api/v4/"
+
+// NewGitLab returns a GitLab provider for the instance whose API is served from baseURL,
+// such as https://gitlab.example.com/api/v4/, authenticated with token, which may be empty
+// for anonymous access to public projects. A URL without a path has /api/v4/ added.
+func NewGitLab(baseURL, token string, opts ...Option) (GitLab, error) {
+	o := options{transport: http.DefaultTransport}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return GitLab{}, err
+	}
+	if base.Scheme == "" || base.Host == "" {
+		return GitLab{}, fmt.Errorf("API URL %q is not an absolute URL", baseURL)
+	}
+	if base.Path == "" || base.Path == "/" {
+		base.Path = "/api/v4/"
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	rt := o.transport
+	if token != "" {
+		rt = &hostTokenTransport{host: base.Host, token: token, base: rt}
+	}
+	return GitLab{
+		BaseURL: base,
+		Client:  &http.Client{Transport: rt},
+		Clock:   o.clock,
+	}, nil
+}
+
+func (g GitLab) client() *http.Client {
+	if g.Client == nil {
+		return http.DefaultClient
+	}
+	return g.Client
+}
+
+// get fetches an API path, relative to BaseURL, and decodes the JSON response into v. It
+// returns the number of the next page, or 0 on the last one.
+func (g GitLab) get(ctx context.Context, path string, query url.Values, v any) (int, error) {
+	u, err := g.BaseURL.Parse(path)
+	if err != nil {
+		return 0, err
+	}
+	u.RawQuery = query.Encode()
+	header, err := getJSON(ctx, g.client(), u, v, g.Clock)
+	if err != nil {
+		return 0, err
+	}
+	next, _ := strconv.Atoi(header.Get("X-Next-Page"))
+	return next, nil
+}
+
+// gitlabProject is the part of a GitLab project the provider reads.
+type gitlabProject struct {
+	Path      string `json:"path"`
+	Namespace struct {
+		FullPath string `json:"full_path"`
+	} `json:"namespace"`
+}
+
+func (g GitLab) ListRepos(ctx context.Context) ([]Repo, error) {
+	query := url.Values{}
+	perPage := g.PerPage
+	if perPage <= 0 || perPage > 100 {
+		perPage = 100
+	}
+	query.Set("per_page", strconv.Itoa(perPage))
+	query.Set("simple", "true")
+	switch g.Sort {
+	case "pushed":
+		query.Set("order_by", "last_activity_at")
+	case "updated":
+		query.Set("order_by", "updated_at")
+	case "created":
+		query.Set("order_by", "created_at")
+	default:
+		query.Set("order_by", "path")
+		query.Set("sort", "asc")
+	}
+	if g.Public {
+		query.Set("visibility", "public")
+	}
+
+	lists := []string{"projects"}
+	if len(g.Orgs) > 0 {
+		lists = lists[:0]
+		query.Set("include_subgroups", "true")
+		for _, group := range g.Orgs {
+			lists = append(lists, "groups/"+url.PathEscape(group)+"/projects")
+		}
+	} else if g.Public {
+		query.Set("owned", "true")
+	} else {
+		query.Set("membership", "true")
+	}
+
+	var out []Repo
+	full := func() bool { return g.MaxRepos > 0 && len(out) >= g.MaxRepos }
+	for i, list := range lists {
+		for page := 1; page != 0 && !full(); {
+			query.Set("page", strconv.Itoa(page))
+			var projects []gitlabProject
+			next, err := g.get(ctx, list, query, &projects)
+			if err != nil {
+				if len(g.Orgs) > 0 {
+					return nil, fmt.Errorf("listing projects of %s: %w", g.Orgs[i], err)
+				}
+				return nil, err
+			}
+			for _, p := range projects {
+				out = append(out, Repo{Owner: p.Namespace.FullPath, Name: p.Path})
+			}
+			page = next
+		}
+	}
+	if full() {
+		out = out[:g.MaxRepos]
+	}
+	return out, nil
+}
+
+// gitlabRelease is a release as the GitLab API returns it.
+type gitlabRelease struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	ReleasedAt  time.Time `json:"released_at"`
+	Upcoming    bool      `json:"upcoming_release"`
+	Links       struct {
+		Self string `json:"self"`
+	} `json:"_links"`
+	Assets struct {
+		Links []struct {
+			ID             int64  `json:"id"`
+			Name           string `json:"name"`
+			URL            string `json:"url"`
+			DirectAssetURL string `json:"direct_asset_url"`
+		} `json:"links"`
+	} `json:"assets"`
+}
+
+// release converts r. GitLab has no prerelease flag, so a release counts as a prerelease
+// when its tag is a semantic version with prerelease identifiers; upcoming releases, whose
+// release date is still ahead, are reported as drafts.
+func (r gitlabRelease) release() *Release {
+	out := &Release{
+		Tag:         r.TagName,
+		Name:        r.Name,
+		Body:        r.Description,
+		URL:         r.Links.Self,
+		PublishedAt: r.ReleasedAt,
+		Draft:       r.Upcoming,
+	}
+	if v, ok := ParseVersion(r.TagName); ok && v.Pre != "" {
+		out.Prerelease = true
+	}
+	for _, l := range r.Assets.Links {
+		u := l.DirectAssetURL
+		if u == "" {
+			u = l.URL
+		}
+		out.Assets = append(out.Assets, Asset{ID: l.ID, Name: l.Name, URL: u})
+	}
+	return out
+}
+
+// projectPath is the API path of the project owner/repo.
+func projectPath(owner, repo string) string {
+	return "projects/" + url.PathEscape(owner+"/"+repo)
+}
+
+func (g GitLab) ResolveRelease(ctx context.Context, owner, repo, versionPattern string) (*Release, error) {
+	list, err := g.ListReleases(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	versionPattern = strings.ToLower(versionPattern)
+	for _, r := range list {
+		if versionPattern != "" {
+			if strings.Contains(strings.ToLower(r.Tag), versionPattern) {
+				return r, nil
+			}
+			continue
+		}
+		// The latest release, as GitHub picks it
+		if !r.Draft && !r.Prerelease {
+			return r, nil
+		}
+	}
+	if versionPattern != "" {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("%w: %s/%s has no release", ErrNotFound, owner, repo)
+}
+
+// ListReleases returns the 100 most recent releases.
+func (g GitLab) ListReleases(ctx context.Context, owner, repo string) ([]*Release, error) {
+	var list []gitlabRelease
+	query := url.Values{"per_page": {"100"}, "order_by": {"released_at"}, "sort": {"desc"}}
+	if _, err := g.get(ctx, projectPath(owner, repo)+"/releases", query, &list); err != nil {
+		return nil, err
+	}
+	out := make([]*Release, 0, len(list))
+	for _, r := range list {
+		out = append(out, r.release())
+	}
+	return out, nil
+}
+
+func (g GitLab) ReleaseByTag(ctx context.Context, owner, repo, tag string) (*Release, error) {
+	var r gitlabRelease
+	if _, err := g.get(ctx, projectPath(owner, repo)+"/releases/"+url.PathEscape(tag), nil, &r); err != nil {
+		return nil, err
+	}
+	return r.release(), nil
+}
+
+func (g GitLab) DownloadAsset(ctx context.Context, c Candidate) (io.ReadCloser, error) {
+	if c.DownloadURL == "" {
+		return nil, fmt.Errorf("asset %s has no download link", c.AssetName)
+	}
+	return downloadLink(ctx, g.client(), c.DownloadURL, c.Size, g.Chunks, g.Clock)
+}
+
+// DownloadAssetFrom returns the content of the asset of c from byte offset on. It fails if
+// the server does not serve that range.
+func (g GitLab) DownloadAssetFrom(ctx context.Context, c Candidate, offset int64) (io.ReadCloser, error) {
+	return downloadLinkFrom(ctx, g.client(), c.DownloadURL, offset)
+}
@@ -0,0 +1,26 @@
+package releases
+
+import (
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// DetectLibc returns the C library of the machine it runs on, for Matcher.Libc: musl when
+// the musl dynamic loader is installed or ldd reports it, gnu on other Linux machines, and
+// "" elsewhere.
+func DetectLibc() string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+	if loaders, _ := filepath.Glob("/lib/ld-musl-*.so.1"); len(loaders) > 0 {
+		return "musl"
+	}
+	// ldd --version exits with 1 on musl, so look at its output whatever the status
+	out, _ := exec.Command("ldd", "--version").CombinedOutput()
+	if strings.Contains(strings.ToLower(string(out)), "musl") {
+		return "musl"
+	}
+	return "gnu"
+}
@@ -0,0 +1,355 @@
+package releases
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Matcher picks the asset of a release to install.
+type Matcher struct {
+	OS   string // operating system, as in runtime.GOOS
+	Arch string // architecture, as in runtime.GOARCH
+
+	// Libc is the C library of a Linux platform, musl or gnu. Among the assets the rules
+	// leave, those built for it by their name are preferred: musl or static builds for musl,
+	// and anything but musl builds for gnu. Empty means unknown.
+	Libc string
+
+	// Glob, if set, keeps only assets whose lower-cased name it matches, replacing the
+	// platform check.
+	Glob string
+
+	// MinSize is the size in bytes below which an asset is taken not to be a binary; zero
+	// means DefaultMinSize and a negative value disables the check. Assets of unknown size
+	// are kept.
+	MinSize int64
+
+	// Rules narrow the assets down further, in order. When no rule includes anything the
+	// platform check applies first: include {os}, then include {arch}.
+	Rules []Rule
+}
+
+// DefaultMinSize is the smallest asset a Matcher considers by default.
+const DefaultMinSize = 16 << 10
+
+// nonBinaryTypes are content types of release assets that are never the tool itself:
+// checksums, signatures, SBOMs and other metadata.
+var nonBinaryTypes = map[string]bool{
+	"application/json":               true,
+	"application/xml":                true,
+	"application/pgp-signature":      true,
+	"application/pgp-keys":           true,
+	"application/x-pem-file":         true,
+	"application/spdx+json":          true,
+	"application/vnd.cyclonedx+json": true,
+	"application/vnd.cyclonedx+xml":  true,
+}
+
+// Rule is one step of asset selection. Exactly one field is set. Patterns are
+// case-insensitive globs over asset names; a pattern without *, ? or [ matches any name
+// containing it, and a pattern enclosed in slashes (/-debug$/) is a regular expression.
+// {os} and {arch} are replaced by the matcher's platform; on their own they also match the
+// other spellings of it, such as x86_64 or x64 for amd64, aarch64 for arm64, armv7 or armhf
+// for arm, i386 for 386 and macos for darwin.
+type Rule struct {
+	Include string `yaml:"include,omitempty" json:"include,omitempty"` // keep only matching assets
+	Exclude string `yaml:"exclude,omitempty" json:"exclude,omitempty"` // drop matching assets
+	Prefer  string `yaml:"prefer,omitempty" json:"prefer,omitempty"`   // keep matching assets if there are any
+	Avoid   string `yaml:"avoid,omitempty" json:"avoid,omitempty"`     // drop matching assets unless all match
+}
+
+// Match returns the best ranked asset of release left after applying the matcher, or nil.
+func (m Matcher) Match(release *Release) *Asset {
+	assets := m.MatchAll(release)
+	if len(assets) == 0 {
+		return nil
+	}
+	return assets[0]
+}
+
+// MatchAll returns every asset of release left after applying the matcher, best ranked
+// first. Assets that rank equally keep their order in the release.
+func (m Matcher) MatchAll(release *Release) []*Asset {
+	return m.match(release, nil)
+}
+
+// Verdict is the matcher's decision on one asset.
+type Verdict struct {
+	Asset    *Asset
+	Selected bool   // among the best ranked assets left
+	Reason   string // why the asset was rejected, or how it was chosen
+}
+
+// Explain returns the matcher's decision on each asset of release, in release order.
+func (m Matcher) Explain(release *Release) []Verdict {
+	reasons := map[*Asset]string{}
+	assets := m.match(release, func(a *Asset, reason string) { reasons[a] = reason })
+	for _, a := range assets {
+		if rank(a.Name) != rank(assets[0].Name) {
+			reasons[a] = fmt.Sprintf("lost ranking to %s: %s ranks below %s", assets[0].Name, kind(a.Name), kind(assets[0].Name))
+		}
+	}
+	verdicts := make([]Verdict, 0, len(release.Assets))
+	for i := range release.Assets {
+		a := &release.Assets[i]
+		v := Verdict{Asset: a, Reason: reasons[a]}
+		if _, rejected := reasons[a]; !rejected {
+			v.Selected = true
+			v.Reason = "matches, as a " + kind(a.Name)
+		}
+		verdicts = append(verdicts, v)
+	}
+	return verdicts
+}
+
+// match is MatchAll, telling reject, if set, why each dropped asset was dropped.
+func (m Matcher) match(release *Release, reject func(*Asset, string)) []*Asset {
+	if reject == nil {
+		reject = func(*Asset, string) {}
+	}
+	var remaining []*Asset
+	for i := range release.Assets {
+		a := &release.Assets[i]
+		if m.Glob != "" {
+			if ok, _ := path.Match(strings.ToLower(m.Glob), strings.ToLower(a.Name)); !ok {
+				reject(a, fmt.Sprintf("does not match glob %q", m.Glob))
+				continue
+			}
+		} else if reason := m.implausible(a); reason != "" {
+			reject(a, reason)
+			continue
+		}
+		remaining = append(remaining, a)
+	}
+	for _, r := range m.rules() {
+		remaining = m.apply(r, remaining, reject)
+	}
+	slices.SortStableFunc(remaining, func(a, b *Asset) int {
+		return rank(b.Name) - rank(a.Name)
+	})
+	return remaining
+}
+
+// packageSuffixes and archiveSuffixes mark assets that need more than a download to install,
+// metadataSuffixes assets that describe another asset rather than being one, and
+// unpackableSuffixes assets compressed in a way Extract cannot undo.
+var (
+	packageSuffixes    = []string{".deb", ".rpm", ".apk", ".msi", ".pkg", ".dmg"}
+	archiveSuffixes    = []string{".tar.gz", ".tgz", ".tar.bz2", ".tbz2", ".zip", ".gz", ".bz2"}
+	metadataSuffixes   = []string{".sha256", ".sha256sum", ".sha512", ".sha1", ".md5", ".sig", ".asc", ".pem", ".crt", ".sigstore", ".sigstore.json", ".intoto.jsonl", ".sbom", ".txt", ".json"}
+	unpackableSuffixes = []string{".xz", ".txz", ".zst", ".tzst"}
+)
+
+// rank scores an asset name: a bare executable installs as is, an archive has to be
+// unpacked, and a system package is the last resort.
+func rank(name string) int {
+	name = strings.ToLower(name)
+	hasSuffix := func(suffixes []string) bool {
+		return slices.ContainsFunc(suffixes, func(s string) bool { return strings.HasSuffix(name, s) })
+	}
+	switch {
+	case hasSuffix(packageSuffixes):
+		return 0
+	case hasSuffix(archiveSuffixes):
+		return 1
+	}
+	return 2
+}
+
+// kind names the rank of an asset name.
+func kind(name string) string {
+	return [...]string{"system package", "archive", "bare binary"}[rank(name)]
+}
+
+// IsArchive reports whether an asset name is that of an archive or a compressed file.
+func IsArchive(name string) bool {
+	return rank(name) == 1
+}
+
+// implausible returns why the metadata of an asset rules out that it is a binary, or "".
+// It is skipped when a glob names the assets explicitly.
+func (m Matcher) implausible(a *Asset) string {
+	ct, _, _ := strings.Cut(strings.ToLower(a.ContentType), ";")
+	ct = strings.TrimSpace(ct)
+	if strings.HasPrefix(ct, "text/") || nonBinaryTypes[ct] {
+		return fmt.Sprintf("content type %s is not a binary", ct)
+	}
+	name := strings.ToLower(a.Name)
+	for _, suffix := range metadataSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return fmt.Sprintf("a %s file describes another asset", suffix)
+		}
+	}
+	for _, suffix := range unpackableSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return fmt.Sprintf("a %s file cannot be unpacked", suffix)
+		}
+	}
+	minSize := m.MinSize
+	if minSize == 0 {
+		minSize = DefaultMinSize
+	}
+	if a.Size != 0 && minSize >= 0 && a.Size < minSize {
+		return fmt.Sprintf("%d bytes is below the minimum size of %d", a.Size, minSize)
+	}
+	return ""
+}
+
+// rules returns the full rule list, with the platform check or glob in front.
+func (m Matcher) rules() []Rule {
+	var rules []Rule
+	if m.Glob == "" && !hasInclude(m.Rules) {
+		rules = append(rules, Rule{Include: "{os}"}, Rule{Include: "{arch}"})
+	}
+	rules = append(rules, m.Rules...)
+	// The platform only breaks ties left by the rules given
+	switch m.OS {
+	case "linux":
+		switch m.Libc {
+		case "musl":
+			rules = append(rules, Rule{Prefer: "/musl|static/"})
+		case "gnu":
+			rules = append(rules, Rule{Avoid: "musl"})
+		}
+	case "darwin":
+		rules = append(rules, Rule{Avoid: universalPattern})
+	case "windows":
+		rules = append(rules, Rule{Prefer: `/\.(exe|zip)$/`})
+	}
+	return rules
+}
+
+// apply narrows assets by one rule, telling reject why each dropped asset was dropped.
+func (m Matcher) apply(r Rule, assets []*Asset, reject func(*Asset, string)) []*Asset {
+	var kept, dropped []*Asset
+	for _, a := range assets {
+		switch {
+		case r.Include != "" && !m.matches(r.Include, a.Name),
+			r.Exclude != "" && m.matches(r.Exclude, a.Name),
+			r.Prefer != "" && !m.matches(r.Prefer, a.Name),
+			r.Avoid != "" && m.matches(r.Avoid, a.Name):
+			dropped = append(dropped, a)
+		default:
+			kept = append(kept, a)
+		}
+	}
+	if (r.Prefer != "" || r.Avoid != "") && len(kept) == 0 {
+		return assets
+	}
+	for _, a := range dropped {
+		switch {
+		case r.Include != "":
+			reject(a, fmt.Sprintf("does not match include %s", m.describe(r.Include)))
+		case r.Exclude != "":
+			reject(a, fmt.Sprintf("matches exclude %s", m.describe(r.Exclude)))
+		case r.Avoid != "":
+			reject(a, fmt.Sprintf("matches avoid %s and other assets do not", m.describe(r.Avoid)))
+		default:
+			reject(a, fmt.Sprintf("other assets match prefer %s", m.describe(r.Prefer)))
+		}
+	}
+	return kept
+}
+
+// describe shows a rule pattern together with its platform expansion, if any.
+func (m Matcher) describe(pattern string) string {
+	expanded := strings.NewReplacer("{os}", m.OS, "{arch}", m.Arch).Replace(pattern)
+	if expanded == pattern {
+		return fmt.Sprintf("%q", pattern)
+	}
+	return fmt.Sprintf("%q (%s)", pattern, expanded)
+}
+
+// Validate reports a malformed pattern in the rule.
+func (r Rule) Validate() error {
+	set := 0
+	for _, p := range []string{r.Include, r.Exclude, r.Prefer, r.Avoid} {
+		if p == "" {
+			continue
+		}
+		set++
+		if re, ok := regexpPattern(p); ok {
+			if _, err := regexp.Compile(re); err != nil {
+				return fmt.Errorf("bad regular expression %q: %w", p, err)
+			}
+		} else if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("bad pattern %q: %w", p, err)
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("a rule needs exactly one of include, exclude, prefer or avoid")
+	}
+	return nil
+}
+
+// regexpPattern returns the expression of a /regexp/ pattern.
+func regexpPattern(p string) (string, bool) {
+	if len(p) > 2 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+		return p[1 : len(p)-1], true
+	}
+	return "", false
+}
+
+// matches reports whether an asset name matches a rule pattern.
+func (m Matcher) matches(pattern, name string) bool {
+	switch pattern {
+	case "{os}":
+		return nameHasOS(name, m.OS)
+	case "{arch}":
+		// Universal macOS binaries run on every architecture
+		return nameHasArch(name, m.Arch) || m.OS == "darwin" && isUniversal(name)
+	}
+	pattern = strings.NewReplacer("{os}", m.OS, "{arch}", m.Arch).Replace(pattern)
+	if expr, ok := regexpPattern(pattern); ok {
+		re, err := regexp.Compile("(?i)" + expr)
+		return err == nil && re.MatchString(name)
+	}
+	pattern = strings.ToLower(pattern)
+	name = strings.ToLower(name)
+	if !strings.ContainsAny(pattern, "*?[") {
+		return strings.Contains(name, pattern)
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// universalPattern matches the names of universal macOS binaries, as published by lipo
+// or GoReleaser (darwin_all).
+const universalPattern = `/universal|[._-]all([._-]|$)/`
+
+var universalName = regexp.MustCompile("(?i)" + universalPattern[1:len(universalPattern)-1])
+
+// isUniversal reports whether an asset name is that of a universal macOS binary.
+func isUniversal(name string) bool {
+	return universalName.MatchString(name)
+}
+
+// hasInclude reports whether any rule is an include rule.
+func hasInclude(rules []Rule) bool {
+	for _, r := range rules {
+		if r.Include != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSBOM reports whether a is a software bill of materials, by content type or by the
+// SPDX and CycloneDX naming conventions.
+func IsSBOM(a Asset) bool {
+	ct, _, _ := strings.Cut(strings.ToLower(a.ContentType), ";")
+	switch strings.TrimSpace(ct) {
+	case "application/spdx+json", "application/vnd.cyclonedx+json", "application/vnd.cyclonedx+xml":
+		return true
+	}
+	name := strings.ToLower(a.Name)
+	for _, s := range []string{".spdx", ".spdx.json", ".cdx.json", ".cdx.xml", ".cyclonedx.json", ".cyclonedx.xml"} {
+		if strings.HasSuffix(name, s) {
+			return true
+		}
+	}
+	return strings.Contains(name, "sbom")
+}
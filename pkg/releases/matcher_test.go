@@ -0,0 +1,164 @@
+package releases
+
+import (
+	"slices"
+	"testing"
+)
+
+// release returns a release with assets of the given names and unknown sizes.
+func release(names ...string) *Release {
+	r := &Release{Tag: "v1.0.0"}
+	for i, name := range names {
+		r.Assets = append(r.Assets, Asset{ID: int64(i + 1), Name: name})
+	}
+	return r
+}
+
+func TestMatcherRanking(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       Matcher
+		release *Release
+		want    []string
+	}{
+		{
+			name:    "bare binary before archive before package",
+			m:       Matcher{OS: "linux", Arch: "amd64"},
+			release: release("tool_linux_amd64.deb", "tool_linux_amd64.tar.gz", "tool_linux_amd64"),
+			want:    []string{"tool_linux_amd64", "tool_linux_amd64.tar.gz", "tool_linux_amd64.deb"},
+		},
+		{
+			name:    "equal ranks keep release order",
+			m:       Matcher{OS: "linux", Arch: "amd64"},
+			release: release("tool-linux-x86_64.zip", "tool_linux_amd64.tar.gz"),
+			want:    []string{"tool-linux-x86_64.zip", "tool_linux_amd64.tar.gz"},
+		},
+		{
+			name:    "other platforms are dropped",
+			m:       Matcher{OS: "linux", Arch: "arm64"},
+			release: release("tool_linux_amd64", "tool_darwin_arm64", "tool_linux_aarch64"),
+			want:    []string{"tool_linux_aarch64"},
+		},
+		{
+			name:    "xz and zstd compressed assets are dropped",
+			m:       Matcher{OS: "linux", Arch: "amd64"},
+			release: release("tool_linux_amd64.tar.xz", "tool_linux_amd64.tar.zst", "tool_linux_amd64.txz", "tool_linux_amd64.tar.gz"),
+			want:    []string{"tool_linux_amd64.tar.gz"},
+		},
+		{
+			name:    "checksums and signatures are dropped",
+			m:       Matcher{OS: "linux", Arch: "amd64"},
+			release: release("tool_linux_amd64.tar.gz.sha256", "tool_linux_amd64.tar.gz.sha256sum", "tool_linux_amd64.tar.gz.sig", "tool_linux_amd64.tar.gz.sigstore.json", "tool_linux_amd64.tar.gz"),
+			want:    []string{"tool_linux_amd64.tar.gz"},
+		},
+		{
+			name:    "musl builds for musl",
+			m:       Matcher{OS: "linux", Arch: "amd64", Libc: "musl"},
+			release: release("tool_linux_amd64_gnu.tar.gz", "tool_linux_amd64_musl.tar.gz"),
+			want:    []string{"tool_linux_amd64_musl.tar.gz"},
+		},
+		{
+			name:    "static builds for musl",
+			m:       Matcher{OS: "linux", Arch: "amd64", Libc: "musl"},
+			release: release("tool_linux_amd64_gnu.tar.gz", "tool_linux_amd64_static.tar.gz"),
+			want:    []string{"tool_linux_amd64_static.tar.gz"},
+		},
+		{
+			name:    "anything but musl for gnu",
+			m:       Matcher{OS: "linux", Arch: "amd64", Libc: "gnu"},
+			release: release("tool_linux_amd64_musl.tar.gz", "tool_linux_amd64.tar.gz"),
+			want:    []string{"tool_linux_amd64.tar.gz"},
+		},
+		{
+			name:    "musl only, for gnu",
+			m:       Matcher{OS: "linux", Arch: "amd64", Libc: "gnu"},
+			release: release("tool_linux_amd64_musl.tar.gz"),
+			want:    []string{"tool_linux_amd64_musl.tar.gz"},
+		},
+		{
+			name:    "no preference",
+			m:       Matcher{OS: "linux", Arch: "amd64"},
+			release: release("tool_linux_amd64_musl.tar.gz", "tool_linux_amd64_gnu.tar.gz"),
+			want:    []string{"tool_linux_amd64_musl.tar.gz", "tool_linux_amd64_gnu.tar.gz"},
+		},
+		{
+			name:    "universal macOS binary only when nothing else fits",
+			m:       Matcher{OS: "darwin", Arch: "arm64"},
+			release: release("tool_darwin_all.tar.gz", "tool_darwin_arm64.tar.gz"),
+			want:    []string{"tool_darwin_arm64.tar.gz"},
+		},
+		{
+			name:    "universal macOS binary matches any architecture",
+			m:       Matcher{OS: "darwin", Arch: "arm64"},
+			release: release("tool_linux_arm64", "tool_macos_universal.zip"),
+			want:    []string{"tool_macos_universal.zip"},
+		},
+		{
+			name:    "windows executables and zips before installers",
+			m:       Matcher{OS: "windows", Arch: "amd64"},
+			release: release("tool_windows_amd64.msi", "tool_windows_amd64.zip"),
+			want:    []string{"tool_windows_amd64.zip"},
+		},
+		{
+			name:    "glob replaces the platform check",
+			m:       Matcher{OS: "linux", Arch: "amd64", Glob: "*windows*"},
+			release: release("tool_linux_amd64", "tool_windows_amd64.exe"),
+			want:    []string{"tool_windows_amd64.exe"},
+		},
+		{
+			name:    "rules run after the platform check",
+			m:       Matcher{OS: "linux", Arch: "amd64", Rules: []Rule{{Exclude: "debug"}, {Prefer: "/full/"}}},
+			release: release("tool_linux_amd64_debug", "tool_linux_amd64", "tool_full_linux_amd64"),
+			want:    []string{"tool_full_linux_amd64"},
+		},
+		{
+			name:    "avoid drops matching assets unless all match",
+			m:       Matcher{OS: "linux", Arch: "amd64", Rules: []Rule{{Avoid: "debug"}}},
+			release: release("tool_linux_amd64_debug", "tool_linux_amd64"),
+			want:    []string{"tool_linux_amd64"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, a := range tt.m.MatchAll(tt.release) {
+				got = append(got, a.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcherMinSize(t *testing.T) {
+	r := &Release{Assets: []Asset{
+		{Name: "tool_linux_amd64.tar.gz", Size: 1 << 10},
+		{Name: "tool_linux_amd64", Size: 4 << 20},
+	}}
+	if a := (Matcher{OS: "linux", Arch: "amd64"}).Match(r); a == nil || a.Name != "tool_linux_amd64" {
+		t.Errorf("got %v, want the asset above the minimum size", a)
+	}
+	if got := len((Matcher{OS: "linux", Arch: "amd64", MinSize: -1}).MatchAll(r)); got != 2 {
+		t.Errorf("got %d assets with the size check disabled, want 2", got)
+	}
+}
+
+func TestIsArchive(t *testing.T) {
+	tests := map[string]bool{
+		"tool.tar.gz":  true,
+		"tool.TGZ":     true,
+		"tool.zip":     true,
+		"tool.tar.bz2": true,
+		"tool.tar.xz":  false,
+		"tool.tar.zst": false,
+		"tool":         false,
+		"tool.exe":     false,
+		"tool.deb":     false,
+	}
+	for name, want := range tests {
+		if got := IsArchive(name); got != want {
+			t.Errorf("IsArchive(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package releases
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+	"golang.org/x/oauth2"
+)
+
+// Clock supplies the current time. Tests can substitute a fixed clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// Option configures a provider built by a constructor such as NewGitHub.
+type Option func(*options)
+
+type options struct {
+	transport http.RoundTripper
+	clock     Clock
+}
+
+// WithTransport sends every request through rt instead of http.DefaultTransport, letting
+// embedders add caching, retries or recorded responses. Credentials are added before rt sees
+// the request.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(o *options) { o.transport = rt }
+}
+
+// WithClock makes the provider read the time from c instead of the system clock.
+func WithClock(c Clock) Option {
+	return func(o *options) { o.clock = c }
+}
+
+// NewGitHub returns a GitHub provider for github.com authenticated with token, which may be
+// empty for anonymous access to public repositories.
+func NewGitHub(token string, opts ...Option) GitHub {
+	o := options{transport: http.DefaultTransport}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	rt := o.transport
+	if token != "" {
+		rt = &oauth2.Transport{
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}),
+			Base:   rt,
+		}
+	}
+	// Asset storage gets the bare transport: its URLs are pre-signed and must not see the token
+	return GitHub{
+		Client:     github.NewClient(&http.Client{Transport: rt}),
+		HTTPClient: &http.Client{Transport: o.transport},
+		Clock:      o.clock,
+	}
+}
+
+// NewGitHubEnterprise is NewGitHub for a GitHub Enterprise Server whose API is served from
+// baseURL, such as https://github.example.com/api/v3/, and uploads from uploadURL. A URL
+// without the /api/v3/ or /api/uploads/ path has it added; an empty uploadURL means the
+// host of baseURL.
+func NewGitHubEnterprise(baseURL, uploadURL, token string, opts ...Option) (GitHub, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return GitHub{}, err
+	}
+	if base.Scheme == "" || base.Host == "" {
+		return GitHub{}, fmt.Errorf("API URL %q is not an absolute URL", baseURL)
+	}
+	if uploadURL == "" {
+		uploadURL = base.Scheme + "://" + base.Host + "/"
+	}
+	g := NewGitHub(token, opts...)
+	if g.Client, err = g.Client.WithEnterpriseURLs(baseURL, uploadURL); err != nil {
+		return GitHub{}, err
+	}
+	return g, nil
+}
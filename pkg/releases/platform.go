@@ -0,0 +1,95 @@
+package releases
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// osNames and archNames map the spellings found in asset names to GOOS and GOARCH values.
+var (
+	osNames = map[string]string{
+		"linux":   "linux",
+		"darwin":  "darwin",
+		"macos":   "darwin",
+		"osx":     "darwin",
+		"apple":   "darwin",
+		"windows": "windows",
+		"win":     "windows",
+		"win64":   "windows",
+		"win32":   "windows",
+		"freebsd": "freebsd",
+		"openbsd": "openbsd",
+		"netbsd":  "netbsd",
+	}
+	archNames = map[string]string{
+		"amd64":   "amd64",
+		"x86_64":  "amd64",
+		"x64":     "amd64",
+		"arm64":   "arm64",
+		"aarch64": "arm64",
+		"386":     "386",
+		"i386":    "386",
+		"i686":    "386",
+		"x86":     "386",
+		"armv7":   "arm",
+		"armv7l":  "arm",
+		"armv6":   "arm",
+		"armv6l":  "arm",
+		"armhf":   "arm",
+		"armel":   "arm",
+		"arm":     "arm",
+		"riscv64": "riscv64",
+		"loong64": "loong64",
+		"ppc64le": "ppc64le",
+		"s390x":   "s390x",
+	}
+)
+
+// DetectPlatform guesses the operating system and architecture an asset is built for from
+// its name, as GOOS and GOARCH values. Either is empty if the name does not say.
+func DetectPlatform(name string) (goos, goarch string) {
+	oses, arches := platformWords(name)
+	if len(oses) > 0 {
+		goos = oses[0]
+	}
+	if len(arches) > 0 {
+		goarch = arches[0]
+	}
+	return goos, goarch
+}
+
+// platformWords returns the GOOS and GOARCH values of the operating systems and
+// architectures the words of an asset name spell, in order.
+func platformWords(name string) (oses, arches []string) {
+	name = strings.ToLower(name)
+	// x86_64 contains the separator, so replace it before splitting: x86 alone is 386
+	name = strings.NewReplacer("x86_64", " amd64 ", "x86-64", " amd64 ").Replace(name)
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		if goos, ok := osNames[w]; ok {
+			oses = append(oses, goos)
+		}
+		if goarch, ok := archNames[w]; ok {
+			arches = append(arches, goarch)
+		}
+	}
+	return oses, arches
+}
+
+// nameHasOS reports whether an asset name spells goos, as a word or an alias such as macos
+// for darwin, or contains it.
+func nameHasOS(name, goos string) bool {
+	oses, _ := platformWords(name)
+	return slices.Contains(oses, goos) || strings.Contains(strings.ToLower(name), goos)
+}
+
+// nameHasArch reports whether an asset name spells goarch, as a word or an alias such as
+// x86_64 for amd64 or armv7 for arm, or contains it. arm is only accepted as a word, since
+// it is part of arm64.
+func nameHasArch(name, goarch string) bool {
+	_, arches := platformWords(name)
+	return slices.Contains(arches, goarch) || goarch != "arm" && strings.Contains(strings.ToLower(name), goarch)
+}
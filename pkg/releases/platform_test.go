@@ -0,0 +1,43 @@
+package releases
+
+import "testing"
+
+func TestDetectPlatform(t *testing.T) {
+	tests := []struct {
+		name, goos, goarch string
+	}{
+		{"tool_linux_amd64.tar.gz", "linux", "amd64"},
+		{"tool-x86_64-unknown-linux-musl.tar.gz", "linux", "amd64"},
+		{"tool-macos-aarch64.zip", "darwin", "arm64"},
+		{"tool_Windows_x64.zip", "windows", "amd64"},
+		{"tool_linux_armv7.tar.gz", "linux", "arm"},
+		{"tool-linux-i686", "linux", "386"},
+		{"tool_linux_riscv64", "linux", "riscv64"},
+		{"tool.tar.gz", "", ""},
+	}
+	for _, tt := range tests {
+		if goos, goarch := DetectPlatform(tt.name); goos != tt.goos || goarch != tt.goarch {
+			t.Errorf("DetectPlatform(%q) = %s, %s, want %s, %s", tt.name, goos, goarch, tt.goos, tt.goarch)
+		}
+	}
+}
+
+func TestNameHasArch(t *testing.T) {
+	tests := []struct {
+		name, goarch string
+		want         bool
+	}{
+		{"tool_linux_x86_64", "amd64", true},
+		{"tool_linux_x86", "386", true},
+		{"tool_linux_x86_64", "386", false},
+		{"tool_linux_armhf", "arm", true},
+		{"tool_linux_arm", "arm", true},
+		{"tool_linux_arm64", "arm", false},
+		{"tool_linux_aarch64", "arm64", true},
+	}
+	for _, tt := range tests {
+		if got := nameHasArch(tt.name, tt.goarch); got != tt.want {
+			t.Errorf("nameHasArch(%q, %s) = %v, want %v", tt.name, tt.goarch, got, tt.want)
+		}
+	}
+}
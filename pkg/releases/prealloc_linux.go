@@ -0,0 +1,12 @@
+package releases
+
+import (
+	"os"
+	"syscall"
+)
+
+// preallocate reserves size bytes for f so that a large download is laid out contiguously.
+// It is only a hint: filesystems without fallocate support are left alone.
+func preallocate(f *os.File, size int64) {
+	syscall.Fallocate(int(f.Fd()), 0, 0, size)
+}
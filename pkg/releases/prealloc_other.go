@@ -0,0 +1,8 @@
+//go:build !linux
+
+package releases
+
+import "os"
+
+// preallocate is a no-op where fallocate is not available.
+func preallocate(f *os.File, size int64) {}
@@ -0,0 +1,59 @@
+package releases
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// ReleaseProvider is a forge that publishes releases. Finder, Matcher and Downloader only
+// talk to a forge through this interface, so adding a backend means implementing it.
+type ReleaseProvider interface {
+	// ListRepos returns every repository searched by Finder.Find.
+	ListRepos(ctx context.Context) ([]Repo, error)
+	// ResolveRelease returns the latest release of owner/repo, or, when versionPattern is
+	// set, the first release whose tag contains it (case-insensitively). A nil release with a
+	// nil error means nothing matched.
+	ResolveRelease(ctx context.Context, owner, repo, versionPattern string) (*Release, error)
+	// ListReleases returns the recent releases of owner/repo, newest first, including
+	// drafts and prereleases.
+	ListReleases(ctx context.Context, owner, repo string) ([]*Release, error)
+	// ReleaseByTag returns the release of owner/repo with exactly the given tag.
+	ReleaseByTag(ctx context.Context, owner, repo, tag string) (*Release, error)
+	// DownloadAsset returns the content of the asset of c.
+	DownloadAsset(ctx context.Context, c Candidate) (io.ReadCloser, error)
+}
+
+// Resumer is implemented by providers that can continue an interrupted download.
+type Resumer interface {
+	// DownloadAssetFrom returns the content of the asset of c from byte offset on.
+	DownloadAssetFrom(ctx context.Context, c Candidate, offset int64) (io.ReadCloser, error)
+}
+
+// Repo identifies a repository.
+type Repo struct {
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+}
+
+// Release is a published release of a repository.
+type Release struct {
+	Tag         string    `json:"tag"`
+	Name        string    `json:"name,omitempty"`
+	Body        string    `json:"body,omitempty"` // release notes, usually Markdown
+	URL         string    `json:"url,omitempty"`  // web page of the release
+	PublishedAt time.Time `json:"published_at"`
+	Prerelease  bool      `json:"prerelease,omitempty"`
+	Draft       bool      `json:"draft,omitempty"`
+	Assets      []Asset   `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	URL         string `json:"url,omitempty"` // browser download URL
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+	Downloads   int    `json:"downloads,omitempty"` // download count, where the forge reports it
+}
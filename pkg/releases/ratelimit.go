@@ -0,0 +1,81 @@
+package releases
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// rateLimitAttempts is how many times RateLimitRetry retries one call.
+const rateLimitAttempts = 5
+
+// RateLimitRetry wraps a ReleaseProvider so that calls refused by a rate limit wait for the
+// limit to lift and are tried again, instead of failing. Waits honour the time GitHub gives,
+// from the rate limit reset or a Retry-After header, and grow exponentially from a second
+// when it gives none, as for some secondary rate limits.
+type RateLimitRetry struct {
+	ReleaseProvider
+	// MaxWait is the longest a call waits at a time. Limits that last longer, such as an
+	// exhausted hourly quota, fail at once. Zero disables retrying.
+	MaxWait time.Duration
+	// Waiting, if set, is called before each wait.
+	Waiting func(err *RateLimitError, wait time.Duration)
+}
+
+// retry calls call until it succeeds, fails with something other than a rate limit, or
+// the limit cannot be waited out.
+func retry[T any](ctx context.Context, p RateLimitRetry, call func() (T, error)) (T, error) {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		v, err := call()
+		var rle *RateLimitError
+		if err == nil || !errors.As(err, &rle) || attempt > rateLimitAttempts {
+			return v, err
+		}
+		wait := max(rle.RetryAfter, backoff)
+		if wait > p.MaxWait {
+			return v, err
+		}
+		if p.Waiting != nil {
+			p.Waiting(rle, wait)
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return v, err
+		case <-t.C:
+		}
+		backoff *= 2
+	}
+}
+
+func (p RateLimitRetry) ListRepos(ctx context.Context) ([]Repo, error) {
+	return retry(ctx, p, func() ([]Repo, error) { return p.ReleaseProvider.ListRepos(ctx) })
+}
+
+func (p RateLimitRetry) ResolveRelease(ctx context.Context, owner, repo, versionPattern string) (*Release, error) {
+	return retry(ctx, p, func() (*Release, error) { return p.ReleaseProvider.ResolveRelease(ctx, owner, repo, versionPattern) })
+}
+
+func (p RateLimitRetry) ListReleases(ctx context.Context, owner, repo string) ([]*Release, error) {
+	return retry(ctx, p, func() ([]*Release, error) { return p.ReleaseProvider.ListReleases(ctx, owner, repo) })
+}
+
+func (p RateLimitRetry) ReleaseByTag(ctx context.Context, owner, repo, tag string) (*Release, error) {
+	return retry(ctx, p, func() (*Release, error) { return p.ReleaseProvider.ReleaseByTag(ctx, owner, repo, tag) })
+}
+
+func (p RateLimitRetry) DownloadAsset(ctx context.Context, c Candidate) (io.ReadCloser, error) {
+	return retry(ctx, p, func() (io.ReadCloser, error) { return p.ReleaseProvider.DownloadAsset(ctx, c) })
+}
+
+// DownloadAssetFrom retries a resumed download when the wrapped provider is a Resumer.
+func (p RateLimitRetry) DownloadAssetFrom(ctx context.Context, c Candidate, offset int64) (io.ReadCloser, error) {
+	r, ok := p.ReleaseProvider.(Resumer)
+	if !ok {
+		return nil, errors.New("source cannot resume downloads")
+	}
+	return retry(ctx, p, func() (io.ReadCloser, error) { return r.DownloadAssetFrom(ctx, c, offset) })
+}
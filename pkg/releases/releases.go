@@ -0,0 +1,40 @@
+// Package releases finds release assets built for a platform, downloads them and
+// verifies their contents. It is the core of the get_gh_release command and can be embedded
+// by other programs:
+//
+//	gh := releases.NewGitHub(os.Getenv("GH_TOKEN"))
+//	f := releases.Finder{Provider: gh, Matcher: releases.Matcher{OS: "linux", Arch: "amd64"}}
+//	candidates, err := f.Find(ctx, "fzf", "", nil)
+//	...
+//	digest, err := releases.Downloader{Provider: gh}.Install(ctx, candidates[0], "/usr/local/bin/fzf")
+//
+// Forges other than GitHub plug in by implementing ReleaseProvider.
+package releases
+
+import "time"
+
+// Candidate holds information about a downloadable release asset.
+type Candidate struct {
+	RepoOwner   string    `json:"repo_owner"`
+	RepoName    string    `json:"repo_name"`
+	AssetName   string    `json:"asset_name"`
+	DownloadURL string    `json:"download_url,omitempty"`
+	AssetID     int64     `json:"asset_id"`
+	Size        int64     `json:"size,omitempty"` // asset size in bytes, 0 if unknown
+	Tag         string    `json:"tag"`
+	Published   time.Time `json:"published,omitzero"` // when the release was published
+}
+
+// NewCandidate builds a Candidate for an asset of the given release.
+func NewCandidate(owner, repo string, release *Release, asset Asset) Candidate {
+	return Candidate{
+		RepoOwner:   owner,
+		RepoName:    repo,
+		AssetName:   asset.Name,
+		DownloadURL: asset.URL,
+		AssetID:     asset.ID,
+		Size:        asset.Size,
+		Tag:         release.Tag,
+		Published:   release.PublishedAt,
+	}
+}
@@ -0,0 +1,144 @@
+package releases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Helpers for the forges whose REST APIs are called directly rather than through a client
+// library.
+
+// now returns the time of clock, or of the system clock when it is nil.
+func now(clock Clock) time.Time {
+	if clock != nil {
+		return clock.Now()
+	}
+	return time.Now()
+}
+
+// getJSON fetches u and decodes the JSON response into v. It returns the response headers,
+// which carry pagination.
+func getJSON(ctx context.Context, client *http.Client, u *url.URL, v any, clock Clock) (http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, restError(resp, now(clock))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return nil, fmt.Errorf("GET %s: bad response: %w", u.Redacted(), err)
+	}
+	return resp.Header, nil
+}
+
+// restError tags a failed API response with the sentinel describing its cause; now is the
+// time the response arrived.
+func restError(resp *http.Response, now time.Time) error {
+	var body struct {
+		Message any    `json:"message"`
+		Error   string `json:"error"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+	msg := resp.Status
+	if body.Message != nil && body.Message != "" {
+		msg += ": " + fmt.Sprint(body.Message)
+	} else if body.Error != "" {
+		msg += ": " + body.Error
+	}
+	err := fmt.Errorf("%s %s: %s", resp.Request.Method, resp.Request.URL.Redacted(), msg)
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		wait := time.Minute
+		if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			wait = time.Duration(s) * time.Second
+		} else if reset, err := strconv.ParseInt(resp.Header.Get("RateLimit-Reset"), 10, 64); err == nil {
+			wait = max(time.Unix(reset, 0).Sub(now), 0)
+		}
+		return &RateLimitError{Reset: now.Add(wait), RetryAfter: wait, Err: err}
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: %w", ErrTokenScope, err)
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	return err
+}
+
+// hostTokenTransport authenticates the requests for one host with a bearer token, which
+// GitLab and Gitea accept for their access tokens. Requests for other hosts, such as asset
+// storage, go out without it.
+type hostTokenTransport struct {
+	host  string
+	token string
+	base  http.RoundTripper
+}
+
+func (t *hostTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(req)
+}
+
+// openAsset requests the asset at link, or the given range of it when byteRange is set.
+func openAsset(ctx context.Context, client *http.Client, link, byteRange string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/octet-stream")
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not download asset content: %w", err)
+	}
+	return resp, nil
+}
+
+// downloadLink returns the content of the asset at link, in parallel ranges when it is
+// large and chunks is 2 or more.
+func downloadLink(ctx context.Context, client *http.Client, link string, size int64, chunks int, clock Clock) (io.ReadCloser, error) {
+	if chunks > 1 && size >= ChunkedMinSize && supportsRanges(ctx, client, link, size) {
+		return openChunked(ctx, client, link, size, chunks), nil
+	}
+	resp, err := openAsset(ctx, client, link, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("could not download asset content: %w", restError(resp, now(clock)))
+	}
+	return resp.Body, nil
+}
+
+// downloadLinkFrom returns the content of the asset at link from byte offset on. It fails
+// if the server does not serve that range.
+func downloadLinkFrom(ctx context.Context, client *http.Client, link string, offset int64) (io.ReadCloser, error) {
+	resp, err := openAsset(ctx, client, link, fmt.Sprintf("bytes=%d-", offset))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusPartialContent || !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("server returned %s to a range request", resp.Status)
+	}
+	return resp.Body, nil
+}
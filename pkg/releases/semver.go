@@ -0,0 +1,265 @@
+package releases
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version parsed from a release tag.
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string // prerelease identifiers, e.g. "rc.1"; empty for a release
+}
+
+// ParseVersion reads a semantic version from a tag. Anything before the first digit, such
+// as a "v" or "release-" prefix, is ignored, as is build metadata after a "+". Missing minor
+// and patch numbers count as zero, so "v2" is 2.0.0.
+func ParseVersion(tag string) (Version, bool) {
+	i := strings.IndexFunc(tag, isDigit)
+	if i < 0 {
+		return Version{}, false
+	}
+	v, _, ok := parsePartial(tag[i:])
+	return v, ok
+}
+
+func isDigit(r rune) bool { return r >= '0' && r <= '9' }
+
+// parsePartial parses a version of one to three numbers and returns how many were given.
+func parsePartial(s string) (Version, int, bool) {
+	s, _, _ = strings.Cut(s, "+")
+	s, pre, _ := strings.Cut(s, "-")
+	var v Version
+	v.Pre = pre
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return Version{}, 0, false
+	}
+	fields := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return Version{}, 0, false
+		}
+		*fields[i] = n
+	}
+	return v, len(parts), true
+}
+
+// Compare orders versions by precedence as semantic versioning defines it: a prerelease
+// comes before the release it leads up to.
+func (v Version) Compare(w Version) int {
+	if c := cmp.Compare(v.Major, w.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Minor, w.Minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Patch, w.Patch); c != 0 {
+		return c
+	}
+	switch {
+	case v.Pre == w.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case w.Pre == "":
+		return -1
+	}
+	a, b := strings.Split(v.Pre, "."), strings.Split(w.Pre, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		x, errX := strconv.Atoi(a[i])
+		y, errY := strconv.Atoi(b[i])
+		var c int
+		switch {
+		case errX == nil && errY == nil:
+			c = cmp.Compare(x, y)
+		case errX == nil:
+			c = -1
+		case errY == nil:
+			c = 1
+		default:
+			c = strings.Compare(a[i], b[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// comparator is one bound of a constraint, e.g. ">= 1.2.0".
+type comparator struct {
+	op string // one of = < <= > >=
+	v  Version
+}
+
+func (c comparator) check(v Version) bool {
+	n := v.Compare(c.v)
+	switch c.op {
+	case "<":
+		return n < 0
+	case "<=":
+		return n <= 0
+	case ">":
+		return n > 0
+	case ">=":
+		return n >= 0
+	}
+	return n == 0
+}
+
+// Constraint is a semantic version range such as "^1.4", "~1.2.3" or ">=2.0.0 <3". Bounds
+// separated by spaces or commas must all hold; ranges separated by "||" are alternatives.
+type Constraint [][]comparator
+
+// IsConstraint reports whether a version pattern is a constraint rather than a substring of
+// the tag, which is the case when it starts with one of ^ ~ = < >, or is a version ending in
+// wildcards such as v2.40.x or 1.*.
+func IsConstraint(pattern string) bool {
+	p := strings.TrimSpace(pattern)
+	return p != "" && strings.ContainsRune("^~=<>", rune(p[0])) || xRange.MatchString(p)
+}
+
+// xRange matches a version whose last numbers are wildcards.
+var xRange = regexp.MustCompile(`^v?\d+(\.\d+)?(\.[xX*])+$`)
+
+// Check reports whether v satisfies the constraint.
+func (c Constraint) Check(v Version) bool {
+	for _, set := range c {
+		if !slices.ContainsFunc(set, func(b comparator) bool { return !b.check(v) }) {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the releases of list whose tags satisfy the constraint, highest version
+// first. Prereleases, whether flagged on the release or in the tag, are left out unless pre
+// is set, and drafts unless drafts is.
+func (c Constraint) Select(list []*Release, pre, drafts bool) []*Release {
+	type versioned struct {
+		r *Release
+		v Version
+	}
+	var matched []versioned
+	for _, r := range list {
+		v, ok := ParseVersion(r.Tag)
+		if !ok || r.Draft && !drafts || !pre && (r.Prerelease || v.Pre != "") || !c.Check(v) {
+			continue
+		}
+		matched = append(matched, versioned{r, v})
+	}
+	slices.SortStableFunc(matched, func(a, b versioned) int { return b.v.Compare(a.v) })
+	out := make([]*Release, len(matched))
+	for i, m := range matched {
+		out[i] = m.r
+	}
+	return out
+}
+
+// MatchVersion reports whether tag is selected by a version pattern: a constraint, or
+// otherwise a case-insensitive substring of the tag. An empty pattern matches every tag.
+func MatchVersion(pattern, tag string) bool {
+	if IsConstraint(pattern) {
+		c, err := ParseConstraint(pattern)
+		v, ok := ParseVersion(tag)
+		return err == nil && ok && c.Check(v)
+	}
+	return strings.Contains(strings.ToLower(tag), strings.ToLower(pattern))
+}
+
+// ParseConstraint parses a version constraint. A caret allows changes that keep the left-most
+// non-zero number, a tilde changes to the patch number (or the minor number when only a
+// major version is given), and a bare partial version such as 1.2, or 1.2.x, means any 1.2.x.
+func ParseConstraint(s string) (Constraint, error) {
+	var c Constraint
+	for _, alt := range strings.Split(s, "||") {
+		var set []comparator
+		fields := strings.FieldsFunc(alt, func(r rune) bool { return r == ' ' || r == ',' })
+		for i := 0; i < len(fields); i++ {
+			f := fields[i]
+			op := f[:len(f)-len(strings.TrimLeft(f, "^~=<>"))]
+			rest := strings.TrimPrefix(f[len(op):], "v")
+			if rest == "" && i+1 < len(fields) {
+				// An operator written apart from its version, as in ">= 1.2"
+				i++
+				rest = strings.TrimPrefix(fields[i], "v")
+			}
+			bounds, err := expand(op, rest)
+			if err != nil {
+				return nil, fmt.Errorf("bad version constraint %q: %w", s, err)
+			}
+			set = append(set, bounds...)
+		}
+		if len(set) == 0 {
+			return nil, fmt.Errorf("bad version constraint %q: empty range", s)
+		}
+		c = append(c, set)
+	}
+	return c, nil
+}
+
+// expand turns one operator and partial version into the bounds it stands for. Trailing
+// wildcards, as in 1.2.x, are the same as leaving the numbers out.
+func expand(op, s string) ([]comparator, error) {
+	for _, w := range []string{".x", ".X", ".*"} {
+		for strings.HasSuffix(s, w) {
+			s = strings.TrimSuffix(s, w)
+		}
+	}
+	v, given, ok := parsePartial(s)
+	if !ok || s == "" {
+		return nil, fmt.Errorf("%q is not a version", s)
+	}
+	// next returns the lowest version above every version that starts with the first n
+	// numbers of v
+	next := func(n int) Version {
+		switch n {
+		case 1:
+			return Version{Major: v.Major + 1, Pre: "0"}
+		case 2:
+			return Version{Major: v.Major, Minor: v.Minor + 1, Pre: "0"}
+		}
+		return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1, Pre: "0"}
+	}
+	switch op {
+	case "", "=":
+		if given == 3 {
+			return []comparator{{"=", v}}, nil
+		}
+		return []comparator{{">=", v}, {"<", next(given)}}, nil
+	case "<", "<=", ">", ">=":
+		if given < 3 && op == "<=" {
+			return []comparator{{"<", next(given)}}, nil
+		}
+		if given < 3 && op == ">" {
+			return []comparator{{">=", next(given)}}, nil
+		}
+		return []comparator{{op, v}}, nil
+	case "~":
+		return []comparator{{">=", v}, {"<", next(min(given, 2))}}, nil
+	case "^":
+		n := 1
+		switch {
+		case v.Major == 0 && v.Minor == 0 && given == 3:
+			n = 3
+		case v.Major == 0 && given >= 2:
+			n = 2
+		}
+		return []comparator{{">=", v}, {"<", next(n)}}, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", op)
+}
@@ -0,0 +1,104 @@
+package releases
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		match      []string
+		reject     []string
+	}{
+		{"^1.4", []string{"v1.4.0", "1.9.3"}, []string{"1.3.9", "2.0.0", "2.0.0-rc.1"}},
+		{"^0.3.1", []string{"0.3.1", "0.3.9"}, []string{"0.3.0", "0.4.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.2.2", "1.3.0"}},
+		{"~1", []string{"1.0.0", "1.9.0"}, []string{"2.0.0"}},
+		{">=2.0.0 <3", []string{"2.0.0", "2.99.1"}, []string{"1.9.9", "3.0.0"}},
+		{">= 1.2, < 1.3", []string{"1.2.0", "1.2.7"}, []string{"1.3.0"}},
+		{"<=1.2", []string{"1.2.9"}, []string{"1.3.0"}},
+		{">1.2", []string{"1.3.0"}, []string{"1.2.9"}},
+		{"1.2", []string{"1.2.0", "1.2.5"}, []string{"1.3.0", "1.1.9"}},
+		{"v2.40.x", []string{"2.40.0", "2.40.3"}, []string{"2.41.0"}},
+		{"1.*", []string{"1.0.0", "1.99.0"}, []string{"2.0.0"}},
+		{"=1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{"^1 || ^3", []string{"1.5.0", "3.1.0"}, []string{"2.0.0"}},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Errorf("ParseConstraint(%q): %v", tt.constraint, err)
+			continue
+		}
+		for _, tag := range tt.match {
+			if v, _ := ParseVersion(tag); !c.Check(v) {
+				t.Errorf("%q rejects %s", tt.constraint, tag)
+			}
+		}
+		for _, tag := range tt.reject {
+			if v, _ := ParseVersion(tag); c.Check(v) {
+				t.Errorf("%q accepts %s", tt.constraint, tag)
+			}
+		}
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	for _, s := range []string{"", "^", ">=x", "1.2.3.4", "^1 ||"} {
+		if _, err := ParseConstraint(s); err == nil {
+			t.Errorf("ParseConstraint(%q) succeeded", s)
+		}
+	}
+}
+
+func TestIsConstraint(t *testing.T) {
+	tests := map[string]bool{
+		"^1.2":    true,
+		"~1":      true,
+		">=2":     true,
+		"v2.x":    true,
+		"1.*":     true,
+		"v1.2.3":  false,
+		"nightly": false,
+		"":        false,
+	}
+	for pattern, want := range tests {
+		if got := IsConstraint(pattern); got != want {
+			t.Errorf("IsConstraint(%q) = %v, want %v", pattern, got, want)
+		}
+	}
+}
+
+func TestConstraintSelect(t *testing.T) {
+	list := []*Release{
+		{Tag: "v1.2.0"},
+		{Tag: "v1.10.0"},
+		{Tag: "v1.11.0-rc.1"},
+		{Tag: "v1.9.0", Draft: true},
+		{Tag: "v2.0.0"},
+		{Tag: "nightly"},
+	}
+	c, err := ParseConstraint("^1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		pre, drafts bool
+		want        []string
+	}{
+		{false, false, []string{"v1.10.0", "v1.2.0"}},
+		{true, false, []string{"v1.11.0-rc.1", "v1.10.0", "v1.2.0"}},
+		{false, true, []string{"v1.10.0", "v1.9.0", "v1.2.0"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range c.Select(list, tt.pre, tt.drafts) {
+			got = append(got, r.Tag)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Select(pre=%v, drafts=%v) = %v, want %v", tt.pre, tt.drafts, got, tt.want)
+		}
+	}
+}
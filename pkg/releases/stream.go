@@ -0,0 +1,112 @@
+package releases
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+// Stream reads an asset once, hashing its bytes and copying them to any tapped writers as
+// they pass, such as a cache file or the input of a signature check. Stages wrapped around
+// it transform the content on its way to disk without another pass over the data.
+type Stream struct {
+	r    io.Reader
+	h    hash.Hash
+	n    int64
+	taps []io.Writer
+}
+
+// NewStream returns a Stream reading from r that copies everything it reads to taps.
+func NewStream(r io.Reader, taps ...io.Writer) *Stream {
+	return &Stream{r: r, h: sha256.New(), taps: taps}
+}
+
+func (s *Stream) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if n > 0 {
+		s.n += int64(n)
+		s.h.Write(p[:n])
+		for _, w := range s.taps {
+			if _, werr := w.Write(p[:n]); werr != nil {
+				return n, werr
+			}
+		}
+	}
+	return n, err
+}
+
+// Digest returns the hex-encoded SHA-256 digest of the bytes read so far; once the stream
+// is drained, that of the whole asset.
+func (s *Stream) Digest() string {
+	return hex.EncodeToString(s.h.Sum(nil))
+}
+
+// Len returns the number of bytes read so far.
+func (s *Stream) Len() int64 {
+	return s.n
+}
+
+// A Stage transforms the content of an asset while it streams. If the reader it returns is
+// also an io.Closer, InstallStream closes it once the content is written.
+type Stage func(io.Reader) (io.Reader, error)
+
+// Decompress is a Stage that undoes gzip or bzip2 compression, recognised by its magic
+// bytes. Other content passes through unchanged.
+func Decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(3)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("could not read gzip stream: %w", err)
+		}
+		return zr, nil
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		return bzip2.NewReader(br), nil
+	}
+	return br, nil
+}
+
+// InstallStream passes r through stages into a new executable file at dest and returns the
+// hex-encoded SHA-256 digest of r, tapping its bytes to taps on the way. Like
+// WriteExecutable, it only replaces dest once the content is complete. The digest covers
+// all of r even if the last stage stops reading early. size, the length of r if known or
+// 0, is used to preallocate dest and to reject truncated content. Memory use does not grow
+// with the size of r.
+func InstallStream(r io.Reader, dest string, size int64, stages []Stage, taps ...io.Writer) (string, error) {
+	s := NewStream(r, taps...)
+	var content io.Reader = s
+	for _, stage := range stages {
+		var err error
+		if content, err = stage(content); err != nil {
+			return "", err
+		}
+		if c, ok := content.(io.Closer); ok {
+			defer c.Close()
+		}
+	}
+	tmp, _, err := writeTemp(content, dest, size)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(io.Discard, s); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("could not read asset content: %w", err)
+	}
+	if size > 0 && s.Len() != size {
+		os.Remove(tmp)
+		return "", fmt.Errorf("asset content is %d bytes, expected %d: %w", s.Len(), size, io.ErrUnexpectedEOF)
+	}
+	if err := commitTemp(tmp, dest); err != nil {
+		return "", err
+	}
+	return s.Digest(), nil
+}
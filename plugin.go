@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// Plugins are executables named get-gh-release-<name> on PATH. Each call runs the plugin
+// once with the method as its only argument, writes a {"method", "params"} request to its
+// standard input and reads a {"result"} or {"error"} response from its standard output.
+// Standard error is passed through.
+//
+// A source plugin (-source <name>) answers list_repos, resolve_release, list_releases,
+// release_by_tag and download_asset, standing in for GitHub. A post-install plugin (-post-install <name>)
+// answers post_install after every installation.
+const pluginPrefix = "get-gh-release-"
+
+// postInstallPlugins are run after every install. They are set from -post-install.
+var postInstallPlugins []string
+
+type pluginRequest struct {
+	Method string `json:"method"`
+	Params any    `json:"params,omitempty"`
+}
+
+type pluginResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error"`
+}
+
+// callPlugin invokes a method of the named plugin and decodes its result into result, which
+// may be nil.
+func callPlugin(ctx context.Context, name, method string, params, result any) error {
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return fmt.Errorf("plugin %s not found: %w", name, err)
+	}
+	req, err := json.Marshal(pluginRequest{Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, method)
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("plugin %s %s: %w", name, method, err)
+	}
+	var resp pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return fmt.Errorf("plugin %s %s: bad response: %w", name, method, err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("plugin %s %s: %s", name, method, resp.Error)
+	}
+	if result == nil || len(resp.Result) == 0 || string(resp.Result) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("plugin %s %s: bad result: %w", name, method, err)
+	}
+	return nil
+}
+
+// pluginProvider is a releases.ReleaseProvider backed by a source plugin.
+type pluginProvider struct {
+	name string
+}
+
+func (p pluginProvider) ListRepos(ctx context.Context) ([]releases.Repo, error) {
+	var repos []releases.Repo
+	err := callPlugin(ctx, p.name, "list_repos", nil, &repos)
+	return repos, err
+}
+
+func (p pluginProvider) ResolveRelease(ctx context.Context, owner, repo, versionPattern string) (*releases.Release, error) {
+	var r *releases.Release
+	err := callPlugin(ctx, p.name, "resolve_release", map[string]string{
+		"owner": owner, "repo": repo, "version": versionPattern,
+	}, &r)
+	return r, err
+}
+
+func (p pluginProvider) ListReleases(ctx context.Context, owner, repo string) ([]*releases.Release, error) {
+	var list []*releases.Release
+	err := callPlugin(ctx, p.name, "list_releases", map[string]string{
+		"owner": owner, "repo": repo,
+	}, &list)
+	return list, err
+}
+
+func (p pluginProvider) ReleaseByTag(ctx context.Context, owner, repo, tag string) (*releases.Release, error) {
+	var r *releases.Release
+	if err := callPlugin(ctx, p.name, "release_by_tag", map[string]string{
+		"owner": owner, "repo": repo, "tag": tag,
+	}, &r); err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, fmt.Errorf("plugin %s: no release %s of %s/%s", p.name, tag, owner, repo)
+	}
+	return r, nil
+}
+
+// DownloadAsset asks the plugin where the asset is: a local file ({"path"}) or a URL to
+// fetch with optional request headers ({"url", "headers"}).
+func (p pluginProvider) DownloadAsset(ctx context.Context, c releases.Candidate) (io.ReadCloser, error) {
+	var loc struct {
+		Path    string            `json:"path"`
+		URL     string            `json:"url"`
+		Headers map[string]string `json:"headers"`
+	}
+	if err := callPlugin(ctx, p.name, "download_asset", c, &loc); err != nil {
+		return nil, err
+	}
+	switch {
+	case loc.Path != "":
+		return os.Open(loc.Path)
+	case loc.URL != "":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, loc.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range loc.Headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("plugin %s: download returned %s", p.name, resp.Status)
+		}
+		return resp.Body, nil
+	}
+	return nil, fmt.Errorf("plugin %s: download_asset returned neither path nor url", p.name)
+}
+
+// runPostInstallPlugins passes a completed install to every post-install plugin in turn.
+func runPostInstallPlugins(ctx context.Context, c releases.Candidate, dest, previous string) error {
+	for _, name := range postInstallPlugins {
+		err := callPlugin(ctx, name, "post_install", map[string]string{
+			"repo":             c.RepoOwner + "/" + c.RepoName,
+			"tag":              c.Tag,
+			"asset":            c.AssetName,
+			"path":             dest,
+			"previous_version": previous,
+		}, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
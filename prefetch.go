@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// manifestStep is one tool of a manifest that apply or sync is installing: the asset it
+// resolved to and where that goes, or why it cannot be installed.
+type manifestStep struct {
+	tool    manifestTool
+	pin     *lockedTool
+	c       releases.Candidate
+	dest    string
+	current bool // installed already, so there is nothing to download
+	err     error
+}
+
+// pending reports whether the step still has an asset to install.
+func (s *manifestStep) pending() bool {
+	return s.err == nil && !s.current
+}
+
+// prefetch downloads the assets the steps still have to install, jobs at a time, so that
+// installing them one after another, with their hooks and the locks of their directories,
+// does not wait on each download in turn. The assets are left in the cache as completed
+// partial downloads: each install takes its asset from there and verifies it as it would a
+// download of its own, so nothing reaches the cache proper unchecked. A step whose download
+// fails gets its error. Assets already cached, and all of them with a single job, are left
+// to the installs.
+func prefetch(ctx context.Context, provider releases.ReleaseProvider, steps []manifestStep, jobs int) {
+	if jobs < 2 {
+		return
+	}
+	var todo []*manifestStep
+	keys := map[string]bool{}
+	for i := range steps {
+		s := &steps[i]
+		if !s.pending() || s.c.Size <= 0 || keys[releaseKey(s.c)] {
+			continue
+		}
+		if _, _, ok := cacheLookup(s.c); ok {
+			continue
+		}
+		keys[releaseKey(s.c)] = true
+		todo = append(todo, s)
+	}
+	if len(todo) < 2 {
+		return
+	}
+
+	board := newProgressBoard(len(todo), jobs)
+	defer board.close()
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for _, s := range todo {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				s.err = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+			l := board.start(s.c.AssetName)
+			start := time.Now()
+			if s.err = prefetchAsset(ctx, provider, s.c, board, l); s.err != nil {
+				board.finish(l, "")
+				return
+			}
+			board.finish(l, fmt.Sprintf("%s: downloaded %s (%s in %s)", s.tool.Repo, s.c.AssetName, formatSize(s.c.Size), time.Since(start).Round(100*time.Millisecond)))
+		}()
+	}
+	wg.Wait()
+}
+
+// prefetchAsset downloads the asset of c into its partial download in the cache, showing
+// its progress on l and trying again, up to -retries times, when the download is cut short.
+func prefetchAsset(ctx context.Context, provider releases.ReleaseProvider, c releases.Candidate, board *progressBoard, l *boardLine) error {
+	for n := 0; ; n++ {
+		err := prefetchOnce(ctx, provider, c, board, l)
+		if err == nil || n >= retries || !transient(err) {
+			return err
+		}
+		wait := backoff(n)
+		board.warnf("Download of %s failed (%v); retrying in %s", c.AssetName, err, wait.Round(time.Millisecond))
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// prefetchOnce makes one attempt at prefetchAsset.
+func prefetchOnce(ctx context.Context, provider releases.ReleaseProvider, c releases.Candidate, board *progressBoard, l *boardLine) error {
+	d, err := openAsset(ctx, provider, c, board.warnf)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if d.blob == nil || !d.blob.partial {
+		// Nowhere to keep it: the install downloads it instead
+		if d.blob != nil {
+			d.blob.abort()
+		}
+		return nil
+	}
+	r := &countingReader{r: board.reader(l, d, c.AssetName, c.Size, d.offset), source: d.source}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(d.blob, h), r)
+	switch {
+	case err != nil:
+	case d.offset+n != c.Size:
+		err = fmt.Errorf("%w: got %d of %d bytes of %s", io.ErrUnexpectedEOF, d.offset+n, c.Size, c.AssetName)
+	case d.want != "" && !strings.EqualFold(d.want, hex.EncodeToString(h.Sum(nil))):
+		// The install would not know the mirror's digest to check it against
+		err = fmt.Errorf("%w: mirror copy of %s is corrupt: expected %s, got %x", releases.ErrVerificationFailed, c.AssetName, d.want, h.Sum(nil))
+	default:
+		err = d.blob.err
+	}
+	if err != nil {
+		d.abandon(c, err)
+		metrics.add("get_gh_release_download_failures_total", 1)
+		return err
+	}
+	d.blob.keep()
+	return nil
+}
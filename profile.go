@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// hiddenFlags are diagnostics flags left out of the usage message.
+var hiddenFlags = map[string]bool{"cpuprofile": true, "memprofile": true, "trace": true}
+
+// usage prints the flags that are not hidden.
+func usage() {
+	visible := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	visible.SetOutput(flag.CommandLine.Output())
+	flag.VisitAll(func(f *flag.Flag) {
+		if !hiddenFlags[f.Name] {
+			visible.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+	fmt.Fprintf(visible.Output(), "Usage of %s:\n", os.Args[0])
+	visible.PrintDefaults()
+}
+
+// stopProfiling finishes the profiles started by startProfiling. fatalf calls it too, so
+// that a failed run still leaves usable profiles behind.
+var stopProfiling = func() {}
+
+// startProfiling starts a CPU profile and an execution trace and arranges for a heap profile
+// to be written by stopProfiling. Empty file names skip the respective profile.
+func startProfiling(cpuFile, memFile, traceFile string) error {
+	var stops []func()
+	stopProfiling = func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+		stops = nil
+	}
+	if cpuFile != "" {
+		f, err := os.Create(cpuFile)
+		if err != nil {
+			return fmt.Errorf("could not create CPU profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return fmt.Errorf("could not start CPU profile: %w", err)
+		}
+		stops = append(stops, func() {
+			pprof.StopCPUProfile()
+			f.Close()
+		})
+	}
+	if traceFile != "" {
+		f, err := os.Create(traceFile)
+		if err != nil {
+			return fmt.Errorf("could not create trace: %w", err)
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			return fmt.Errorf("could not start trace: %w", err)
+		}
+		stops = append(stops, func() {
+			trace.Stop()
+			f.Close()
+		})
+	}
+	if memFile != "" {
+		stops = append(stops, func() {
+			f, err := os.Create(memFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not create memory profile: %v\n", err)
+				return
+			}
+			defer f.Close()
+			runtime.GC()
+			if err := pprof.WriteHeapProfile(f); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not write memory profile: %v\n", err)
+			}
+		})
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// quiet turns off the progress display of downloads. It is set from -quiet.
+var quiet bool
+
+// progress shows how far a download has got on standard error, redrawing one line at most
+// every progressInterval and clearing it when the download ends.
+type progress struct {
+	r     io.Reader
+	name  string
+	total int64 // 0 if unknown
+	from  int64 // bytes downloaded before, when resuming
+	n     int64
+	start time.Time
+	drawn time.Time
+}
+
+// progressInterval is how often the progress line is redrawn.
+const progressInterval = 200 * time.Millisecond
+
+// withProgress wraps r, the content of the asset name of size total from byte from on, to
+// show its progress when standard error is a terminal and neither -quiet nor CI mode is in
+// effect.
+func withProgress(r io.Reader, name string, total, from int64) io.Reader {
+	if !showProgress() {
+		return r
+	}
+	return &progress{r: r, name: name, total: total, from: from, n: from, start: time.Now()}
+}
+
+// showProgress reports whether progress is drawn: standard error is a terminal, and neither
+// -quiet nor CI mode is in effect.
+func showProgress() bool {
+	if quiet || ciMode || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := os.Stderr.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func (p *progress) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.n += int64(n)
+	if err != nil {
+		// Done or failed: the line has served its purpose
+		if !p.drawn.IsZero() {
+			fmt.Fprint(os.Stderr, "\r\033[K")
+		}
+		return n, err
+	}
+	if now := time.Now(); now.Sub(p.drawn) >= progressInterval && now.Sub(p.start) >= progressInterval {
+		p.drawn = now
+		p.draw(now.Sub(p.start))
+	}
+	return n, err
+}
+
+// draw redraws the progress line after elapsed time.
+func (p *progress) draw(elapsed time.Duration) {
+	fmt.Fprintf(os.Stderr, "\r\033[K%s", progressLine(p.name, p.n, p.from, p.total, elapsed))
+}
+
+// progressLine describes a download of name that has reached n bytes of total, 0 if unknown,
+// after elapsed time, having started at byte from.
+func progressLine(name string, n, from, total int64, elapsed time.Duration) string {
+	rate := float64(n-from) / elapsed.Seconds()
+	line := fmt.Sprintf("%s  %s", name, formatSize(n))
+	if total > 0 {
+		line += fmt.Sprintf(" / %s  %3d%%", formatSize(total), n*100/total)
+	}
+	line += fmt.Sprintf("  %s/s", formatSize(int64(rate)))
+	if total > 0 && rate > 0 && n < total {
+		eta := time.Duration(float64(total-n) / rate * float64(time.Second))
+		line += "  ETA " + eta.Round(time.Second).String()
+	}
+	return line
+}
+
+// progressBoard shows the progress of several downloads running at once on standard error,
+// a line for each below a line counting them, and prints each download's outcome on
+// standard output as it ends. Where withProgress would show nothing, only the outcomes are
+// printed.
+type progressBoard struct {
+	mu     sync.Mutex
+	live   bool
+	width  int
+	total  int // downloads in all
+	done   int // downloads ended
+	active []*boardLine
+	drawn  int // lines on screen
+}
+
+// boardLine is the line of one download on a progressBoard.
+type boardLine struct {
+	text string
+}
+
+// newProgressBoard returns a board for total downloads, of which at most jobs run at once.
+// It only draws when all of them fit on the terminal.
+func newProgressBoard(total, jobs int) *progressBoard {
+	b := &progressBoard{total: total}
+	if showProgress() {
+		var rows int
+		b.width, rows = terminalSize(os.Stderr)
+		b.live = jobs+1 < rows
+	}
+	return b
+}
+
+// start adds a line for a download of name.
+func (b *progressBoard) start(name string) *boardLine {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	l := &boardLine{text: name}
+	b.active = append(b.active, l)
+	b.redraw()
+	return l
+}
+
+// update replaces the text of l.
+func (b *progressBoard) update(l *boardLine, text string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	l.text = text
+	b.redraw()
+}
+
+// finish removes the line of a download that ended, printing outcome in its place unless it
+// is empty.
+func (b *progressBoard) finish(l *boardLine, outcome string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done++
+	b.active = slices.DeleteFunc(b.active, func(a *boardLine) bool { return a == l })
+	b.clear()
+	if outcome != "" {
+		fmt.Println(outcome)
+	}
+	b.redraw()
+}
+
+// warnf prints a message on standard error above the board.
+func (b *progressBoard) warnf(format string, args ...any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clear()
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	b.redraw()
+}
+
+// close takes the board off the screen.
+func (b *progressBoard) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clear()
+}
+
+// clear erases the lines drawn. It is called with mu held.
+func (b *progressBoard) clear() {
+	if b.drawn > 0 {
+		fmt.Fprintf(os.Stderr, "\033[%dA\r\033[J", b.drawn)
+		b.drawn = 0
+	}
+}
+
+// redraw draws the board over the lines drawn before. It is called with mu held.
+func (b *progressBoard) redraw() {
+	if !b.live {
+		return
+	}
+	lines := []string{fmt.Sprintf("Downloading assets, %d of %d done", b.done, b.total)}
+	for _, l := range b.active {
+		lines = append(lines, "  "+l.text)
+	}
+	var out strings.Builder
+	if b.drawn > 0 {
+		fmt.Fprintf(&out, "\033[%dA", b.drawn)
+	}
+	for _, line := range lines {
+		if r := []rune(line); len(r) >= b.width {
+			line = string(r[:b.width-1])
+		}
+		fmt.Fprintf(&out, "\r\033[K%s\n", line)
+	}
+	if b.drawn > len(lines) {
+		out.WriteString("\033[J")
+	}
+	b.drawn = len(lines)
+	os.Stderr.WriteString(out.String())
+}
+
+// reader wraps r, the content of the asset name of size total from byte from on, to show its
+// progress on l.
+func (b *progressBoard) reader(l *boardLine, r io.Reader, name string, total, from int64) io.Reader {
+	if !b.live {
+		return r
+	}
+	return &boardReader{b: b, l: l, progress: progress{r: r, name: name, total: total, from: from, n: from, start: time.Now()}}
+}
+
+// boardReader is a progress reader that draws on a progressBoard.
+type boardReader struct {
+	b *progressBoard
+	l *boardLine
+	progress
+}
+
+func (r *boardReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.n += int64(n)
+	if now := time.Now(); now.Sub(r.drawn) >= progressInterval && now.Sub(r.start) >= progressInterval {
+		r.drawn = now
+		r.b.update(r.l, progressLine(r.name, r.n, r.from, r.total, now.Sub(r.start)))
+	}
+	return n, err
+}
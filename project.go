@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// projectManifestName is the manifest picked up automatically from the working directory or
+// one of its parents. projectManifestNames lists the other names accepted, in order.
+const projectManifestName = ".get-gh-release.yaml"
+
+var projectManifestNames = []string{projectManifestName, ".get-gh-release.toml"}
+
+// findProjectManifest walks up from the working directory looking for a project manifest and
+// returns its path, or "" if there is none.
+func findProjectManifest() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, name := range projectManifestNames {
+			candidate := filepath.Join(dir, name)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, nil
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return "", err
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// loadProjectManifest loads the project manifest if one exists, or returns nil.
+func loadProjectManifest() (*manifest, error) {
+	file, err := findProjectManifest()
+	if err != nil || file == "" {
+		return nil, err
+	}
+	return loadManifest(file)
+}
+
+// manifestArg returns the manifest named on the command line, falling back to the project
+// manifest when none is given.
+func manifestArg(fs *flag.FlagSet) (string, error) {
+	switch fs.NArg() {
+	case 1:
+		return fs.Arg(0), nil
+	case 0:
+		file, err := findProjectManifest()
+		if err != nil {
+			return "", err
+		}
+		if file == "" {
+			return "", fmt.Errorf("no manifest given and no %s found", projectManifestName)
+		}
+		return file, nil
+	default:
+		return "", fmt.Errorf("too many arguments")
+	}
+}
+
+// pins returns the version each listed repository is pinned to, keyed by lower-case owner/repo.
+func (m *manifest) pins() map[string]string {
+	pins := map[string]string{}
+	for _, t := range m.Tools {
+		if t.Version != "" {
+			pins[strings.ToLower(t.Repo)] = strings.ToLower(t.Version)
+		}
+	}
+	return pins
+}
+
+// projectDest returns where a plain download of c goes under the project manifest: the
+// listed tool's directory and name, or the default directory. It returns "" when the project
+// manifest says nothing about where c belongs.
+func (m *manifest) projectDest(c releases.Candidate) string {
+	t := m.toolFor(c.RepoOwner, c.RepoName)
+	if t == nil {
+		if m.Defaults.Dest == "" {
+			return ""
+		}
+		t = &manifestTool{}
+	}
+	name := t.Name
+	if name == "" {
+		name = installName(c)
+	}
+	return filepath.Join(m.destDir(*t), name)
+}
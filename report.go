@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// versionReport is one tracked tool in the report command's output. The field names follow
+// Renovate's package data, so the renovate format can be fed to a custom datasource as is.
+type versionReport struct {
+	DepName         string          `json:"depName"`
+	Datasource      string          `json:"datasource"`
+	CurrentValue    string          `json:"currentValue,omitempty"`
+	LatestVersion   string          `json:"latestVersion,omitempty"`
+	UpdateAvailable bool            `json:"updateAvailable"`
+	SourceURL       string          `json:"sourceUrl"`
+	Releases        []reportRelease `json:"releases"`
+	Error           string          `json:"error,omitempty"`
+	Path            string          `json:"path,omitempty"` // install path, for installed tools
+}
+
+// reportRelease is a release entry in Renovate's custom datasource schema.
+type reportRelease struct {
+	Version          string `json:"version"`
+	ReleaseTimestamp string `json:"releaseTimestamp,omitempty"`
+}
+
+// runReport lists each tracked tool with its current and latest release. Tools are taken from
+// a manifest, whose version pins are what Renovate bumps, or with -installed from the record
+// of installed tools.
+func runReport(ctx context.Context, provider releases.ReleaseProvider, args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	format := fs.String("format", "text", "Output format: text or renovate (JSON).")
+	installed := fs.Bool("installed", false, "Report the installed tools instead of a manifest.")
+	output := fs.String("o", "-", "File to write the report to (- for standard output).")
+	fs.Parse(args)
+	if *format != "text" && *format != "renovate" {
+		return fmt.Errorf("unknown report format %q (want text or renovate)", *format)
+	}
+
+	var reports []versionReport
+	if *installed {
+		st, err := loadState()
+		if err != nil {
+			return err
+		}
+		for _, t := range st.Tools {
+			reports = append(reports, versionReport{DepName: t.RepoOwner + "/" + t.RepoName, CurrentValue: t.Tag, Path: t.Path})
+		}
+	} else {
+		file, err := manifestArg(fs)
+		if err != nil {
+			return fmt.Errorf("usage: get_gh_release report [-format text|renovate] [-installed] [manifest.yaml]: %w", err)
+		}
+		m, err := loadManifest(file)
+		if err != nil {
+			return err
+		}
+		for _, t := range m.Tools {
+			reports = append(reports, versionReport{DepName: t.Repo, CurrentValue: t.Version})
+		}
+	}
+
+	for i := range reports {
+		r := &reports[i]
+		r.Datasource = "github-releases"
+		r.SourceURL = githubWeb + r.DepName
+		r.Releases = []reportRelease{}
+		owner, repo, _ := strings.Cut(r.DepName, "/")
+		release, err := provider.ResolveRelease(ctx, owner, repo, "")
+		if err != nil {
+			r.Error = err.Error()
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", r.DepName, err)
+			continue
+		}
+		r.LatestVersion = release.Tag
+		r.UpdateAvailable = r.CurrentValue != "" && !releases.MatchVersion(r.CurrentValue, r.LatestVersion)
+		rel := reportRelease{Version: r.LatestVersion}
+		if !release.PublishedAt.IsZero() {
+			rel.ReleaseTimestamp = release.PublishedAt.UTC().Format("2006-01-02T15:04:05Z")
+		}
+		r.Releases = append(r.Releases, rel)
+	}
+
+	out := io.Writer(os.Stdout)
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	if *format == "renovate" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(reports)
+	}
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPO\tCURRENT\tLATEST\t")
+	for _, r := range reports {
+		current, latest := r.CurrentValue, r.LatestVersion
+		if current == "" {
+			current = "(latest)"
+		}
+		if r.Error != "" {
+			latest = "error"
+		} else if r.UpdateAvailable {
+			latest += " *"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t\n", r.DepName, current, latest)
+	}
+	return tw.Flush()
+}
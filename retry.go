@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// retries is the number of times a request or download failing for a passing reason is
+// tried again, and retryWait the wait before the first retry, doubled for each one after it.
+// They are set from -retries and -retry-wait.
+var (
+	retries   = 3
+	retryWait = time.Second
+)
+
+// transient reports whether err is a failure worth trying again: a dropped connection or a
+// body that ended early. Cancelled runs and timeouts are not.
+func transient(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// backoff is the wait before retry n, counting from 0: retryWait doubled n times, less up to
+// half of it at random, so that clients failing together do not all retry together.
+func backoff(n int) time.Duration {
+	d := retryWait << min(n, 16)
+	return d - rand.N(d/2+1)
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryTransport tries requests again when the server fails with a 5xx status, the connection
+// is reset, or a JSON response arrives cut short; API responses are read in full here so
+// that a truncated one can be fetched again. Only requests that are safe to repeat are
+// retried.
+type retryTransport struct {
+	base    http.RoundTripper
+	retries int
+}
+
+func (t retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return t.base.RoundTrip(req)
+	}
+	for n := 0; ; n++ {
+		resp, err := t.base.RoundTrip(req)
+		if err == nil && resp.StatusCode < 500 && strings.Contains(resp.Header.Get("Content-Type"), "json") {
+			if err = bufferBody(resp); err != nil {
+				resp = nil
+			}
+		}
+		var reason string
+		var wait time.Duration
+		switch {
+		case err != nil && !transient(err):
+			return nil, err
+		case err != nil:
+			reason = err.Error()
+		case resp.StatusCode >= 500:
+			reason = resp.Status
+			if s, perr := strconv.Atoi(resp.Header.Get("Retry-After")); perr == nil {
+				wait = time.Duration(s) * time.Second
+			}
+		default:
+			return resp, nil
+		}
+		if n >= t.retries {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+		wait = max(wait, backoff(n))
+		fmt.Fprintf(os.Stderr, "Request to %s failed (%s); retrying in %s\n", req.URL.Host, reason, wait.Round(time.Millisecond))
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// bufferBody reads the body of resp into memory, failing if it ends early.
+func bufferBody(resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// installScriptTemplate is the standalone installer written by -emit-script. It needs only
+// curl and sha256sum (or shasum) and honours INSTALL_DIR and GITHUB_TOKEN.
+var installScriptTemplate = template.Must(template.New("script").Parse(`#!/bin/sh
+# Installs {{.Repo}} {{.Tag}} ({{.Asset}}).
+# Generated by get_gh_release. Requires curl and sha256sum or shasum.
+# Set INSTALL_DIR to change where it goes; set GITHUB_TOKEN for private repositories.
+set -eu
+
+url={{.URL}}
+api_url={{.APIURL}}
+sha256={{.SHA256}}
+name={{.Name}}
+dir="${INSTALL_DIR:-{{.Dir}}}"
+
+tmp="$(mktemp)"
+trap 'rm -f "$tmp"' EXIT INT TERM
+
+if [ -n "${GITHUB_TOKEN:-}" ]; then
+	curl -fsSL -H "Authorization: Bearer $GITHUB_TOKEN" -H "Accept: application/octet-stream" -o "$tmp" "$api_url"
+else
+	curl -fsSL -o "$tmp" "$url"
+fi
+
+if command -v sha256sum >/dev/null 2>&1; then
+	actual="$(sha256sum "$tmp" | cut -d' ' -f1)"
+else
+	actual="$(shasum -a 256 "$tmp" | cut -d' ' -f1)"
+fi
+if [ "$actual" != "$sha256" ]; then
+	echo "checksum mismatch for $name: expected $sha256, got $actual" >&2
+	exit 1
+fi
+
+mkdir -p "$dir"
+chmod 0755 "$tmp"
+mv -f "$tmp" "$dir/$name"
+trap - EXIT INT TERM
+echo "installed $dir/$name"
+`))
+
+// renderInstallScript builds an installer that downloads the asset of c, checks it against
+// digest and installs it as name below dir.
+func renderInstallScript(c releases.Candidate, digest, dir, name string) (string, error) {
+	var b strings.Builder
+	err := installScriptTemplate.Execute(&b, map[string]string{
+		"Repo":   c.RepoOwner + "/" + c.RepoName,
+		"Tag":    c.Tag,
+		"Asset":  c.AssetName,
+		"URL":    shellQuote(c.DownloadURL),
+		"APIURL": shellQuote(fmt.Sprintf("%srepos/%s/%s/releases/assets/%d", githubAPI, c.RepoOwner, c.RepoName, c.AssetID)),
+		"SHA256": digest,
+		"Name":   shellQuote(name),
+		"Dir":    scriptDir(dir),
+	})
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// scriptDir quotes dir for the double-quoted default of the script's install directory,
+// leaving a leading ~/ to the home directory of whoever runs the script.
+func scriptDir(dir string) string {
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace
+	if rest, ok := strings.CutPrefix(dir, "~/"); ok {
+		return "$HOME/" + quote(rest)
+	}
+	return quote(dir)
+}
+
+// writeInstallScript writes the installer to file, or to standard output for "-".
+func writeInstallScript(file, script string) error {
+	if file == "-" {
+		_, err := fmt.Print(script)
+		return err
+	}
+	return os.WriteFile(file, []byte(script), 0755)
+}
+
+// shellQuote quotes s as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// runSearch lists the assets of the releases matching a repository pattern without
+// downloading anything. Assets the finder would pick on this platform are marked with *.
+func runSearch(ctx context.Context, finder releases.Finder, args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	matching := fs.Bool("match", false, "List only the assets that would be installed on this platform.")
+	fs.Parse(args)
+	if fs.NArg() > 3 {
+		return fmt.Errorf("usage: get_gh_release search [-match] [pattern [version [asset_glob]]]")
+	}
+	pattern, version := fs.Arg(0), strings.ToLower(fs.Arg(1))
+	if fs.Arg(2) != "" {
+		finder.Matcher.Glob = fs.Arg(2)
+	}
+
+	found, err := finder.Releases(ctx, pattern, version, nil)
+	var partial *releases.PartialError
+	if errors.As(err, &partial) {
+		for _, e := range partial.Errors {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", e)
+		}
+	} else if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPO\tTAG\tASSET\tSIZE\tPLATFORM\t")
+	for _, rr := range found {
+		// Mark what a search would install, without narrowing to one asset
+		picked := map[string]bool{}
+		for _, a := range finder.MatcherFor(rr.Repo).MatchAll(rr.Release) {
+			picked[a.Name] = true
+		}
+		for _, a := range rr.Release.Assets {
+			if *matching && !picked[a.Name] {
+				continue
+			}
+			name := a.Name
+			if picked[a.Name] {
+				name += " *"
+			}
+			goos, goarch := releases.DetectPlatform(a.Name)
+			platform := "-"
+			if goos != "" || goarch != "" {
+				platform = strings.Trim(goos+"/"+goarch, "/")
+			}
+			fmt.Fprintf(tw, "%s/%s\t%s\t%s\t%s\t%s\t\n", rr.Repo.Owner, rr.Repo.Name, rr.Release.Tag, name, formatSize(a.Size), platform)
+		}
+	}
+	return tw.Flush()
+}
+
+// formatSize renders a byte count with a binary unit.
+func formatSize(n int64) string {
+	if n <= 0 {
+		return "-"
+	}
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// printExplanation writes one search decision of -explain to standard error.
+func printExplanation(e releases.Explanation) {
+	subject := e.Repo.Owner + "/" + e.Repo.Name
+	if e.Tag != "" {
+		subject += " " + e.Tag
+	}
+	if e.Asset != "" {
+		subject += " " + e.Asset
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s: %s\n", subject, explanationVerdict(e), e.Reason)
+}
+
+// explanationVerdict sums up a search decision: selected, rejected or skipped.
+func explanationVerdict(e releases.Explanation) string {
+	switch {
+	case e.Selected:
+		return "selected"
+	case e.Asset == "":
+		return "skipped"
+	}
+	return "rejected"
+}
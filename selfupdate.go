@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// selfOwner and selfRepo name the repository get_gh_release itself is released from.
+const (
+	selfOwner = "abgoyal"
+	selfRepo  = "get_gh_release"
+)
+
+// runSelfUpdate replaces the running executable with the latest release of get_gh_release
+// for the platform, downloaded and checked like any other install. The new binary is
+// written next to the old one, run once to make sure it works, and renamed over it, so an
+// interrupted update leaves the old binary in place.
+func runSelfUpdate(ctx context.Context, provider releases.ReleaseProvider, platformOS, platformArch string, filter releases.ReleaseFilter, args []string) error {
+	fs := flag.NewFlagSet("self-update", flag.ExitOnError)
+	check := fs.Bool("check", false, "Only report whether a newer release is available.")
+	force := fs.Bool("force", false, "Install the latest release even if it is not newer than this binary, as for development builds.")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: get_gh_release self-update [-check] [-force]")
+	}
+
+	release, _, err := filter.Resolve(ctx, provider, selfOwner, selfRepo, "")
+	if err != nil {
+		return fmt.Errorf("could not look up the latest release: %w", err)
+	}
+	if release == nil {
+		return fmt.Errorf("%w: %s/%s has no release", releases.ErrNoCandidates, selfOwner, selfRepo)
+	}
+	current, currentOK := releases.ParseVersion(version)
+	latest, latestOK := releases.ParseVersion(release.Tag)
+	newer := currentOK && latestOK && latest.Compare(current) > 0
+	switch {
+	case !currentOK:
+		fmt.Printf("This is a development build (%s); the latest release is %s.\n", version, release.Tag)
+	case newer:
+		fmt.Printf("get_gh_release %s is available (this is %s).\n", release.Tag, version)
+	default:
+		fmt.Printf("get_gh_release %s is the latest release.\n", version)
+	}
+	if *check {
+		return nil
+	}
+	if !*force {
+		if !currentOK {
+			fmt.Println("Not replacing a development build; pass -force to.")
+		}
+		if !newer {
+			return nil
+		}
+	}
+
+	asset := releases.Matcher{OS: platformOS, Arch: platformArch, Libc: platformLibc}.Match(release)
+	if asset == nil {
+		return fmt.Errorf("%w: release %s has no asset for %s/%s", releases.ErrNoCandidates, release.Tag, platformOS, platformArch)
+	}
+	c := releases.NewCandidate(selfOwner, selfRepo, release, *asset)
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("could not locate this executable: %w", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return fmt.Errorf("could not locate this executable: %w", err)
+	}
+	fmt.Printf("%s: %s -> %s\n", c.Tag, c.AssetName, exe)
+	tmp := exe + ".new"
+	if _, err := downloadAndPrepare(ctx, provider, c, tmp, installStages(c)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if out, err := exec.CommandContext(ctx, tmp, "-version").CombinedOutput(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("%w: the downloaded binary does not run: %v: %s", releases.ErrVerificationFailed, err, strings.TrimSpace(string(out)))
+	}
+	if runtime.GOOS == "windows" {
+		// A running executable cannot be replaced on Windows, but it can be moved aside
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("could not move %s aside: %w", exe, err)
+		}
+	}
+	if err := os.Rename(tmp, exe); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("could not replace %s: %w", exe, err)
+	}
+	fmt.Printf("Updated get_gh_release to %s.\n", release.Tag)
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// digestHeader carries the hex SHA-256 of an asset served from the cache.
+const digestHeader = "X-Checksum-Sha256"
+
+// runServe exposes the local asset cache over HTTP so other machines can use it as their
+// mirror, until ctx is cancelled.
+func runServe(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "Address to listen on.")
+	fs.Parse(args)
+
+	root, err := cacheRoot()
+	if err != nil {
+		return err
+	}
+	log.Printf("serving %s on %s", root, *addr)
+	return listenAndServe(ctx, *addr, cacheHandler(root))
+}
+
+// shutdownGrace is how long requests in flight may take to finish once a server is stopped.
+const shutdownGrace = 10 * time.Second
+
+// listenAndServe serves handler on addr until ctx is cancelled, then stops accepting
+// connections and waits up to shutdownGrace for the requests in flight.
+func listenAndServe(ctx context.Context, addr string, handler http.Handler) error {
+	srv := &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	stopped := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(stopped)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownGrace)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	})
+	defer stop()
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	// ListenAndServe returns as soon as shutdown begins
+	<-stopped
+	return nil
+}
+
+// cacheHandler serves the cache layout rooted at root:
+//
+//	GET /releases/<owner>/<repo>/<tag>/<asset>
+//	GET /blobs/sha256/<digest>
+func cacheHandler(root string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /releases/{owner}/{repo}/{tag}/{asset}", func(w http.ResponseWriter, r *http.Request) {
+		key := path.Join(r.PathValue("owner"), r.PathValue("repo"), r.PathValue("tag"), r.PathValue("asset"))
+		if !validKey(key, 4) {
+			http.NotFound(w, r)
+			return
+		}
+		data, err := os.ReadFile(filepath.Join(root, "releases", filepath.FromSlash(key)))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		serveBlob(w, r, root, strings.TrimSpace(string(data)), r.PathValue("asset"))
+	})
+	mux.HandleFunc("GET /blobs/sha256/{digest}", func(w http.ResponseWriter, r *http.Request) {
+		serveBlob(w, r, root, r.PathValue("digest"), r.PathValue("digest"))
+	})
+	mux.Handle("GET /metrics", metrics)
+	return countServed(logRequests(mux))
+}
+
+// serveBlob writes the blob with the given digest, advertising the digest in a header.
+func serveBlob(w http.ResponseWriter, r *http.Request, root, digest, name string) {
+	if !validKey(digest, 1) {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := os.Open(blobPath(root, digest))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(digestHeader, digest)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
+// validKey reports whether key is a clean relative path of exactly n non-dot segments.
+func validKey(key string, n int) bool {
+	parts := strings.Split(key, "/")
+	if len(parts) != n {
+		return false
+	}
+	for _, p := range parts {
+		if p == "" || p == "." || p == ".." || strings.ContainsRune(p, '\\') {
+			return false
+		}
+	}
+	return true
+}
+
+// statusRecorder remembers the status code and body size written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	n, err := s.ResponseWriter.Write(p)
+	s.bytes += int64(n)
+	return n, err
+}
+
+// countServed records served requests and bytes in the metrics registry.
+func countServed(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+		if r.URL.Path == "/metrics" {
+			return
+		}
+		metrics.add("get_gh_release_served_requests_total", 1, "code", strconv.Itoa(rec.status))
+		metrics.add("get_gh_release_served_bytes_total", float64(rec.bytes))
+	})
+}
+
+// logRequests logs one line per request.
+func logRequests(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+		log.Printf("%s %s %s %d", r.RemoteAddr, r.Method, r.URL.Path, rec.status)
+	})
+}
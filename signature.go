@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// verifyMode is how signatures published with a release are treated, set from -verify:
+// "required" refuses assets without a valid one, "prefer" checks those present where gpg or
+// cosign is installed and has what it needs, and "off" ignores them. A signature that is
+// checked and found bad fails the install in both other modes.
+var verifyMode = "prefer"
+
+// gpgKeyring is a keyring file to check GPG signatures against instead of the user's own.
+// It is set from -gpg-keyring.
+var gpgKeyring string
+
+// sigstoreIssuer is the OIDC issuer of the certificates of keyless signatures made in GitHub
+// Actions, which is where releases are signed. It changes when releases come from GitLab.
+var sigstoreIssuer = "https://token.actions.githubusercontent.com"
+
+// signatureSuffixes are the names signatures are published under, after the asset name:
+// detached GPG signatures, cosign signatures with their certificate, and Sigstore bundles.
+var signatureSuffixes = []string{".asc", ".sig", ".pem", ".sigstore", ".sigstore.json"}
+
+// errCannotVerify is a signature that could not be checked, as opposed to a bad one: the
+// tool to check it is not installed, or the key that made it is not known.
+var errCannotVerify = errors.New("cannot verify signature")
+
+// signatures are the signature files a release publishes for one asset, downloaded to a
+// temporary directory by fetchSignatures.
+type signatures struct {
+	c     releases.Candidate
+	dir   string
+	files map[string]string // suffix -> path
+}
+
+// fetchSignatures downloads the signatures the release of c publishes for its asset. It
+// returns nil if verifyMode is off or the release publishes none, which in required mode
+// is an error. The caller removes the files with cleanup.
+func fetchSignatures(ctx context.Context, provider releases.ReleaseProvider, c releases.Candidate) (*signatures, error) {
+	if verifyMode == "off" {
+		return nil, nil
+	}
+	release, err := provider.ReleaseByTag(ctx, c.RepoOwner, c.RepoName, c.Tag)
+	if err != nil {
+		if verifyMode == "required" {
+			return nil, fmt.Errorf("could not look up signatures: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: could not look up signatures, not verifying %s: %v\n", c.AssetName, err)
+		return nil, nil
+	}
+	s := &signatures{c: c, files: map[string]string{}}
+	for _, a := range release.Assets {
+		suffix, ok := strings.CutPrefix(a.Name, c.AssetName)
+		if !ok || !isSignatureSuffix(suffix) {
+			continue
+		}
+		if s.dir == "" {
+			if s.dir, err = os.MkdirTemp("", "get_gh_release-sig-*"); err != nil {
+				return nil, err
+			}
+		}
+		file := filepath.Join(s.dir, "asset"+suffix)
+		if err := downloadTo(ctx, provider, releases.NewCandidate(c.RepoOwner, c.RepoName, release, a), file); err != nil {
+			s.cleanup()
+			return nil, fmt.Errorf("could not download %s: %w", a.Name, err)
+		}
+		s.files[suffix] = file
+	}
+	if len(s.files) == 0 {
+		if verifyMode == "required" {
+			return nil, fmt.Errorf("%w: release %s of %s/%s publishes no signature for %s", releases.ErrVerificationFailed, c.Tag, c.RepoOwner, c.RepoName, c.AssetName)
+		}
+		return nil, nil
+	}
+	return s, nil
+}
+
+func isSignatureSuffix(suffix string) bool {
+	for _, s := range signatureSuffixes {
+		if suffix == s {
+			return true
+		}
+	}
+	return false
+}
+
+// downloadTo saves the asset of c, which is small, to file.
+func downloadTo(ctx context.Context, provider releases.ReleaseProvider, c releases.Candidate, file string) error {
+	rc, err := provider.DownloadAsset(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, io.LimitReader(rc, 1<<20)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// cleanup removes the downloaded signatures. It does nothing on nil.
+func (s *signatures) cleanup() {
+	if s != nil && s.dir != "" {
+		os.RemoveAll(s.dir)
+	}
+}
+
+// check verifies asset, a file holding the asset as published, against every signature. A
+// bad signature is an ErrVerificationFailed; one that cannot be checked only warns, unless
+// verifyMode is required and no other signature could be checked either.
+func (s *signatures) check(ctx context.Context, asset string) error {
+	var checks []func() error
+	if sig, ok := s.files[".asc"]; ok {
+		checks = append(checks, func() error { return gpgVerify(ctx, sig, asset) })
+	}
+	if sig, ok := s.files[".sig"]; ok {
+		if cert, ok := s.files[".pem"]; ok {
+			checks = append(checks, func() error { return s.cosignVerify(ctx, asset, "--signature", sig, "--certificate", cert) })
+		} else {
+			checks = append(checks, func() error { return gpgVerify(ctx, sig, asset) })
+		}
+	}
+	if bundle, ok := s.files[".sigstore"]; ok {
+		checks = append(checks, func() error { return s.cosignVerify(ctx, asset, "--bundle", bundle) })
+	}
+	if bundle, ok := s.files[".sigstore.json"]; ok {
+		checks = append(checks, func() error { return s.cosignVerify(ctx, asset, "--bundle", bundle, "--new-bundle-format") })
+	}
+
+	verified := 0
+	var unchecked []error
+	for _, check := range checks {
+		err := check()
+		switch {
+		case err == nil:
+			verified++
+		case errors.Is(err, errCannotVerify):
+			unchecked = append(unchecked, err)
+		default:
+			return fmt.Errorf("%w: signature of %s: %v", releases.ErrVerificationFailed, s.c.AssetName, err)
+		}
+	}
+	if verified == 0 {
+		err := errors.Join(unchecked...)
+		if err == nil {
+			err = errors.New("no signature in a known format")
+		}
+		if verifyMode == "required" {
+			return fmt.Errorf("%w: %s: %v", releases.ErrVerificationFailed, s.c.AssetName, err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: not verifying the signature of %s: %v\n", s.c.AssetName, err)
+		return nil
+	}
+	fmt.Println("verified signature")
+	return nil
+}
+
+// gpgVerify checks the detached GPG signature sig of file against gpgKeyring, or the user's
+// keyring.
+func gpgVerify(ctx context.Context, sig, file string) error {
+	args := []string{"--batch", "--status-fd", "1"}
+	if gpgKeyring != "" {
+		keyring, err := filepath.Abs(gpgKeyring)
+		if err != nil {
+			return err
+		}
+		args = append(args, "--no-default-keyring", "--keyring", keyring)
+	}
+	args = append(args, "--verify", sig, file)
+	cmd := exec.CommandContext(ctx, "gpg", args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("%w: gpg is not installed", errCannotVerify)
+	}
+	status := map[string]string{}
+	sc := bufio.NewScanner(strings.NewReader(string(out)))
+	for sc.Scan() {
+		if fields := strings.Fields(sc.Text()); len(fields) >= 2 && fields[0] == "[GNUPG:]" {
+			status[fields[1]] = strings.Join(fields[2:], " ")
+		}
+	}
+	switch {
+	case status["BADSIG"] != "":
+		return fmt.Errorf("bad GPG signature by %s", status["BADSIG"])
+	case err == nil && status["VALIDSIG"] != "":
+		return nil
+	case status["NO_PUBKEY"] != "":
+		return fmt.Errorf("%w: GPG key %s is not in the keyring", errCannotVerify, status["NO_PUBKEY"])
+	case err != nil:
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		return fmt.Errorf("gpg could not verify the signature: %v: %s", err, lines[len(lines)-1])
+	}
+	return errors.New("gpg reported no valid signature")
+}
+
+// cosignVerify checks a keyless signature of file with cosign, requiring its certificate to
+// name a CI workflow of the repository the asset comes from.
+func (s *signatures) cosignVerify(ctx context.Context, file string, sigArgs ...string) error {
+	identity := "^" + regexp.QuoteMeta(githubWeb+s.c.RepoOwner+"/"+s.c.RepoName+"/")
+	args := append([]string{"verify-blob",
+		"--certificate-identity-regexp", identity,
+		"--certificate-oidc-issuer", sigstoreIssuer,
+	}, sigArgs...)
+	args = append(args, file)
+	out, err := exec.CommandContext(ctx, "cosign", args...).CombinedOutput()
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		return fmt.Errorf("%w: cosign is not installed", errCannotVerify)
+	case err != nil:
+		return fmt.Errorf("cosign: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// Update policies control what the daemon does when a newer release is published.
+const (
+	policyAuto   = "auto"   // download and install the new release
+	policyNotify = "notify" // report the new release but leave the install alone
+	policyPinned = "pinned" // never check for updates
+)
+
+// validPolicy reports whether p is a known update policy.
+func validPolicy(p string) bool {
+	return p == policyAuto || p == policyNotify || p == policyPinned
+}
+
+// installRecord describes a binary previously installed by this tool.
+type installRecord struct {
+	Name        string    `json:"name"`
+	Path        string    `json:"path"`
+	RepoOwner   string    `json:"repo_owner"`
+	RepoName    string    `json:"repo_name"`
+	Tag         string    `json:"tag"`
+	AssetName   string    `json:"asset_name"`
+	SHA256      string    `json:"sha256"`
+	FileSHA256  string    `json:"file_sha256,omitempty"` // digest of the installed file, if unpacked from the asset
+	InstalledAt time.Time `json:"installed_at"`
+	NotifiedTag string    `json:"notified_tag,omitempty"` // newest release already announced
+	InstalledBy string    `json:"installed_by,omitempty"` // version of get_gh_release that installed it
+	toolSettings
+
+	// The release this one replaced, for rolling back.
+	PreviousTag   string `json:"previous_tag,omitempty"`
+	PreviousAsset string `json:"previous_asset,omitempty"`
+}
+
+// reinstall makes installs replace a release that is already in place instead of skipping
+// it. It is set from the -reinstall flag.
+var reinstall bool
+
+// current reports whether r records c as installed and the file at r.Path still has the
+// recorded digest. A nil record is never current.
+func (r *installRecord) current(c releases.Candidate) bool {
+	if r == nil || r.Tag != c.Tag || r.AssetName != c.AssetName {
+		return false
+	}
+	digest, err := fileDigest(r.Path)
+	return err == nil && digest == r.installedDigest()
+}
+
+// installedDigest returns the digest the file at r.Path had when installed.
+func (r *installRecord) installedDigest() string {
+	if r.FileSHA256 != "" {
+		return r.FileSHA256
+	}
+	return r.SHA256
+}
+
+// unpackedDigest returns the digest of the file at path for FileSHA256: "" when it is the
+// asset with the given digest as published.
+func unpackedDigest(path, digest string) string {
+	if d, err := fileDigest(path); err == nil && d != digest {
+		return d
+	}
+	return ""
+}
+
+// toolSettings are chosen when a tool is installed and govern how it is updated later.
+type toolSettings struct {
+	Policy string     `json:"policy"`
+	Hooks  *toolHooks `json:"hooks,omitempty"`
+	MinAge string     `json:"min_age,omitempty"` // releases younger than this duration are not adopted
+	Block  []string   `json:"block,omitempty"`   // tags never adopted
+	Pre    bool       `json:"pre,omitempty"`     // prereleases may be adopted
+
+	// Manifest is the manifest whose apply or sync installed the tool, which sync -prune of
+	// that manifest may remove it for.
+	Manifest string `json:"manifest,omitempty"`
+
+	// The asset selection the tool was installed with, so that updates pick the same build.
+	Glob    string          `json:"asset_glob,omitempty"`
+	MinSize int64           `json:"min_size,omitempty"`
+	Rules   []releases.Rule `json:"rules,omitempty"`
+	Libc    string          `json:"libc,omitempty"` // musl, gnu, or none for no preference
+}
+
+// selectAssets records the asset selection of m in the settings. The default minimum size
+// is recorded as zero, which means the same, and a Linux install without a C library
+// preference as none.
+func (t *toolSettings) selectAssets(m releases.Matcher) {
+	t.Glob, t.MinSize, t.Rules, t.Libc = m.Glob, m.MinSize, m.Rules, m.Libc
+	if t.MinSize == releases.DefaultMinSize {
+		t.MinSize = 0
+	}
+	if t.Libc == "" && m.OS == "linux" {
+		t.Libc = "none"
+	}
+}
+
+// matcher returns the matcher that picks assets for platformOS/platformArch the way the
+// install did. Installs recorded before the selection was kept get the platform defaults.
+func (t toolSettings) matcher(platformOS, platformArch string) releases.Matcher {
+	libc := t.Libc
+	switch libc {
+	case "":
+		libc = platformLibc
+	case "none":
+		libc = ""
+	}
+	return releases.Matcher{OS: platformOS, Arch: platformArch, Libc: libc, Glob: t.Glob, MinSize: t.MinSize, Rules: t.Rules}
+}
+
+// filter returns the release filter for the settings.
+func (t toolSettings) filter() releases.ReleaseFilter {
+	d, _ := time.ParseDuration(t.MinAge)
+	return releases.ReleaseFilter{MinAge: d, Blocked: t.Block, Prereleases: t.Pre}
+}
+
+// installState is the on-disk registry of installed tools.
+type installState struct {
+	Tools []installRecord `json:"tools"`
+}
+
+// statePath returns the location of the state file, honouring XDG_STATE_HOME.
+func statePath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not locate home directory: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "get_gh_release", "state.json"), nil
+}
+
+// loadState reads the state file. A missing file yields an empty state.
+func loadState() (*installState, error) {
+	path, err := statePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &installState{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read state file: %w", err)
+	}
+	var s installState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("could not parse state file %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// save writes the state file, replacing it atomically.
+func (s *installState) save() error {
+	path, err := statePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("could not create state directory: %w", err)
+	}
+	sort.Slice(s.Tools, func(i, j int) bool { return s.Tools[i].Path < s.Tools[j].Path })
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("could not write state file: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// put adds or replaces the record for r.Path.
+func (s *installState) put(r installRecord) {
+	for i := range s.Tools {
+		if s.Tools[i].Path == r.Path {
+			s.Tools[i] = r
+			return
+		}
+	}
+	s.Tools = append(s.Tools, r)
+}
+
+// updateState applies f to the state file under its lock, saving the result unless f fails.
+// Concurrent runs each see the changes of the others.
+func updateState(f func(*installState) error) error {
+	path, err := statePath()
+	if err != nil {
+		return err
+	}
+	release, err := lockFile(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer release()
+	s, err := loadState()
+	if err != nil {
+		return err
+	}
+	if err := f(s); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// recordInstall stores a freshly installed candidate in the state file.
+func recordInstall(c releases.Candidate, dest, digest string, settings toolSettings) error {
+	path, err := filepath.Abs(dest)
+	if err != nil {
+		return err
+	}
+	return updateState(func(s *installState) error {
+		r := installRecord{
+			Name:         filepath.Base(path),
+			Path:         path,
+			RepoOwner:    c.RepoOwner,
+			RepoName:     c.RepoName,
+			Tag:          c.Tag,
+			AssetName:    c.AssetName,
+			SHA256:       digest,
+			FileSHA256:   unpackedDigest(path, digest),
+			InstalledAt:  time.Now().UTC(),
+			InstalledBy:  userAgent(),
+			toolSettings: settings,
+		}
+		if old := s.find(path); old != nil {
+			r.PreviousTag, r.PreviousAsset = old.PreviousTag, old.PreviousAsset
+			if old.Tag != c.Tag {
+				r.PreviousTag, r.PreviousAsset = old.Tag, old.AssetName
+			}
+		}
+		s.put(r)
+		return nil
+	})
+}
+
+// find returns the record installed at path, or nil.
+func (s *installState) find(path string) *installRecord {
+	for i := range s.Tools {
+		if s.Tools[i].Path == path {
+			return &s.Tools[i]
+		}
+	}
+	return nil
+}
+
+// installedAt returns the record of the install at dest, or nil if there is none or the
+// state cannot be read.
+func installedAt(dest string) *installRecord {
+	path, err := filepath.Abs(dest)
+	if err != nil {
+		return nil
+	}
+	s, err := loadState()
+	if err != nil {
+		return nil
+	}
+	return s.find(path)
+}
+
+// previousTag returns the tag recorded for the install at dest, or "".
+func previousTag(dest string) string {
+	if r := installedAt(dest); r != nil {
+		return r.Tag
+	}
+	return ""
+}
+
+// remove drops the record installed at path.
+func (s *installState) remove(path string) {
+	for i := range s.Tools {
+		if s.Tools[i].Path == path {
+			s.Tools = append(s.Tools[:i], s.Tools[i+1:]...)
+			return
+		}
+	}
+}
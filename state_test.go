@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+func TestToolSettingsLibc(t *testing.T) {
+	defer func(saved string) { platformLibc = saved }(platformLibc)
+	platformLibc = "gnu"
+	tests := []struct {
+		name     string
+		m        releases.Matcher
+		recorded string
+		want     string
+	}{
+		{name: "detected", m: releases.Matcher{OS: "linux", Libc: "gnu"}, recorded: "gnu", want: "gnu"},
+		{name: "overridden", m: releases.Matcher{OS: "linux", Libc: "musl"}, recorded: "musl", want: "musl"},
+		{name: "turned off", m: releases.Matcher{OS: "linux"}, recorded: "none", want: ""},
+		{name: "not linux", m: releases.Matcher{OS: "darwin"}, recorded: "", want: "gnu"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s toolSettings
+			s.selectAssets(tt.m)
+			if s.Libc != tt.recorded {
+				t.Errorf("recorded libc %q, want %q", s.Libc, tt.recorded)
+			}
+			if got := s.matcher(tt.m.OS, "amd64").Libc; got != tt.want {
+				t.Errorf("got matcher libc %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// runSummary times the phases of a run. Together with the counters in metrics it makes up
+// the report printed when the run ends.
+type runSummary struct {
+	start time.Time
+
+	mu     sync.Mutex
+	phases []phaseTiming
+}
+
+// phaseTiming is the time spent in one phase of a run.
+type phaseTiming struct {
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+}
+
+// summaryReport is the printed form of a runSummary.
+type summaryReport struct {
+	ReposScanned    int           `json:"repos_scanned"`
+	APIRequests     int           `json:"api_requests"`
+	CacheHits       int           `json:"cache_hits"`
+	BytesDownloaded int64         `json:"bytes_downloaded"`
+	Phases          []phaseTiming `json:"phases"`
+	Seconds         float64       `json:"seconds"`
+}
+
+// stats is the summary of the current run.
+var stats = &runSummary{start: time.Now()}
+
+// phase starts timing a phase of the run and returns the function that ends it.
+func (s *runSummary) phase(name string) func() {
+	start := time.Now()
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.phases = append(s.phases, phaseTiming{Name: name, Seconds: time.Since(start).Seconds()})
+	}
+}
+
+// report collects the figures of the run so far.
+func (s *runSummary) report() summaryReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return summaryReport{
+		ReposScanned:    int(metrics.total("get_gh_release_repos_scanned_total")),
+		APIRequests:     int(metrics.total("get_gh_release_api_requests_total")),
+		CacheHits:       int(metrics.get("get_gh_release_downloads_total", "source", "cache")),
+		BytesDownloaded: int64(metrics.total("get_gh_release_download_bytes_total") - metrics.get("get_gh_release_download_bytes_total", "source", "cache")),
+		Phases:          append([]phaseTiming{}, s.phases...),
+		Seconds:         time.Since(s.start).Seconds(),
+	}
+}
+
+// validSummaryFormat reports whether f is a format accepted by write.
+func validSummaryFormat(f string) bool {
+	return f == "text" || f == "json" || f == "none"
+}
+
+// write prints the summary as text or json; format none prints nothing.
+func (s *runSummary) write(w io.Writer, format string) error {
+	r := s.report()
+	switch format {
+	case "none":
+		return nil
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "text":
+		fmt.Fprintf(w, "Scanned %d repositories with %d API requests; %d cache hits, %d bytes downloaded in %s\n",
+			r.ReposScanned, r.APIRequests, r.CacheHits, r.BytesDownloaded, roundSeconds(r.Seconds))
+		for _, p := range r.Phases {
+			fmt.Fprintf(w, "  %-10s %s\n", p.Name, roundSeconds(p.Seconds))
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown summary format %q (want text, json or none)", format)
+}
+
+// roundSeconds formats a duration in seconds to the millisecond.
+func roundSeconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Millisecond)
+}
+
+// countingProvider counts the repositories whose releases are looked up for the summary.
+type countingProvider struct {
+	releases.ReleaseProvider
+}
+
+func (p countingProvider) ResolveRelease(ctx context.Context, owner, repo, versionPattern string) (*releases.Release, error) {
+	metrics.add("get_gh_release_repos_scanned_total", 1)
+	return p.ReleaseProvider.ResolveRelease(ctx, owner, repo, versionPattern)
+}
+
+// DownloadAssetFrom resumes a download if the wrapped provider can.
+func (p countingProvider) DownloadAssetFrom(ctx context.Context, c releases.Candidate, offset int64) (io.ReadCloser, error) {
+	r, ok := p.ReleaseProvider.(releases.Resumer)
+	if !ok {
+		return nil, fmt.Errorf("source cannot resume downloads")
+	}
+	return r.DownloadAssetFrom(ctx, c, offset)
+}
+
+func (p countingProvider) ListReleases(ctx context.Context, owner, repo string) ([]*releases.Release, error) {
+	metrics.add("get_gh_release_repos_scanned_total", 1)
+	return p.ReleaseProvider.ListReleases(ctx, owner, repo)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// runSync reconciles installed tools with a manifest: missing tools are installed, tools at a
+// different version are upgraded or downgraded, and with -prune tools the manifest installed
+// earlier but no longer lists are removed.
+func runSync(ctx context.Context, provider releases.ReleaseProvider, platformOS, platformArch string, args []string) error {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	locked := fs.Bool("locked", false, "Reconcile against the tags, assets and digests recorded in the lockfile.")
+	frozen := fs.Bool("frozen", false, "Like -locked, but fail before changing anything if the lockfile does not match the manifest.")
+	prune := fs.Bool("prune", false, "Remove tools this manifest installed earlier that it no longer lists.")
+	lockFlag := fs.String("lockfile", "", "Lockfile to read and write instead of the one next to the manifest.")
+	maxFailures := fs.Int("max-failures", 0, "Number of tools that may fail to sync before the command fails.")
+	jobs := fs.Int("jobs", 4, "Number of assets downloaded at once before installing them in order; 1 downloads each as it is installed.")
+	fs.Parse(args)
+	file, err := manifestArg(fs)
+	if err != nil {
+		return fmt.Errorf("usage: get_gh_release sync [-locked|-frozen] [-prune] [-lockfile file] [-max-failures n] [-jobs n] [manifest.yaml]: %w", err)
+	}
+
+	m, err := loadManifest(file)
+	if err != nil {
+		return err
+	}
+	lockFile := lockPath(file)
+	if *lockFlag != "" {
+		lockFile = *lockFlag
+	}
+	lock, err := manifestLock(m, lockFile, *locked, *frozen)
+	if err != nil {
+		return err
+	}
+	b := batch{maxFailures: *maxFailures}
+	resolved, err := reconcile(ctx, provider, m, lock, lockFile, *prune, *jobs, platformOS, platformArch, &b)
+	b.printTable(os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	if lock != nil || lockFile == "" {
+		return b.err()
+	}
+	if b.failed() > 0 {
+		// A lockfile missing the failed tools would drop their pins
+		fmt.Printf("%s not updated\n", lockFile)
+		return b.err()
+	}
+	if err := resolved.save(lockFile); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s\n", lockFile)
+	return nil
+}
+
+// reconcile brings the installed tools in line with m and returns the resolved lock entries.
+// The assets of the tools to install are downloaded jobs at a time before the installs. The
+// outcome for each tool is recorded in b; pruning is skipped if any tool failed, since the
+// install path of a tool that could not be resolved is unknown.
+func reconcile(ctx context.Context, provider releases.ReleaseProvider, m *manifest, lock *lockfile, lockFile string, prune bool, jobs int, platformOS, platformArch string, b *batch) (lockfile, error) {
+	var resolved lockfile
+	st, err := loadState()
+	if err != nil {
+		return resolved, err
+	}
+
+	wanted := map[string]bool{}
+	steps := make([]manifestStep, len(m.Tools))
+	for i, t := range m.Tools {
+		s := &steps[i]
+		s.tool = t
+		if lock != nil {
+			if s.pin = lock.find(t.Repo); s.pin == nil {
+				s.err = fmt.Errorf("not present in lockfile %s", lockFile)
+				continue
+			}
+		}
+		if s.c, s.dest, s.err = resolveManifestTool(ctx, provider, m, t, s.pin, platformOS, platformArch); s.err != nil {
+			continue
+		}
+		wanted[s.dest] = true
+		rec := st.find(s.dest)
+		s.current = !reinstall && rec.current(s.c) && (s.pin == nil || rec.SHA256 == s.pin.SHA256) && (t.SHA256 == "" || rec.SHA256 == t.SHA256)
+	}
+	prefetch(ctx, provider, steps, jobs)
+
+	for _, s := range steps {
+		if s.err != nil {
+			b.add(s.tool.Repo, s.c.Tag, s.err)
+			continue
+		}
+		rec := st.find(s.dest)
+		if s.current {
+			fmt.Printf("%s: up to date (%s)\n", s.dest, s.c.Tag)
+			resolved.Tools = append(resolved.Tools, lockedCandidate(s.tool.Repo, s.c, rec.SHA256))
+			b.add(s.tool.Repo, s.c.Tag, nil)
+			continue
+		}
+		if rec == nil {
+			fmt.Printf("%s: installing %s\n", s.dest, s.c.Tag)
+		} else {
+			fmt.Printf("%s: changing %s -> %s\n", s.dest, rec.Tag, s.c.Tag)
+		}
+		lt, err := installResolved(ctx, provider, m, s.tool, s.pin, s.c, s.dest)
+		b.add(s.tool.Repo, s.c.Tag, err)
+		if err != nil {
+			continue
+		}
+		resolved.Tools = append(resolved.Tools, lt)
+	}
+
+	if prune && b.failed() == 0 {
+		if m.file == "" {
+			fmt.Println("Not pruning: a manifest read from standard input installed nothing earlier")
+		} else if err := pruneInstalls(wanted, m.file); err != nil {
+			return resolved, err
+		}
+	}
+	return resolved, nil
+}
+
+// pruneInstalls removes the recorded installs of the manifest file that are not in wanted.
+// Tools installed by hand or by other manifests are left alone, wherever they live.
+func pruneInstalls(wanted map[string]bool, file string) error {
+	return updateState(func(st *installState) error {
+		for _, r := range append([]installRecord(nil), st.Tools...) {
+			if wanted[r.Path] || r.Manifest != file {
+				continue
+			}
+			if err := os.Remove(r.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("could not remove %s: %w", r.Path, err)
+			}
+			fmt.Printf("%s: removed\n", r.Path)
+			st.remove(r.Path)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// restartTemplates maps a restart policy name to the [Service] directives it expands to.
+var restartTemplates = map[string]string{
+	"always":     "Restart=always\nRestartSec=5",
+	"on-failure": "Restart=on-failure\nRestartSec=10",
+	"no":         "Restart=no",
+}
+
+// systemdUnitTemplate is the service unit written for a downloaded binary.
+var systemdUnitTemplate = template.Must(template.New("unit").Parse(`[Unit]
+Description={{.Name}} (installed by get_gh_release from {{.Source}})
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+ExecStart={{.ExecStart}}
+{{.Restart}}
+
+[Install]
+WantedBy=default.target
+`))
+
+// renderSystemdUnit builds the unit file contents for the given binary and restart policy.
+func renderSystemdUnit(name, source, execPath, restart string) (string, error) {
+	directives, ok := restartTemplates[restart]
+	if !ok {
+		return "", fmt.Errorf("unknown restart policy %q", restart)
+	}
+	execStart, err := systemdQuote(execPath)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	err = systemdUnitTemplate.Execute(&b, struct {
+		Name, Source, ExecStart, Restart string
+	}{name, source, execStart, directives})
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// systemdQuote quotes a path as a single ExecStart= word. systemd would otherwise split it
+// at spaces and expand % specifiers and $ variables in it, so those are escaped. Paths with
+// control characters cannot be written into a unit file at all.
+func systemdQuote(path string) (string, error) {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range path {
+		switch {
+		case r < 0x20 || r == 0x7f:
+			return "", fmt.Errorf("cannot run %q from a systemd unit: it contains a control character", path)
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+		case r == '%':
+			b.WriteByte('%')
+		case r == '$':
+			b.WriteByte('$')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String(), nil
+}
+
+// emitSystemdUnit prints a service unit for the candidate installed at dest and optionally
+// installs it into the user's systemd directory.
+func emitSystemdUnit(c releases.Candidate, dest, restart string, install bool) error {
+	execPath, err := filepath.Abs(dest)
+	if err != nil {
+		return fmt.Errorf("could not resolve binary path: %w", err)
+	}
+	unit, err := renderSystemdUnit(c.RepoName, c.RepoOwner+"/"+c.RepoName, execPath, restart)
+	if err != nil {
+		return err
+	}
+	if !install {
+		fmt.Print(unit)
+		return nil
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return fmt.Errorf("could not locate user config directory: %w", err)
+	}
+	unitDir := filepath.Join(configDir, "systemd", "user")
+	if err := os.MkdirAll(unitDir, 0755); err != nil {
+		return fmt.Errorf("could not create %s: %w", unitDir, err)
+	}
+	unitPath := filepath.Join(unitDir, c.RepoName+".service")
+	if err := os.WriteFile(unitPath, []byte(unit), 0644); err != nil {
+		return fmt.Errorf("could not write unit file: %w", err)
+	}
+	fmt.Printf("installed unit %s\n", unitPath)
+	fmt.Printf("enable with: systemctl --user daemon-reload && systemctl --user enable --now %s.service\n", c.RepoName)
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSystemdQuote(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"/home/me/bin/tool", `"/home/me/bin/tool"`},
+		{"/home/me/My Tools/tool", `"/home/me/My Tools/tool"`},
+		{"/srv/100%/tool", `"/srv/100%%/tool"`},
+		{"/srv/$HOME/tool", `"/srv/$$HOME/tool"`},
+		{`/srv/a"b\c/tool`, `"/srv/a\"b\\c/tool"`},
+	}
+	for _, tt := range tests {
+		got, err := systemdQuote(tt.path)
+		if err != nil {
+			t.Errorf("systemdQuote(%q): %v", tt.path, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("systemdQuote(%q) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+	if _, err := systemdQuote("/srv/a\nb"); err == nil {
+		t.Error("systemdQuote accepted a path with a newline")
+	}
+}
+
+func TestRenderSystemdUnit(t *testing.T) {
+	unit, err := renderSystemdUnit("tool", "me/tool", "/opt/my tools/tool", "always")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(unit, "\nExecStart=\"/opt/my tools/tool\"\n") {
+		t.Errorf("unit has no quoted ExecStart:\n%s", unit)
+	}
+	if !strings.Contains(unit, "\nRestart=always\n") {
+		t.Errorf("unit has no restart policy:\n%s", unit)
+	}
+	if _, err := renderSystemdUnit("tool", "me/tool", "/opt/tool", "sometimes"); err == nil {
+		t.Error("renderSystemdUnit accepted an unknown restart policy")
+	}
+}
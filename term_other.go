@@ -0,0 +1,18 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// The dashboard drives the terminal directly and is only available on Linux.
+
+func makeRaw(f *os.File) (func(), error) {
+	return nil, errors.New("the dashboard is only available on Linux")
+}
+
+func terminalSize(f *os.File) (int, int) { return 80, 24 }
+
+func notifyResize(ch chan<- os.Signal) {}
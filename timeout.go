@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// errTimedOut is a request given up on by timeoutTransport. Unlike a cancelled run, it is
+// an ordinary failure.
+var errTimedOut = errors.New("timed out")
+
+// timeoutTransport fails requests whose response has not started within timeout, and
+// responses whose body then stalls for as long. Downloads of any size can take as long as
+// they need as long as data keeps arriving.
+type timeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	var expired atomic.Bool
+	timer := time.AfterFunc(t.timeout, func() {
+		expired.Store(true)
+		cancel()
+	})
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		timer.Stop()
+		cancel()
+		if expired.Load() {
+			err = fmt.Errorf("%w: no response from %s within %s", errTimedOut, req.URL.Host, t.timeout)
+		}
+		return nil, err
+	}
+	timer.Reset(t.timeout)
+	resp.Body = &idleTimeoutBody{rc: resp.Body, timer: timer, timeout: t.timeout, expired: &expired, cancel: cancel}
+	return resp, nil
+}
+
+// idleTimeoutBody is a response body that gives up when no data arrives for timeout.
+type idleTimeoutBody struct {
+	rc      io.ReadCloser
+	timer   *time.Timer
+	timeout time.Duration
+	expired *atomic.Bool
+	cancel  context.CancelFunc
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	if err != nil && err != io.EOF && b.expired.Load() {
+		err = fmt.Errorf("%w: no data received for %s", errTimedOut, b.timeout)
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	b.cancel()
+	return b.rc.Close()
+}
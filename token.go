@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// keyringService is the service name tokens are stored under in the system keyring.
+const keyringService = "get_gh_release"
+
+// getToken resolves the GitHub token for host from, in order: the -token flag, GH_TOKEN,
+// the static constant, a token stored by login, the gh CLI's stored credentials, and
+// ~/.netrc. It returns "" if none has one.
+func getToken(tokenFlag, host string) string {
+	if tokenFlag != "" {
+		return tokenFlag
+	}
+	if token := os.Getenv("GH_TOKEN"); token != "" {
+		return token
+	}
+	if staticToken != "" {
+		return staticToken
+	}
+	if token := storedToken(host); token != "" {
+		return token
+	}
+	if token := ghCLIToken(host); token != "" {
+		return token
+	}
+	return netrcToken(host)
+}
+
+// apiHost returns the host name tokens are looked up for: github.com, or the host of a
+// GitHub Enterprise Server's API URL.
+func apiHost(apiURL string) string {
+	if apiURL == "" {
+		return "github.com"
+	}
+	if u, err := url.Parse(apiURL); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	return apiURL
+}
+
+// ghCLIToken returns the token the gh CLI has stored for host, or "" if gh is not installed
+// or not logged in there.
+func ghCLIToken(host string) string {
+	out, err := exec.Command("gh", "auth", "token", "--hostname", host).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// netrcToken returns the password ~/.netrc (or the file named by NETRC) gives for host or its
+// API host, or for the default machine.
+func netrcToken(host string) string {
+	file := os.Getenv("NETRC")
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		file = filepath.Join(home, ".netrc")
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	var machine, fallback string
+	found := map[string]string{}
+	fields := strings.Fields(string(data))
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			if i+1 < len(fields) {
+				i++
+				machine = fields[i]
+			}
+		case "default":
+			machine = ""
+		case "password":
+			if i+1 < len(fields) {
+				i++
+				if machine == "" {
+					fallback = fields[i]
+				} else if _, ok := found[machine]; !ok {
+					found[machine] = fields[i]
+				}
+			}
+		case "macdef":
+			// A macro runs to the next blank line, which Fields has lost; stop rather than
+			// misread it
+			i = len(fields)
+		}
+	}
+	for _, m := range []string{host, "api." + host} {
+		if p := found[m]; p != "" {
+			return p
+		}
+	}
+	return fallback
+}
+
+// keyringGet returns the token stored in the system keyring for host.
+func keyringGet(host string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keyringService, "-a", host, "-w")
+	case "windows":
+		return "", errors.New("the system keyring is not supported on Windows")
+	default:
+		cmd = exec.Command("secret-tool", "lookup", "service", keyringService, "host", host)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// keyringSet stores token for host in the system keyring: the macOS keychain, or the Secret
+// Service (GNOME Keyring, KWallet) through secret-tool elsewhere.
+func keyringSet(host, token string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// With -w last, security prompts for the password, and its confirmation, on standard
+		// input, so the token never shows up in the process list
+		cmd = exec.Command("security", "add-generic-password", "-U", "-s", keyringService, "-a", host, "-w")
+		cmd.Stdin = strings.NewReader(token + "\n" + token + "\n")
+	case "windows":
+		return errors.New("the system keyring is not supported on Windows")
+	default:
+		cmd = exec.Command("secret-tool", "store", "--label", "get_gh_release token for "+host, "service", keyringService, "host", host)
+		cmd.Stdin = strings.NewReader(token)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return fmt.Errorf("%s is not installed", cmd.Args[0])
+		}
+		return fmt.Errorf("%s failed: %v: %s", cmd.Args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// tokenFile is where a token for host is kept when there is no system keyring.
+func tokenFile(host string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "get_gh_release", "token-"+host), nil
+}
+
+// storedToken returns the token login stored for host, or "".
+func storedToken(host string) string {
+	if token, err := keyringGet(host); err == nil && token != "" {
+		return token
+	}
+	file, err := tokenFile(host)
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// storeToken keeps token for host in the system keyring or, where there is none, in a file
+// only the user can read. It returns a description of where the token went.
+func storeToken(host, token string) (string, error) {
+	kerr := keyringSet(host, token)
+	if kerr == nil {
+		return "the system keyring", nil
+	}
+	file, err := tokenFile(host)
+	if err != nil {
+		return "", kerr
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(file, []byte(token+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s (no system keyring: %v)", file, kerr), nil
+}
+
+// runLogin stores a GitHub token for later runs, so that it never has to be passed on the
+// command line or kept in the environment. The token is read from standard input, without
+// echo on a terminal, or with -device obtained by authorising this machine in a browser.
+func runLogin(ctx context.Context, apiURL string, args []string) error {
+	fs := flag.NewFlagSet("login", flag.ExitOnError)
+	device := fs.Bool("device", false, "Obtain a token through GitHub's device flow: enter a code shown here on GitHub's website.")
+	clientID := fs.String("client-id", cmp.Or(os.Getenv("GET_GH_RELEASE_CLIENT_ID"), oauthClientID), "Client ID of the OAuth app used by -device.")
+	scopes := fs.String("scopes", "repo", "Comma-separated OAuth scopes requested by -device.")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: get_gh_release login [-device [-client-id id] [-scopes list]] [< token]")
+	}
+	host := apiHost(apiURL)
+	var token string
+	var err error
+	if *device {
+		token, err = deviceFlowToken(ctx, webRoot(apiURL), *clientID, splitList(*scopes))
+	} else {
+		fmt.Fprintf(os.Stderr, "Token for %s: ", host)
+		token, err = readSecret(os.Stdin)
+		fmt.Fprintln(os.Stderr)
+	}
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return errors.New("no token given")
+	}
+	where, err := storeToken(host, token)
+	if err != nil {
+		return fmt.Errorf("could not store token: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Stored the token for %s in %s.\n", host, where)
+	return nil
+}
+
+// readSecret reads one line from f, without echoing it when f is a terminal.
+func readSecret(f *os.File) (string, error) {
+	restore, err := makeRaw(f)
+	if err != nil {
+		line, err := bufio.NewReader(f).ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimSpace(line), nil
+	}
+	defer restore()
+	var secret []byte
+	buf := make([]byte, 1)
+	for {
+		if _, err := f.Read(buf); err != nil {
+			return "", err
+		}
+		switch buf[0] {
+		case '\r', '\n':
+			return strings.TrimSpace(string(secret)), nil
+		case 3, 4: // Ctrl-C, Ctrl-D
+			return "", errors.New("cancelled")
+		case 127, 8: // Backspace
+			if len(secret) > 0 {
+				secret = secret[:len(secret)-1]
+			}
+		default:
+			secret = append(secret, buf[0])
+		}
+	}
+}
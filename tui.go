@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// dashboardRow is one managed tool and what is known about its latest release.
+type dashboardRow struct {
+	rec     installRecord
+	latest  *releases.Release // nil until fetched
+	err     error
+	checked bool
+}
+
+// dashboard is the full-screen view of the managed tools. Its state is only touched by the
+// event loop; background work reports back by sending closures on events.
+type dashboard struct {
+	ctx          context.Context
+	provider     releases.ReleaseProvider
+	platformOS   string
+	platformArch string
+
+	term   *os.File
+	rows   []dashboardRow
+	sel    int
+	status string
+	busy   bool
+	events chan func()
+}
+
+// runDashboard shows the installed tools with their latest releases and lets the user
+// update, pin or roll back each one.
+func runDashboard(ctx context.Context, provider releases.ReleaseProvider, platformOS, platformArch string, args []string) error {
+	fs := flag.NewFlagSet("tui", flag.ExitOnError)
+	fs.Parse(args)
+
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+	if len(st.Tools) == 0 {
+		return fmt.Errorf("no installed tools are recorded")
+	}
+	if !interactive() {
+		return fmt.Errorf("the dashboard needs an interactive terminal")
+	}
+	d := &dashboard{
+		ctx:          ctx,
+		provider:     provider,
+		platformOS:   platformOS,
+		platformArch: platformArch,
+		term:         os.Stdout,
+		events:       make(chan func(), 16),
+	}
+	for _, t := range st.Tools {
+		d.rows = append(d.rows, dashboardRow{rec: t})
+	}
+
+	restore, err := makeRaw(os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	// Progress output from installs and hooks would corrupt the screen
+	stdout, stderr := os.Stdout, os.Stderr
+	if devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0); err == nil {
+		os.Stdout, os.Stderr = devNull, devNull
+		defer func() {
+			os.Stdout, os.Stderr = stdout, stderr
+			devNull.Close()
+		}()
+	}
+	fmt.Fprint(d.term, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(d.term, "\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan string)
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- string(buf[:n])
+		}
+	}()
+	resize := make(chan os.Signal, 1)
+	notifyResize(resize)
+
+	d.checkLatest()
+	for {
+		d.draw()
+		select {
+		case k, ok := <-keys:
+			if !ok || !d.key(k) {
+				return nil
+			}
+		case f := <-d.events:
+			f()
+		case <-resize:
+		}
+	}
+}
+
+// checkLatest looks up the latest release of every tool in the background, a few at a time.
+func (d *dashboard) checkLatest() {
+	sem := make(chan struct{}, 4)
+	for i := range d.rows {
+		rec := d.rows[i].rec
+		go func() {
+			sem <- struct{}{}
+			release, _, err := rec.filter().Resolve(d.ctx, d.provider, rec.RepoOwner, rec.RepoName, configPin(rec.RepoOwner, rec.RepoName))
+			<-sem
+			d.events <- func() {
+				d.rows[i].latest, d.rows[i].err, d.rows[i].checked = release, err, true
+			}
+		}()
+	}
+}
+
+// key handles one key press and reports whether the dashboard stays open.
+func (d *dashboard) key(k string) bool {
+	switch k {
+	case "q", "\x03", "\x1b":
+		return false
+	case "k", "\x1b[A", "\x1bOA":
+		d.sel = max(d.sel-1, 0)
+	case "j", "\x1b[B", "\x1bOB":
+		d.sel = min(d.sel+1, len(d.rows)-1)
+	case "u":
+		d.update()
+	case "p":
+		d.togglePin()
+	case "r":
+		d.rollBack()
+	}
+	return true
+}
+
+// update installs the latest release of the selected tool.
+func (d *dashboard) update() {
+	row := d.rows[d.sel]
+	switch {
+	case d.busy:
+		return
+	case row.latest == nil:
+		d.status = row.rec.Name + ": latest release not known"
+		return
+	case row.latest.Tag == row.rec.Tag:
+		d.status = row.rec.Name + " is up to date"
+		return
+	}
+	asset := row.rec.matcher(d.platformOS, d.platformArch).Match(row.latest)
+	if asset == nil {
+		d.status = fmt.Sprintf("%s: release %s has no asset for %s/%s", row.rec.Name, row.latest.Tag, d.platformOS, d.platformArch)
+		return
+	}
+	d.install(row.rec, releases.NewCandidate(row.rec.RepoOwner, row.rec.RepoName, row.latest, *asset))
+}
+
+// rollBack reinstalls the release the selected tool replaced.
+func (d *dashboard) rollBack() {
+	rec := d.rows[d.sel].rec
+	if d.busy {
+		return
+	}
+	if rec.PreviousTag == "" {
+		d.status = rec.Name + ": no previous version recorded"
+		return
+	}
+	d.busy = true
+	d.status = fmt.Sprintf("%s: fetching %s…", rec.Name, rec.PreviousTag)
+	go func() {
+		release, err := d.provider.ReleaseByTag(d.ctx, rec.RepoOwner, rec.RepoName, rec.PreviousTag)
+		d.events <- func() {
+			d.busy = false
+			if err != nil {
+				d.status = fmt.Sprintf("%s: %v", rec.Name, err)
+				return
+			}
+			for _, a := range release.Assets {
+				if a.Name == rec.PreviousAsset {
+					d.install(rec, releases.NewCandidate(rec.RepoOwner, rec.RepoName, release, a))
+					return
+				}
+			}
+			d.status = fmt.Sprintf("%s: release %s no longer has asset %s", rec.Name, rec.PreviousTag, rec.PreviousAsset)
+		}
+	}()
+}
+
+// install replaces the selected tool with c in the background.
+func (d *dashboard) install(rec installRecord, c releases.Candidate) {
+	d.busy = true
+	d.status = fmt.Sprintf("%s: installing %s…", rec.Name, c.Tag)
+	go func() {
+		err := withHooks(d.ctx, rec.Hooks, c, rec.Path, rec.Tag, func() error {
+			digest, err := downloadAndPrepare(d.ctx, d.provider, c, rec.Path, installStages(c))
+			if err != nil {
+				return err
+			}
+			return recordInstall(c, rec.Path, digest, rec.toolSettings)
+		})
+		d.events <- func() {
+			d.busy = false
+			if err != nil {
+				d.status = fmt.Sprintf("%s: %v", rec.Name, err)
+				return
+			}
+			d.status = fmt.Sprintf("%s: installed %s", rec.Name, c.Tag)
+			d.reload()
+		}
+	}()
+}
+
+// togglePin switches the selected tool between the pinned and notify policies.
+func (d *dashboard) togglePin() {
+	rec := d.rows[d.sel].rec
+	policy := policyPinned
+	if rec.Policy == policyPinned {
+		policy = policyNotify
+	}
+	err := updateState(func(s *installState) error {
+		if r := s.find(rec.Path); r != nil {
+			r.Policy = policy
+		}
+		return nil
+	})
+	if err != nil {
+		d.status = fmt.Sprintf("%s: %v", rec.Name, err)
+		return
+	}
+	d.status = fmt.Sprintf("%s: policy %s", rec.Name, policy)
+	d.reload()
+}
+
+// reload refreshes the install records from the state file, keeping the latest releases.
+func (d *dashboard) reload() {
+	st, err := loadState()
+	if err != nil {
+		d.status = err.Error()
+		return
+	}
+	for i := range d.rows {
+		if r := st.find(d.rows[i].rec.Path); r != nil {
+			d.rows[i].rec = *r
+		}
+	}
+}
+
+// draw repaints the whole screen.
+func (d *dashboard) draw() {
+	width, height := terminalSize(d.term)
+	var b bytes.Buffer
+	b.WriteString("\x1b[H\x1b[2J")
+	line := func(s string) {
+		b.WriteString(truncate(s, width) + "\r\n")
+	}
+
+	line(fmt.Sprintf("\x1b[1mget_gh_release\x1b[0m  %d managed tools", len(d.rows)))
+	line("")
+	var table bytes.Buffer
+	tw := tabwriter.NewWriter(&table, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TOOL\tINSTALLED\tLATEST\tPOLICY\tUPDATED\t")
+	for _, r := range d.rows {
+		latest := "…"
+		switch {
+		case r.err != nil:
+			latest = "error"
+		case r.latest != nil && r.latest.Tag != r.rec.Tag:
+			latest = r.latest.Tag + " *"
+		case r.latest != nil:
+			latest = r.latest.Tag
+		case r.checked:
+			latest = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t\n", r.rec.Name, r.rec.Tag, latest, r.rec.Policy, r.rec.InstalledAt.Local().Format(time.DateOnly))
+	}
+	tw.Flush()
+	for i, l := range strings.Split(strings.TrimRight(table.String(), "\n"), "\n") {
+		l = truncate(" "+l, width)
+		if i-1 == d.sel {
+			l = "\x1b[7m" + l + "\x1b[0m"
+		}
+		b.WriteString(l + "\r\n")
+	}
+	used := len(d.rows) + 3
+
+	// Pending release notes of the selected tool fill the space left
+	if row := d.rows[d.sel]; row.latest != nil && row.latest.Tag != row.rec.Tag && height-used > 5 {
+		line("")
+		line(fmt.Sprintf("\x1b[1mRelease notes for %s %s\x1b[0m", row.rec.Name, row.latest.Tag))
+		var notes bytes.Buffer
+		renderMarkdown(&notes, row.latest.Body, false)
+		for i, l := range strings.Split(strings.TrimRight(notes.String(), "\n"), "\n") {
+			if i >= height-used-5 {
+				break
+			}
+			line(l)
+		}
+	} else if row.err != nil {
+		line("")
+		line(row.err.Error())
+	}
+
+	fmt.Fprintf(&b, "\x1b[%d;1H", height-1)
+	line(d.status)
+	b.WriteString(truncate("↑/↓ select  u update  p pin/unpin  r roll back  q quit", width))
+	d.term.Write(b.Bytes())
+}
+
+// truncate cuts s to at most width runes of visible text, keeping ANSI escapes intact.
+func truncate(s string, width int) string {
+	var b strings.Builder
+	visible := 0
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			j := strings.IndexFunc(s[i+1:], func(r rune) bool { return r >= '@' && r <= '~' && r != '[' })
+			if j >= 0 {
+				b.WriteString(s[i : i+j+2])
+				i += j + 2
+				continue
+			}
+		}
+		if visible == width {
+			break
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteRune(r)
+		visible++
+		i += size
+	}
+	return b.String()
+}
@@ -0,0 +1,27 @@
+package main
+
+import "net/http"
+
+// userAgent identifies this tool, down to the commit it was built from, in the logs of
+// GitHub and GitHub Enterprise servers.
+func userAgent() string {
+	if c := shortCommit(); c != "" {
+		return "get_gh_release/" + version + " (" + c + ")"
+	}
+	return "get_gh_release/" + version
+}
+
+// headerTransport sets fixed headers, such as the User-Agent and a request ID, on every
+// request it passes to base.
+type headerTransport struct {
+	base   http.RoundTripper
+	header http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.header {
+		req.Header[k] = v
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// A cassette holds captured HTTP exchanges. -record writes one while talking to GitHub and
+// -replay answers requests from it without touching the network, so a search can be re-run
+// offline and without a token. Request credentials are never stored, nor are the signatures
+// of pre-signed download URLs or the bodies of assets.
+type cassette struct {
+	Interactions []interaction `json:"interactions"`
+}
+
+// interaction is one request and the response it received. The body of an asset, or of any
+// response over maxRecordedBody, is left out and Omitted set instead.
+type interaction struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Status  int         `json:"status"`
+	Header  http.Header `json:"header"`
+	Body    []byte      `json:"body"`
+	Omitted bool        `json:"omitted,omitempty"`
+}
+
+// maxRecordedBody is the largest response body a cassette stores.
+const maxRecordedBody = 16 << 20
+
+// stopRecording writes the cassette of -record. fatalf calls it too, so that a failed run
+// still leaves the exchanges that led up to the failure.
+var stopRecording = func() {}
+
+// recordingTransport passes requests to base and collects each exchange for a cassette file,
+// which save writes once the run is over. Asset bodies, and any body over maxRecordedBody,
+// stream through unrecorded instead of being held in memory.
+type recordingTransport struct {
+	base http.RoundTripper
+	file string
+
+	mu       sync.Mutex
+	cassette cassette
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	in := interaction{
+		Method: req.Method,
+		URL:    redactURL(req.URL.String()),
+		Status: resp.StatusCode,
+		Header: redactHeader(resp.Header),
+	}
+	if isAssetBody(req, resp) {
+		in.Omitted = true
+	} else {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxRecordedBody+1))
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		if len(body) > maxRecordedBody {
+			resp.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+			in.Omitted = true
+		} else {
+			resp.Body.Close()
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			in.Body = body
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cassette.Interactions = append(t.cassette.Interactions, in)
+	return resp, nil
+}
+
+// save writes the exchanges recorded so far to the cassette file.
+func (t *recordingTransport) save() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	data, err := json.MarshalIndent(t.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(t.file, data, 0600); err != nil {
+		return fmt.Errorf("could not write cassette: %w", err)
+	}
+	return nil
+}
+
+// isAssetBody reports whether resp carries the contents of a release asset rather than an
+// API document.
+func isAssetBody(req *http.Request, resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	return req.Header.Get("Accept") == "application/octet-stream" ||
+		strings.HasPrefix(resp.Header.Get("Content-Type"), "application/octet-stream")
+}
+
+// redactHeader returns a copy of h without cookies and with the signatures of a redirect's
+// pre-signed URL removed.
+func redactHeader(h http.Header) http.Header {
+	h = h.Clone()
+	h.Del("Set-Cookie")
+	if loc := h.Get("Location"); loc != "" {
+		h.Set("Location", redactURL(loc))
+	}
+	return h
+}
+
+// redactURL replaces the values of query parameters that sign or authorise a request, such
+// as the X-Amz-Signature and jwt of GitHub's pre-signed asset URLs, with "REDACTED". Replay
+// redacts the URLs it is asked for the same way, so the redirects it hands out still match.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return raw
+	}
+	q := u.Query()
+	changed := false
+	for name := range q {
+		if secretParam(name) {
+			q.Set(name, "REDACTED")
+			changed = true
+		}
+	}
+	if !changed {
+		return raw
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// secretParam reports whether a query parameter of the given name carries a credential or
+// a signature.
+func secretParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"sig", "token", "jwt", "credential", "policy", "key"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// replayTransport answers requests from a cassette. Exchanges for the same method and URL
+// are replayed in recorded order; once used up, the last one is repeated.
+type replayTransport struct {
+	mu       sync.Mutex
+	cassette cassette
+	used     []bool
+}
+
+// loadReplayTransport reads a cassette written by -record.
+func loadReplayTransport(file string) (*replayTransport, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read cassette: %w", err)
+	}
+	t := &replayTransport{}
+	if err := json.Unmarshal(data, &t.cassette); err != nil {
+		return nil, fmt.Errorf("could not parse cassette %s: %w", file, err)
+	}
+	t.used = make([]bool, len(t.cassette.Interactions))
+	return t, nil
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	match := -1
+	for i, in := range t.cassette.Interactions {
+		if in.Method != req.Method || in.URL != redactURL(req.URL.String()) {
+			continue
+		}
+		match = i
+		if !t.used[i] {
+			break
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, req.URL)
+	}
+	t.used[match] = true
+	in := t.cassette.Interactions[match]
+	var body io.Reader = bytes.NewReader(in.Body)
+	length := int64(len(in.Body))
+	if in.Omitted {
+		body, length = omittedBody{in.URL}, -1
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Status, http.StatusText(in.Status)),
+		StatusCode:    in.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        in.Header.Clone(),
+		Body:          io.NopCloser(body),
+		ContentLength: length,
+		Request:       req,
+	}, nil
+}
+
+// omittedBody stands in for a response body the cassette left out: reading it fails.
+type omittedBody struct {
+	url string
+}
+
+func (b omittedBody) Read([]byte) (int, error) {
+	return 0, fmt.Errorf("the cassette does not hold the body of %s", b.url)
+}
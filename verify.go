@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+)
+
+// runVerify re-hashes installed binaries and compares them with the digests recorded when
+// they were installed, flagging files that were modified or removed since. Arguments limit
+// the check to the named tools or install paths.
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	fs.Parse(args)
+
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+	wanted := map[string]bool{}
+	for _, a := range fs.Args() {
+		wanted[a] = true
+		if abs, err := filepath.Abs(a); err == nil {
+			wanted[abs] = true
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tTAG\tRESULT\t")
+	checked, bad := 0, 0
+	for _, t := range st.Tools {
+		if len(wanted) > 0 && !wanted[t.Name] && !wanted[t.Path] {
+			continue
+		}
+		checked++
+		result := "ok"
+		switch digest, err := fileDigest(t.Path); {
+		case errors.Is(err, os.ErrNotExist):
+			result = "MISSING"
+		case err != nil:
+			result = "ERROR: " + err.Error()
+		case t.SHA256 == "":
+			result = "no recorded digest"
+		case releases.Verify(t.Name, digest, t.installedDigest()) != nil:
+			result = "MODIFIED"
+		}
+		if result != "ok" && result != "no recorded digest" {
+			bad++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t\n", t.Path, t.Tag, result)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(wanted) > 0 && checked == 0 {
+		return fmt.Errorf("no installed tool matches %v", fs.Args())
+	}
+	if bad > 0 {
+		return fmt.Errorf("%w: %d of %d installed tools changed since installation", releases.ErrVerificationFailed, bad, checked)
+	}
+	return nil
+}
+
+// fileDigest returns the hex-encoded SHA-256 digest of the file at path.
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
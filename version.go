@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, set by release builds with
+// -ldflags "-X main.version=v1.2.3 -X main.commit=... -X main.date=...".
+// Builds without them fall back to what the Go toolchain recorded in the binary.
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
+
+func init() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	// go install module@version records the module version
+	if version == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		version = info.Main.Version
+	}
+	modified := false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if commit == "" {
+				commit = s.Value
+			}
+		case "vcs.time":
+			if date == "" {
+				date = s.Value
+			}
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if modified && commit != "" {
+		commit += "-dirty"
+	}
+}
+
+// shortCommit returns the abbreviated commit the binary was built from, or "".
+func shortCommit() string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+// printVersion writes the version, commit, build date and platform of the binary.
+func printVersion() {
+	fmt.Printf("get_gh_release %s\n", version)
+	if commit != "" {
+		fmt.Printf("commit:   %s\n", commit)
+	}
+	if date != "" {
+		fmt.Printf("built:    %s\n", date)
+	}
+	fmt.Printf("go:       %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
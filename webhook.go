@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/abgoyal/get_gh_release/pkg/releases"
+	"github.com/google/go-github/v62/github"
+)
+
+// runWebhook listens for GitHub release webhooks and installs new releases of the tools listed
+// in a manifest as soon as they are published.
+func runWebhook(ctx context.Context, provider releases.ReleaseProvider, platformOS, platformArch string, args []string) error {
+	fs := flag.NewFlagSet("webhook", flag.ExitOnError)
+	addr := fs.String("addr", ":9000", "Address to listen on.")
+	secret := fs.String("secret", os.Getenv("GET_GH_RELEASE_WEBHOOK_SECRET"), "Webhook secret used to validate payload signatures.")
+	fs.Parse(args)
+	file, err := manifestArg(fs)
+	if err != nil {
+		return fmt.Errorf("usage: get_gh_release webhook [-addr addr] [-secret secret] [manifest.yaml]: %w", err)
+	}
+	if *secret == "" {
+		return fmt.Errorf("a webhook secret is required (-secret or GET_GH_RELEASE_WEBHOOK_SECRET)")
+	}
+	m, err := loadManifest(file)
+	if err != nil {
+		return err
+	}
+
+	h := &webhookHandler{
+		ctx:          ctx,
+		provider:     provider,
+		manifest:     m,
+		secret:       []byte(*secret),
+		platformOS:   platformOS,
+		platformArch: platformArch,
+	}
+	mux := http.NewServeMux()
+	mux.Handle("POST /", h)
+	mux.Handle("GET /metrics", metrics)
+	log.Printf("listening for release webhooks on %s (%d tools)", *addr, len(m.Tools))
+	err = listenAndServe(ctx, *addr, logRequests(mux))
+	// Installs run on after their request was answered; let them stop before exiting
+	h.installs.Wait()
+	return err
+}
+
+// webhookHandler validates release events and installs matching releases in the background.
+type webhookHandler struct {
+	ctx                      context.Context
+	provider                 releases.ReleaseProvider
+	manifest                 *manifest
+	secret                   []byte
+	platformOS, platformArch string
+
+	mu       sync.Mutex     // serialises installs
+	installs sync.WaitGroup // installs started and not yet finished
+}
+
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	payload, err := github.ValidatePayload(r, h.secret)
+	if err != nil {
+		metrics.add("get_gh_release_webhook_events_total", 1, "result", "rejected")
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	event, err := github.ParseWebHook(github.WebHookType(r), payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch e := event.(type) {
+	case *github.PingEvent:
+		fmt.Fprintln(w, "pong")
+	case *github.ReleaseEvent:
+		if e.GetAction() != "published" && e.GetAction() != "released" {
+			fmt.Fprintf(w, "ignored action %s\n", e.GetAction())
+			return
+		}
+		t := h.manifest.toolFor(e.GetRepo().GetOwner().GetLogin(), e.GetRepo().GetName())
+		if t == nil {
+			fmt.Fprintf(w, "%s is not managed\n", e.GetRepo().GetFullName())
+			return
+		}
+		release := releases.FromGitHub(e.GetRelease())
+		if release.Prerelease && !t.Pre {
+			fmt.Fprintf(w, "%s is a prerelease\n", release.Tag)
+			return
+		}
+		if t.Version != "" && !releases.MatchVersion(t.Version, release.Tag) {
+			fmt.Fprintf(w, "%s does not match version %s\n", release.Tag, t.Version)
+			return
+		}
+		if reason := h.manifest.settings(*t).filter().Reject(release); reason != "" {
+			fmt.Fprintf(w, "holding back %s: %s\n", release.Tag, reason)
+			return
+		}
+		metrics.add("get_gh_release_webhook_events_total", 1, "result", "accepted")
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "installing %s %s\n", t.Repo, release.Tag)
+		h.installs.Add(1)
+		go func() {
+			defer h.installs.Done()
+			h.install(*t, release)
+		}()
+	default:
+		fmt.Fprintf(w, "ignored event %s\n", github.WebHookType(r))
+	}
+}
+
+// install downloads the asset of release that t selects.
+func (h *webhookHandler) install(t manifestTool, release *releases.Release) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	owner, repo := t.ownerRepo()
+	asset := h.manifest.matcher(t, h.platformOS, h.platformArch).Match(release)
+	if asset == nil {
+		log.Printf("%s: release %s has no matching asset", t.Repo, release.Tag)
+		return
+	}
+	c := releases.NewCandidate(owner, repo, release, *asset)
+	dest, err := h.manifest.installPath(t, c)
+	if err != nil {
+		log.Printf("%s: %v", t.Repo, err)
+		return
+	}
+	if _, err := installResolved(h.ctx, h.provider, h.manifest, t, nil, c, dest); err != nil {
+		metrics.add("get_gh_release_webhook_events_total", 1, "result", "install_failed")
+		log.Printf("%s: install of %s failed: %v", t.Repo, c.Tag, err)
+		return
+	}
+	log.Printf("%s: installed %s to %s", t.Repo, c.Tag, dest)
+}